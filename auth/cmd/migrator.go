@@ -42,6 +42,7 @@ func NewMigratorCommand(_ ...cmd.ICommandOptions) cmd.ICommand {
 
 	migrator.Init(migrator.Config{
 		DB:             connection.DB(),
+		Driver:         connection.Driver(),
 		MigrationsPath: "migrations",
 	})
 
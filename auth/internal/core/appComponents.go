@@ -2,6 +2,7 @@ package core
 
 import (
 	cvn "github.com/epicoon/lxgo/auth/internal/conventions"
+	"github.com/epicoon/lxgo/kernel/ratelimit"
 	"github.com/epicoon/lxgo/session"
 )
 
@@ -9,6 +10,9 @@ func setComponents(app cvn.IApp) error {
 	if err := session.SetAppComponent(app, "Components.SessionStorage"); err != nil {
 		return err
 	}
+	if err := ratelimit.SetAppComponent(app, "Components.RateLimit"); err != nil {
+		return err
+	}
 
 	return nil
 }
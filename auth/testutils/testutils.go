@@ -198,6 +198,7 @@ func CreateAdminUser(login, password string) (*models.User, string) {
 func actualizeMigrations(app kernel.IApp) {
 	migrator.Init(migrator.Config{
 		DB:             app.Connection().DB(),
+		Driver:         app.Connection().Driver(),
 		MigrationsPath: app.Pathfinder().GetAbsPath("migrations"),
 	})
 	migrator.Up()
@@ -7,13 +7,16 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"net/http"
 
 	"github.com/epicoon/lxgo/kernel"
 	lxApp "github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/health"
 	lxHttp "github.com/epicoon/lxgo/kernel/http"
 )
 
@@ -151,6 +154,33 @@ func (c *AuthClient) Config() *AuthConfig {
 	return (c.GetConfig()).(*AuthConfig)
 }
 
+// Run registers the "auth" readiness check on the app's health component,
+// if the app has one - see kernel.IAppComponent.
+func (c *AuthClient) Run() error {
+	if h, err := health.AppComponent(c.App()); err == nil {
+		h.AddReadinessCheck("auth", c.Ping)
+	}
+	return nil
+}
+
+// Ping reports whether the authorization service is reachable - any
+// response but a 5xx counts.
+func (c *AuthClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Config().Server, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("authorization service replied %s", resp.Status)
+	}
+	return nil
+}
+
 // PrepareClientSettings renders the client-side config (client ID,
 // redirect/state/logout/refresh/user-data paths) as an inline <script> tag
 // that sets window._lxauth_settings - embed it in a page template so
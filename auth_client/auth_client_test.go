@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -263,3 +264,15 @@ func TestLogOut_ServerRejects(t *testing.T) {
 		t.Fatal("expected an error for a rejected access token")
 	}
 }
+
+func TestPing(t *testing.T) {
+	up := newJSONStub(t, "/tokens", http.StatusOK, nil)
+	if err := newTestAuthClient(t, kernel.Dict{"Server": up.URL}).Ping(context.Background()); err != nil {
+		t.Fatalf("expected a reachable service (a 404 included), got %v", err)
+	}
+
+	down := newJSONStub(t, "/", http.StatusBadGateway, nil)
+	if err := newTestAuthClient(t, kernel.Dict{"Server": down.URL}).Ping(context.Background()); err == nil {
+		t.Fatal("expected an error for a 5xx reply")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
@@ -15,8 +16,10 @@ import (
 	"os/signal"
 	"reflect"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -25,6 +28,7 @@ import (
 	"github.com/epicoon/lxgo/kernel/config"
 	"github.com/epicoon/lxgo/kernel/events"
 	lxHttp "github.com/epicoon/lxgo/kernel/http"
+	"github.com/epicoon/lxgo/kernel/logger"
 	"github.com/epicoon/lxgo/kernel/template"
 )
 
@@ -34,6 +38,12 @@ import (
 // "ShutdownTimeout" (whole seconds), see InitApp.
 const defaultShutdownTimeout = 5 * time.Second
 
+// defaultComponentStartTimeout is how long Run waits for a component's Run
+// to return before giving up on startup - used unless the app's config
+// sets its own "ComponentStartTimeout" (whole seconds, 0 for no limit) or
+// the component implements kernel.IAppComponentStartTimeout.
+const defaultComponentStartTimeout = 30 * time.Second
+
 /** @interface kernel.IApp */
 
 // App is the default kernel.IApp implementation - embed it in your own
@@ -43,14 +53,22 @@ type App struct {
 	pathfinder      kernel.IPathfinder
 	config          kernel.IDict
 	manageSocket    *manageSocket
+	manageMu        sync.RWMutex
+	manageCommands  map[string]FManageCommand
 	components      map[any]kernel.IAppComponent
+	componentKeys   []any
 	logger          kernel.ILogger
 	diContainer     kernel.IDIContainer
 	connection      kernel.IConnection
+	connections     map[string]kernel.IConnection
 	router          kernel.IRouter
 	tplHolder       kernel.ITemplateHolder
 	events          kernel.IEventManager
 	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	componentStart  time.Duration
+	server          ServerConfig
+	tls             *tlsSettings
 }
 
 /** @constructor */
@@ -65,6 +83,7 @@ func NewApp() *App {
 	app.tplHolder = template.NewTemplateHolder(app)
 	app.events = events.NewEventManager(app)
 	app.shutdownTimeout = defaultShutdownTimeout
+	app.componentStart = defaultComponentStartTimeout
 	return app
 }
 
@@ -88,9 +107,11 @@ func Configure(app kernel.IApp) error {
 	return nil
 }
 
-// InitApp sets up app from an already-loaded config: port, optional manage
-// socket, optional DB connection, and router - see Configure for the
-// usual entry point that also loads the config file.
+// InitApp sets up app from an already-loaded config: port, optional logger,
+// optional manage socket, optional DB connection, router (compression,
+// assets' Cache-Control, CORS, security headers), and HTTP server
+// (timeouts, TLS) - see Configure for the usual entry point that also
+// loads the config file.
 func InitApp(app kernel.IApp, c kernel.IDict) error {
 	port, err := config.GetParam[int](c, "Port")
 	if err != nil {
@@ -100,6 +121,18 @@ func InitApp(app kernel.IApp, c kernel.IDict) error {
 	app.SetPort(port)
 	app.SetConfig(c)
 
+	if config.HasParam(c, "Logger") {
+		logConf, err := config.GetParam[kernel.Dict](c, "Logger")
+		if err != nil {
+			return fmt.Errorf("can not read Logger config: %s", err)
+		}
+		l, err := newLogger(app, logConf)
+		if err != nil {
+			return fmt.Errorf("can not create logger: %s", err)
+		}
+		app.SetLogger(l)
+	}
+
 	if config.HasParam(c, "ManageSocket") {
 		a, ok := app.BaseApp().(*App)
 		if ok {
@@ -115,9 +148,83 @@ func InitApp(app kernel.IApp, c kernel.IDict) error {
 		if err != nil {
 			return fmt.Errorf("can not read Database config: %s", err)
 		}
-		app.SetConnection(NewConnection())
-		app.Connection().SetApp(app)
-		app.Connection().SetConfig(dbConf)
+		conn, err := NewNamedConnection(dbConf, "")
+		if err != nil {
+			return fmt.Errorf("can not read Database config: %s", err)
+		}
+		conn.SetApp(app)
+		app.SetConnection(conn)
+
+		if named, ok := dbConf["Connections"]; ok {
+			names, err := cast.To[kernel.Dict](named)
+			if err != nil {
+				return fmt.Errorf("can not read Database config: Connections: %s", err)
+			}
+			for name := range names {
+				conn, err := NewNamedConnection(dbConf, name)
+				if err != nil {
+					return fmt.Errorf("can not read Database config: %s", err)
+				}
+				conn.SetApp(app)
+				app.SetNamedConnection(name, conn)
+			}
+		}
+	}
+
+	if config.HasParam(c, "Compression") {
+		compConf, err := config.GetParam[kernel.Dict](c, "Compression")
+		if err != nil {
+			return fmt.Errorf("can not read Compression config: %s", err)
+		}
+		var cc kernel.CompressionConfig
+		if err := cast.DictToStruct(&compConf, &cc); err != nil {
+			return fmt.Errorf("can not read Compression config: %s", err)
+		}
+		app.Router().SetCompression(cc)
+	}
+
+	if config.HasParam(c, "AssetsCacheControl") {
+		rules, err := config.GetParam[map[string]string](c, "AssetsCacheControl")
+		if err != nil {
+			return fmt.Errorf("can not read AssetsCacheControl config: %s", err)
+		}
+		app.Router().SetAssetsCacheControl(rules)
+	}
+
+	if config.HasParam(c, "Cors") {
+		corsConf, err := config.GetParam[kernel.Dict](c, "Cors")
+		if err != nil {
+			return fmt.Errorf("can not read Cors config: %s", err)
+		}
+		var cc kernel.CorsConfig
+		if err := cast.DictToStruct(&corsConf, &cc); err != nil {
+			return fmt.Errorf("can not read Cors config: %s", err)
+		}
+		if err := lxHttp.CheckCorsConfig(cc); err != nil {
+			return fmt.Errorf("can not read Cors config: %s", err)
+		}
+		app.Router().SetCors(cc)
+	}
+
+	if config.HasParam(c, "SecurityHeaders") {
+		shConf, err := config.GetParam[kernel.Dict](c, "SecurityHeaders")
+		if err != nil {
+			return fmt.Errorf("can not read SecurityHeaders config: %s", err)
+		}
+		var sc kernel.SecurityHeadersConfig
+		if err := cast.DictToStruct(&shConf, &sc); err != nil {
+			return fmt.Errorf("can not read SecurityHeaders config: %s", err)
+		}
+		app.Router().SetSecurityHeaders(sc)
+	}
+
+	if config.HasParam(c, "Server") || config.HasParam(c, "TLS") {
+		a, ok := app.BaseApp().(*App)
+		if ok {
+			if err := a.initServer(c); err != nil {
+				return err
+			}
+		}
 	}
 
 	if config.HasParam(c, "ShutdownTimeout") {
@@ -131,6 +238,43 @@ func InitApp(app kernel.IApp, c kernel.IDict) error {
 		}
 	}
 
+	if config.HasParam(c, "Events") {
+		em, ok := app.Events().(*events.EventManager)
+		if ok {
+			evConf, err := config.GetParam[kernel.Dict](c, "Events")
+			if err != nil {
+				return fmt.Errorf("can not read Events config: %s", err)
+			}
+			var pc events.PoolConfig
+			if err := cast.DictToStruct(&evConf, &pc); err != nil {
+				return fmt.Errorf("can not read Events config: %s", err)
+			}
+			em.SetPoolConfig(pc)
+		}
+	}
+
+	if config.HasParam(c, "ShutdownDrainDelay") {
+		a, ok := app.BaseApp().(*App)
+		if ok {
+			seconds, err := config.GetParam[int](c, "ShutdownDrainDelay")
+			if err != nil {
+				return fmt.Errorf("can not read ShutdownDrainDelay config: %s", err)
+			}
+			a.drainDelay = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if config.HasParam(c, "ComponentStartTimeout") {
+		a, ok := app.BaseApp().(*App)
+		if ok {
+			seconds, err := config.GetParam[int](c, "ComponentStartTimeout")
+			if err != nil {
+				return fmt.Errorf("can not read ComponentStartTimeout config: %s", err)
+			}
+			a.componentStart = time.Duration(seconds) * time.Second
+		}
+	}
+
 	return nil
 }
 
@@ -207,11 +351,16 @@ func (app *App) Config() kernel.IDict {
 	return app.config
 }
 
-// SetComponent registers a component under key.
+// SetComponent registers a component under key. Components are run in
+// the order they were registered in, except that the ones a
+// kernel.IAppComponentDependent depends on always run before it.
 func (app *App) SetComponent(key any, c kernel.IAppComponent) {
 	if app.components == nil {
 		app.components = make(map[any]kernel.IAppComponent)
 	}
+	if _, exists := app.components[key]; !exists {
+		app.componentKeys = append(app.componentKeys, key)
+	}
 	app.components[key] = c
 }
 
@@ -235,6 +384,15 @@ func (app *App) SetConnection(c kernel.IConnection) {
 	app.connection = c
 }
 
+// SetNamedConnection sets an additional DB connection under name (e.g. a
+// read replica).
+func (app *App) SetNamedConnection(name string, c kernel.IConnection) {
+	if app.connections == nil {
+		app.connections = make(map[string]kernel.IConnection)
+	}
+	app.connections[name] = c
+}
+
 // Pathfinder returns the application's IPathfinder.
 func (app *App) Pathfinder() kernel.IPathfinder {
 	return app.pathfinder
@@ -270,6 +428,30 @@ func (app *App) Connection() kernel.IConnection {
 	return app.connection
 }
 
+// NamedConnection returns the DB connection set under name, or nil - the
+// main one if name is empty.
+func (app *App) NamedConnection(name string) kernel.IConnection {
+	if name == "" {
+		return app.connection
+	}
+	c, exists := app.connections[name]
+	if !exists {
+		return nil
+	}
+	return c
+}
+
+// ConnectionNames returns the names the additional DB connections are set
+// under, sorted.
+func (app *App) ConnectionNames() []string {
+	names := make([]string, 0, len(app.connections))
+	for name := range app.connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Log writes an informational message under category, via the configured
 // logger or the standard log package if none is set.
 func (app *App) Log(msg string, category string) {
@@ -310,9 +492,12 @@ func (app *App) SetLogger(l kernel.ILogger) {
 	app.logger = l
 }
 
-// Run starts the manage socket (if configured), the router, the HTTP
-// server, and every registered component, then blocks until SIGINT/SIGTERM,
-// at which point it gives the server up to shutdownTimeout to finish
+// Run starts the manage socket (if configured), every registered component
+// - dependencies first, each within its start timeout - and the HTTP
+// server - HTTPS with HTTP/2 if TLS is configured, plus the HTTP-to-HTTPS
+// redirect listener if enabled - then blocks until
+// SIGINT/SIGTERM, at which point it fires EVENT_APP_BEFORE_SHUTDOWN, keeps
+// serving for drainDelay, then gives the server up to shutdownTimeout to finish
 // in-flight requests (http.Server.Shutdown) before returning. Run itself
 // never calls Final - that stays the caller's job, so there is
 // exactly one place that ever calls it, on a graceful shutdown or a
@@ -321,8 +506,7 @@ func (app *App) Run() {
 	defer func() {
 		if r := recover(); r != nil {
 			app.events.Trigger(kernel.EVENT_APP_BEFORE_FAIL)
-			fmt.Printf("Panic: %s\n", r)
-			debug.PrintStack()
+			app.LogError(fmt.Sprintf("Panic: %s\n%s", r, debug.Stack()), "App")
 			return
 		}
 	}()
@@ -335,38 +519,67 @@ func (app *App) Run() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	fmt.Printf("Start a new application on port %d\n", app.port)
+	app.Log(fmt.Sprintf("Start a new application on port %d", app.port), "App")
 
 	if app.manageSocket != nil {
 		if err := app.manageSocket.Run(); err != nil {
-			fmt.Printf("Manage socket failed: %v\n", err)
+			app.LogError(fmt.Sprintf("Manage socket failed: %v", err), "App")
 			return
 		}
 	}
 
-	app.router.Start()
-
-	for _, c := range app.components {
-		if err := c.Run(); err != nil {
-			fmt.Printf("Could not start app component '%s': %s\n", c.Name(), err.Error())
+	components, err := app.orderedComponents()
+	if err != nil {
+		app.LogError(fmt.Sprintf("Could not start app components: %s", err.Error()), "App")
+		return
+	}
+	for _, oc := range components {
+		if err := app.runComponent(oc.component); err != nil {
+			app.LogError(fmt.Sprintf("Could not start app component '%s'%s: %s",
+				oc.component.Name(), requiredBy(oc.chain), err.Error()), "App")
 			return
 		}
 	}
 
-	srv := &http.Server{Addr: ":" + strconv.Itoa(app.port), Handler: nil}
+	srv := app.newServer()
 
 	// Bind synchronously, so a port conflict is reported (and stops
 	// startup) right here rather than racing the goroutine
 	// below - only Serve, which blocks until Shutdown/Close, moves there.
 	ln, err := net.Listen("tcp", srv.Addr)
 	if err != nil {
-		fmt.Printf("Could not start server: %s\n", err.Error())
+		app.LogError(fmt.Sprintf("Could not start server: %s", err.Error()), "App")
 		return
 	}
 
+	var redirectSrv *http.Server
+	if app.tls != nil && app.tls.conf.RedirectPort != 0 {
+		redirectSrv = &http.Server{
+			Addr:              ":" + strconv.Itoa(app.tls.conf.RedirectPort),
+			Handler:           redirectHandler(app.port),
+			ReadHeaderTimeout: srv.ReadHeaderTimeout,
+		}
+		redirectLn, err := net.Listen("tcp", redirectSrv.Addr)
+		if err != nil {
+			ln.Close()
+			app.LogError(fmt.Sprintf("Could not start HTTPS redirect server: %s", err.Error()), "App")
+			return
+		}
+		go redirectSrv.Serve(redirectLn)
+		defer redirectSrv.Close()
+	}
+
 	srvErr := make(chan error, 1)
 	go func() {
-		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		var err error
+		if srv.TLSConfig != nil {
+			// Certificates come from TLSConfig.GetCertificate. ServeTLS,
+			// unlike Serve, enables HTTP/2.
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			srvErr <- err
 			return
 		}
@@ -375,38 +588,249 @@ func (app *App) Run() {
 
 	select {
 	case <-ctx.Done():
-		fmt.Println("Shutting down...")
+		app.Log("Shutting down...", "App")
+		app.events.Trigger(kernel.EVENT_APP_BEFORE_SHUTDOWN)
+		if app.drainDelay > 0 {
+			// Still serving - for load balancers to notice the app isn't
+			// ready anymore before it stops accepting requests.
+			time.Sleep(app.drainDelay)
+		}
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), app.shutdownTimeout)
 		defer cancel()
 		if err := srv.Shutdown(shutdownCtx); err != nil {
-			fmt.Printf("Graceful shutdown failed: %s\n", err.Error())
+			app.LogError(fmt.Sprintf("Graceful shutdown failed: %s", err.Error()), "App")
 		}
 		<-srvErr // Serve has returned by now (Shutdown/Close unblocks it) - drain so the goroutine isn't left dangling
 	case err := <-srvErr:
 		if err != nil {
-			fmt.Printf("Server error: %s\n", err.Error())
+			app.LogError(fmt.Sprintf("Server error: %s", err.Error()), "App")
 		}
 	}
 }
 
-// Final fires EVENT_APP_BEFORE_FINAL, closes the DB connection, stops the
-// manage socket, and finalizes every registered component.
+// Final fires EVENT_APP_BEFORE_FINAL, stops the manage socket, finalizes
+// every registered component in the reverse of the order Run starts them
+// in, waits for the async event handlers, closes the DI container's
+// singletons and then the DB connections.
 func (app *App) Final() {
 	app.events.Trigger(kernel.EVENT_APP_BEFORE_FINAL)
 
+	if app.manageSocket != nil {
+		app.manageSocket.Final()
+	}
+
+	components, err := app.orderedComponents()
+	if err != nil {
+		// Still finalize everything - in the reverse of registration order.
+		components = nil
+		for _, key := range app.componentKeys {
+			components = append(components, orderedComponent{component: app.components[key]})
+		}
+	}
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i].component
+		if err := c.Final(); err != nil {
+			app.LogError(fmt.Sprintf("Could not finish app component '%s': %v\n", c.Name(), err.Error()), "App")
+		}
+	}
+
+	app.events.Close()
+
+	if app.diContainer != nil {
+		if err := app.diContainer.Close(); err != nil {
+			app.LogError(fmt.Sprintf("Could not close DI container: %v", err), "App")
+		}
+	}
+
+	// After the components and singletons - they may still use the DB while
+	// they stop, e.g. to give a running job back to its queue.
 	if app.connection != nil {
 		if err := app.connection.Close(); err != nil {
 			app.LogError(fmt.Sprintf("Could not close app connection: %v", err), "App")
 		}
 	}
+	for name, c := range app.connections {
+		if err := c.Close(); err != nil {
+			app.LogError(fmt.Sprintf("Could not close app connection '%s': %v", name, err), "App")
+		}
+	}
 
-	if app.manageSocket != nil {
-		app.manageSocket.Final()
+	// Last - everything above may still be logging.
+	if c, ok := app.logger.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("Could not close app logger: %v\n", err)
+		}
 	}
+}
 
-	for _, c := range app.components {
-		if err := c.Final(); err != nil {
-			app.LogError(fmt.Sprintf("Could not finish app component '%s': %v\n", c.Name(), err.Error()), "App")
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// initServer reads the "Server" and "TLS" config sections - the TLS
+// certificate is reloaded whenever the config is refreshed.
+func (app *App) initServer(c kernel.IDict) error {
+	if config.HasParam(c, "Server") {
+		serverConf, err := config.GetParam[kernel.Dict](c, "Server")
+		if err != nil {
+			return fmt.Errorf("can not read Server config: %s", err)
+		}
+		if err := cast.DictToStruct(&serverConf, &app.server); err != nil {
+			return fmt.Errorf("can not read Server config: %s", err)
+		}
+	}
+
+	if config.HasParam(c, "TLS") {
+		tlsConf, err := config.GetParam[kernel.Dict](c, "TLS")
+		if err != nil {
+			return fmt.Errorf("can not read TLS config: %s", err)
+		}
+		app.tls, err = newTLSSettings(app, tlsConf)
+		if err != nil {
+			return fmt.Errorf("can not read TLS config: %s", err)
 		}
+		app.events.Subscribe(kernel.EVENT_CONFIG_REFRESHED, func(kernel.IEvent) {
+			app.tls.reload()
+		})
+	}
+	return nil
+}
+
+// newServer builds the app's http.Server - HTTPS if it has TLS settings.
+func (app *App) newServer() *http.Server {
+	srv := &http.Server{
+		Addr:              ":" + strconv.Itoa(app.port),
+		Handler:           app.router,
+		ReadTimeout:       time.Duration(app.server.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(app.server.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(app.server.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(app.server.IdleTimeout) * time.Second,
+	}
+	if app.tls != nil {
+		srv.TLSConfig = app.tls.config.Clone()
+	}
+	return srv
+}
+
+// newLogger builds the app's logger from its "Logger" config section (see
+// logger.Config), resolving a file output against the app's root.
+func newLogger(app kernel.IApp, c kernel.Dict) (*logger.Logger, error) {
+	conf := logger.Config{}
+	if err := cast.DictToStruct(&c, &conf); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(conf.Output) {
+	case "", "stdout", "stderr":
+	default:
+		conf.Output = app.Pathfinder().GetAbsPath(conf.Output)
+	}
+	return logger.NewFromConfig(conf)
+}
+
+// orderedComponent is a registered component along with the chain of keys
+// of the components that depend on it, the outermost first - empty unless
+// it was reached as a dependency.
+type orderedComponent struct {
+	component kernel.IAppComponent
+	chain     []any
+}
+
+// orderedComponents returns the registered components in the order Run
+// starts them in: in registration order, each preceded by the components
+// it depends on. Fails on a dependency cycle or an unregistered dependency.
+func (app *App) orderedComponents() ([]orderedComponent, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[any]int, len(app.components))
+	order := make([]orderedComponent, 0, len(app.components))
+
+	var visit func(key any, chain []any) error
+	visit = func(key any, chain []any) error {
+		switch state[key] {
+		case visiting:
+			for i, k := range chain {
+				if k == key {
+					return fmt.Errorf("dependency cycle: %s", formatChain(append(chain[i:], key)))
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		c, ok := app.components[key]
+		if !ok {
+			return fmt.Errorf("component '%v' is not registered%s", key, requiredBy(chain))
+		}
+		state[key] = visiting
+		if dc, ok := c.(kernel.IAppComponentDependent); ok {
+			depChain := append(chain[:len(chain):len(chain)], key)
+			for _, dep := range dc.DependsOn() {
+				if err := visit(dep, depChain); err != nil {
+					return err
+				}
+			}
+		}
+		state[key] = visited
+		order = append(order, orderedComponent{component: c, chain: chain})
+		return nil
+	}
+
+	for _, key := range app.componentKeys {
+		if err := visit(key, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// runComponent runs c, failing if it takes longer than its start timeout.
+// Run is expected to return once the component is started - long-running
+// work belongs in goroutines it stops on Final. A Run that times out can't
+// be interrupted: it's left running in the background while startup is
+// abandoned, and its result is discarded.
+func (app *App) runComponent(c kernel.IAppComponent) error {
+	timeout := app.componentStart
+	if ct, ok := c.(kernel.IAppComponentStartTimeout); ok {
+		timeout = ct.StartTimeout()
+	}
+	if timeout <= 0 {
+		return c.Run()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		done <- c.Run()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("not started within %s", timeout)
+	}
+}
+
+// requiredBy formats chain for error messages, e.g. " (required by 'ws' -> 'session')".
+func requiredBy(chain []any) string {
+	if len(chain) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (required by %s)", formatChain(chain))
+}
+
+func formatChain(keys []any) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("'%v'", key)
 	}
+	return strings.Join(parts, " -> ")
 }
@@ -25,10 +25,8 @@ func (c *runComponent) Run() error   { c.ran.Store(true); return nil }
 const shutdownTestTimeout = 2 * time.Second
 
 // TestApp_Run_StartsComponentsAndReturnsOnSIGTERM is a regression test for
-// two things at once (kept in one test, not two - Router.Start() registers
-// on the process-global http.DefaultServeMux, so a second App in the same
-// test binary calling Run() would panic on the resulting duplicate "/"
-// registration; there can only be one Run() per process here regardless):
+// two things at once (kept in one test, not two - Run() reacts to a
+// process-wide signal, so there can only be one Run() at a time here):
 //
 //  1. The components loop in Run() used to sit right after the blocking
 //     srv.ListenAndServe() call, so it was unreachable during normal
@@ -7,6 +7,7 @@ import (
 	"github.com/epicoon/lxgo/kernel"
 	"github.com/epicoon/lxgo/kernel/cast"
 	"github.com/epicoon/lxgo/kernel/config"
+	"github.com/epicoon/lxgo/kernel/logger"
 )
 
 /* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
@@ -231,6 +232,12 @@ func (c *AppComponent) LogError(msg string, params ...any) {
 	}
 }
 
+// Logger returns a structured logger writing under LogCategory - see
+// logger.For.
+func (c *AppComponent) Logger() kernel.IStructuredLogger {
+	return logger.For(c.App()).WithCategory(c.logCategory())
+}
+
 /** @abstract */
 
 // LogCategory returns "AppComponent" - override it with a component-specific category.
@@ -0,0 +1,81 @@
+package app_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+type textResource struct {
+	*lxHttp.Resource
+}
+
+func (r *textResource) Run() kernel.IHttpResponse {
+	return r.JsonResponse(kernel.JsonResponseConfig{Data: strings.Repeat("text ", 20)})
+}
+
+func TestInitApp_Compression(t *testing.T) {
+	a, err := apptest.New(kernel.Dict{
+		"Compression": kernel.Dict{"MinSize": 50, "Types": []any{"application/json"}},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	a.Router().RegisterResource("/text", "GET", func() kernel.IHttpResource {
+		return &textResource{Resource: lxHttp.NewResource()}
+	})
+
+	req := httptest.NewRequest("GET", "/text", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected the configured compression, got headers %v", rec.Header())
+	}
+}
+
+func TestInitApp_InvalidCompression(t *testing.T) {
+	if _, err := apptest.New(kernel.Dict{"Compression": "yes"}); err == nil {
+		t.Fatal("expected an error for an invalid Compression section")
+	}
+}
+
+func TestInitApp_CorsAndSecurityHeaders(t *testing.T) {
+	a, err := apptest.New(kernel.Dict{
+		"Cors":            kernel.Dict{"AllowOrigins": []any{"https://app.example.com"}, "MaxAge": 60},
+		"SecurityHeaders": kernel.Dict{"FrameOptions": "SAMEORIGIN", "NoSniff": true},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	a.Router().RegisterResource("/text", "GET", func() kernel.IHttpResource {
+		return &textResource{Resource: lxHttp.NewResource()}
+	})
+
+	req := httptest.NewRequest("OPTIONS", "/text", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+
+	if rec.Code != 204 || rec.Header().Get("Access-Control-Max-Age") != "60" {
+		t.Fatalf("expected the configured CORS preflight, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Header().Get("X-Frame-Options") != "SAMEORIGIN" || rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatalf("expected the configured security headers, got %v", rec.Header())
+	}
+}
+
+func TestInitApp_CorsAnyOriginWithCredentials(t *testing.T) {
+	_, err := apptest.New(kernel.Dict{
+		"Cors": kernel.Dict{"AllowOrigins": []any{"*"}, "AllowCredentials": true},
+	})
+	if err == nil || !strings.Contains(err.Error(), "AllowCredentials") {
+		t.Fatalf(`expected "*" with credentials to be rejected, got %v`, err)
+	}
+}
@@ -4,30 +4,63 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/epicoon/lxgo/kernel"
 	"github.com/epicoon/lxgo/kernel/cast"
+	"github.com/go-sql-driver/mysql"
 
+	_ "github.com/glebarez/go-sqlite"
 	_ "github.com/lib/pq"
 )
 
+const (
+	// DriverPostgres is the PostgreSQL driver (github.com/lib/pq), the default.
+	DriverPostgres = "postgres"
+	// DriverMysql is the MySQL/MariaDB driver (github.com/go-sql-driver/mysql).
+	DriverMysql = "mysql"
+	// DriverSqlite is the SQLite driver (github.com/glebarez/go-sqlite, pure
+	// Go - no cgo or server needed).
+	DriverSqlite = "sqlite"
+)
+
 // ConnectionConfig configures a Connection - see Connection.SetConfig.
 type ConnectionConfig struct {
-	Host                string
-	Port                int
-	User                string
-	Password            string
-	DBName              string
-	SSLMode             string
+	// Driver is one of DriverPostgres (default), DriverMysql or DriverSqlite.
+	Driver string
+	// SQLDriver is the database/sql driver name to open, if it differs from
+	// Driver (e.g. "sqlite3" for github.com/mattn/go-sqlite3).
+	SQLDriver string
+	// DSN is used as is if set, instead of building one from the fields below.
+	DSN string
+
+	Host     string
+	Port     int
+	User     string
+	Password string
+	// DBName is the database name - or the database file for SQLite,
+	// relative to the app root (":memory:" for an in-memory database).
+	DBName  string
+	SSLMode string
+
 	ConnectAttempts     int
 	ConnectAttemptDelay int
+
+	// MaxOpenConns, MaxIdleConns - see sql.DB.SetMaxOpenConns/SetMaxIdleConns;
+	// zero keeps the database/sql defaults.
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxLifetime, ConnMaxIdleTime are in seconds; zero means no limit.
+	ConnMaxLifetime int
+	ConnMaxIdleTime int
 }
 
 /** @interface kernel.IConnection */
 
 // Connection is the default kernel.IConnection implementation - a
-// PostgreSQL connection via database/sql.
+// PostgreSQL, MySQL or SQLite connection via database/sql.
 type Connection struct {
 	app kernel.IApp
 	cfg *ConnectionConfig
@@ -43,6 +76,36 @@ func NewConnection() *Connection {
 	return new(Connection)
 }
 
+// NewNamedConnection constructs a Connection configured by the "Database"
+// config section dbConf for the connection name - the main one if name is
+// empty, otherwise the one from dbConf's "Connections" map, which inherits
+// every field it doesn't set from the main section.
+func NewNamedConnection(dbConf kernel.Dict, name string) (*Connection, error) {
+	conf := kernel.Dict{}
+	for key, val := range dbConf {
+		if key != "Connections" {
+			conf[key] = val
+		}
+	}
+	if name != "" {
+		named, err := cast.To[kernel.Dict](dbConf["Connections"])
+		if err != nil {
+			return nil, fmt.Errorf("DB connection '%s' is not defined", name)
+		}
+		own, err := cast.To[kernel.Dict](named[name])
+		if err != nil || named[name] == nil {
+			return nil, fmt.Errorf("DB connection '%s' is not defined", name)
+		}
+		for key, val := range own {
+			conf[key] = val
+		}
+	}
+
+	c := NewConnection()
+	c.SetConfig(conf)
+	return c, nil
+}
+
 // SetApp binds the connection to its owning app.
 func (c *Connection) SetApp(app kernel.IApp) {
 	c.app = app
@@ -58,6 +121,14 @@ func (c *Connection) SetConfig(cfg kernel.IDict) {
 	cast.DictToStruct(dict, c.cfg)
 }
 
+// Driver returns the configured driver, DriverPostgres by default.
+func (c *Connection) Driver() string {
+	if c.cfg == nil || c.cfg.Driver == "" {
+		return DriverPostgres
+	}
+	return strings.ToLower(c.cfg.Driver)
+}
+
 // DB returns the underlying *sql.DB, or nil before Connect succeeds.
 func (c *Connection) DB() *sql.DB {
 	return c.db
@@ -67,23 +138,24 @@ func (c *Connection) DB() *sql.DB {
 // (10 by default) with ConnectAttemptDelay seconds between attempts (2 by default).
 func (c *Connection) Connect() error {
 	cfg := c.cfg
-	if err := validateConfig(cfg); err != nil {
+	if err := validateConfig(cfg, c.Driver()); err != nil {
 		return err
 	}
 
-	SSLMode := cfg.SSLMode
-	if SSLMode == "" {
-		SSLMode = "disable"
+	driver, dsn, err := c.dataSource()
+	if err != nil {
+		return err
 	}
-	dsn := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, SSLMode,
-	)
 
-	db, err := sql.Open("postgres", dsn)
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return fmt.Errorf("failed to open database connection: %w", err)
 	}
+	applyPoolConfig(db, cfg)
+	if c.Driver() == DriverSqlite && cfg.DBName == ":memory:" && cfg.MaxOpenConns == 0 {
+		// Every pooled connection would get its own in-memory database.
+		db.SetMaxOpenConns(1)
+	}
 
 	attempts := cfg.ConnectAttempts
 	if attempts == 0 {
@@ -100,9 +172,12 @@ func (c *Connection) Connect() error {
 			c.db = db
 			return nil
 		}
-		time.Sleep(delay)
+		if i < attempts {
+			time.Sleep(delay)
+		}
 	}
 
+	db.Close()
 	return fmt.Errorf("failed to connect to DB after %d attempts: %w", attempts, err)
 }
 
@@ -114,17 +189,94 @@ func (c *Connection) Close() error {
 	return nil
 }
 
-func validateConfig(cfg *ConnectionConfig) error {
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// dataSource returns the database/sql driver name and DSN to open.
+func (c *Connection) dataSource() (string, string, error) {
+	cfg := c.cfg
+	driver := c.Driver()
+	sqlDriver := cfg.SQLDriver
+	if sqlDriver == "" {
+		sqlDriver = driver
+	}
+	if cfg.DSN != "" {
+		return sqlDriver, cfg.DSN, nil
+	}
+
+	switch driver {
+	case DriverPostgres:
+		SSLMode := cfg.SSLMode
+		if SSLMode == "" {
+			SSLMode = "disable"
+		}
+		return sqlDriver, fmt.Sprintf(
+			"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, SSLMode,
+		), nil
+
+	case DriverMysql:
+		mc := mysql.NewConfig()
+		mc.Net = "tcp"
+		mc.Addr = fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+		mc.User = cfg.User
+		mc.Passwd = cfg.Password
+		mc.DBName = cfg.DBName
+		mc.ParseTime = true
+		if cfg.SSLMode != "" && cfg.SSLMode != "disable" {
+			mc.TLSConfig = "true"
+		}
+		return sqlDriver, mc.FormatDSN(), nil
+
+	case DriverSqlite:
+		path := cfg.DBName
+		if path != ":memory:" && !strings.HasPrefix(path, "file:") && !filepath.IsAbs(path) && c.app != nil {
+			path = c.app.Pathfinder().GetAbsPath(path)
+		}
+		return sqlDriver, path, nil
+	}
+
+	return "", "", fmt.Errorf("unknown DB driver '%s'", driver)
+}
+
+func applyPoolConfig(db *sql.DB, cfg *ConnectionConfig) {
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+	}
+	if cfg.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime) * time.Second)
+	}
+}
+
+func validateConfig(cfg *ConnectionConfig, driver string) error {
 	if cfg == nil {
 		return errors.New("DB connection config is not defined")
 	}
+	if cfg.DSN != "" {
+		return nil
+	}
 
-	requiredFields := map[string]string{
-		"host":     cfg.Host,
-		"port":     fmt.Sprintf("%d", cfg.Port),
-		"user":     cfg.User,
-		"password": cfg.Password,
-		"name":     cfg.DBName,
+	var requiredFields map[string]string
+	switch driver {
+	case DriverPostgres, DriverMysql:
+		requiredFields = map[string]string{
+			"host":     cfg.Host,
+			"port":     fmt.Sprintf("%d", cfg.Port),
+			"user":     cfg.User,
+			"password": cfg.Password,
+			"name":     cfg.DBName,
+		}
+	case DriverSqlite:
+		requiredFields = map[string]string{"name": cfg.DBName}
+	default:
+		return fmt.Errorf("unknown DB driver '%s'", driver)
 	}
 
 	for field, value := range requiredFields {
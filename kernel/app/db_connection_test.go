@@ -0,0 +1,106 @@
+package app_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/apptest"
+)
+
+func TestInitApp_NamedConnections(t *testing.T) {
+	replicaPath := filepath.Join(t.TempDir(), "replica.db")
+	a, err := apptest.New(kernel.Dict{
+		"Database": kernel.Dict{
+			"Driver":       "sqlite",
+			"DBName":       ":memory:",
+			"MaxOpenConns": 1,
+			"Connections": map[string]any{
+				"replica": map[string]any{"DBName": replicaPath, "MaxOpenConns": 3},
+				"reports": map[string]any{"Driver": "mysql", "Host": "localhost"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	defer a.Final()
+
+	if a.NamedConnection("") != a.Connection() {
+		t.Fatal("an empty name must return the main connection")
+	}
+	if a.NamedConnection("unknown") != nil {
+		t.Fatal("expected nil for an unknown connection")
+	}
+	if got := a.NamedConnection("reports").Driver(); got != app.DriverMysql {
+		t.Fatalf("got driver %q, want mysql", got)
+	}
+	if err := a.NamedConnection("reports").Connect(); err == nil || !strings.Contains(err.Error(), "undefined db") {
+		t.Fatalf("expected a config error for an incomplete connection, got %v", err)
+	}
+
+	for name, want := range map[string]int{"": 1, "replica": 3} {
+		conn := a.NamedConnection(name)
+		if conn.Driver() != app.DriverSqlite {
+			t.Fatalf("connection %q: got driver %q", name, conn.Driver())
+		}
+		if err := conn.Connect(); err != nil {
+			t.Fatalf("connection %q: %v", name, err)
+		}
+		if got := conn.DB().Stats().MaxOpenConnections; got != want {
+			t.Errorf("connection %q: got MaxOpenConnections %d, want %d", name, got, want)
+		}
+	}
+
+	replica := a.NamedConnection("replica").DB()
+	if _, err := replica.Exec(`CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	if _, err := replica.Exec(`INSERT INTO notes (body) VALUES (?)`, "hello"); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	var body string
+	if err := replica.QueryRow(`SELECT body FROM notes WHERE id = 1`).Scan(&body); err != nil || body != "hello" {
+		t.Fatalf("expected the row back, got %q, %v", body, err)
+	}
+	if _, err := a.Connection().DB().Exec(`SELECT 1 FROM notes`); err == nil {
+		t.Fatal("expected the main database not to share the replica's tables")
+	}
+}
+
+func TestConnection_SqliteMemory(t *testing.T) {
+	conn := app.NewConnection()
+	conn.SetConfig(kernel.Dict{"Driver": "sqlite", "DBName": ":memory:"})
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer conn.Close()
+
+	// A single pooled connection keeps the one in-memory database.
+	if got := conn.DB().Stats().MaxOpenConnections; got != 1 {
+		t.Fatalf("got MaxOpenConnections %d, want 1", got)
+	}
+	if _, err := conn.DB().Exec(`CREATE TABLE t (v INTEGER)`); err != nil {
+		t.Fatalf("create table: %v", err)
+	}
+	var n int
+	if err := conn.DB().QueryRow(`SELECT count(*) FROM t`).Scan(&n); err != nil {
+		t.Fatalf("expected the table to stay, got %v", err)
+	}
+}
+
+func TestNewNamedConnection_Unknown(t *testing.T) {
+	if _, err := app.NewNamedConnection(kernel.Dict{"Driver": "sqlite"}, "replica"); err == nil {
+		t.Fatal("expected an error for an undefined connection")
+	}
+}
+
+func TestConnection_UnknownDriver(t *testing.T) {
+	conn := app.NewConnection()
+	conn.SetConfig(kernel.Dict{"Driver": "oracle", "DBName": "x"})
+	if err := conn.Connect(); err == nil || !strings.Contains(err.Error(), "unknown DB driver") {
+		t.Fatalf("expected an unknown driver error, got %v", err)
+	}
+}
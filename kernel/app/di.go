@@ -1,17 +1,69 @@
 package app
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/epicoon/lxgo/kernel"
 )
 
+// Provide registers how values of type T are made in c - ctor is either a
+// ready value of T (always a singleton) or a constructor returning T, or T
+// and an error. The constructor's parameters are resolved from c by their
+// types - kernel.IApp, kernel.IDIContainer and, for values that aren't
+// singletons, kernel.IHandleContext included:
+//
+//	// func NewSmtpMailer(app kernel.IApp) (*SmtpMailer, error)
+//	app.Provide[IMailer](c, NewSmtpMailer)
+//	// func NewUserService(m IMailer, ctx kernel.IHandleContext) *UserService
+//	app.Provide[*UserService](c, NewUserService, kernel.DI_REQUEST)
+//
+// lifetime defaults to kernel.DI_SINGLETON.
+func Provide[T any](c kernel.IDIContainer, ctor any, lifetime ...kernel.DILifetime) error {
+	l := kernel.DI_SINGLETON
+	if len(lifetime) > 0 {
+		l = lifetime[0]
+	}
+	return c.ProvideType(reflect.TypeFor[T](), ctor, l)
+}
+
+// Resolve returns the value of type T provided to c, creating it (and its
+// dependencies) as their lifetimes require - ctx is needed if a
+// request-scoped value is involved.
+func Resolve[T any](c kernel.IDIContainer, ctx ...kernel.IHandleContext) (T, error) {
+	var scope kernel.IHandleContext
+	if len(ctx) > 0 {
+		scope = ctx[0]
+	}
+	v, err := c.ResolveType(reflect.TypeFor[T](), scope)
+	if err != nil {
+		return *new(T), err
+	}
+	// v is nil if T is an interface and its constructor returned nil.
+	t, _ := v.(T)
+	return t, nil
+}
+
 /** @interface kernel.IDIContainer */
 
-// DIContainer is the default kernel.IDIContainer implementation.
+// DIContainer is the default kernel.IDIContainer implementation. Values
+// closed with it - or, for request-scoped ones, once their request is
+// handled - are the ones implementing io.Closer or having a Close() method.
 type DIContainer struct {
-	app  kernel.IApp
-	list kernel.CAnyList
+	app kernel.IApp
+
+	mu sync.Mutex
+	// providers are keyed by the reflect.Type they provide or, for the
+	// named ones, by their string key.
+	providers map[any]*diProvider
+	closers   []any
+	// subscribed is set once request scopes are closed on
+	// EVENT_APP_AFTER_HANDLE_REQUEST.
+	subscribed bool
 }
 
 var _ kernel.IDIContainer = (*DIContainer)(nil)
@@ -20,40 +72,392 @@ var _ kernel.IDIContainer = (*DIContainer)(nil)
 
 // NewDIContainer constructs a DIContainer bound to app.
 func NewDIContainer(app kernel.IApp) kernel.IDIContainer {
-	return &DIContainer{app: app}
+	return &DIContainer{
+		app:       app,
+		providers: make(map[any]*diProvider),
+	}
 }
 
-// Init registers list, replacing any previously registered factories.
+// Init registers list's factories as transient named providers, replacing
+// any previously registered named ones.
 func (c *DIContainer) Init(list kernel.CAnyList) {
-	c.list = list
+	c.mu.Lock()
+	for key := range c.providers {
+		if _, ok := key.(string); ok {
+			delete(c.providers, key)
+		}
+	}
+	c.mu.Unlock()
+	c.Register(list)
 }
 
-// Register adds list's factories to the container, failing if any key is already registered.
+// Register adds list's factories as transient named providers, failing if
+// any key is already provided - see ProvideKey for other lifetimes.
 func (c *DIContainer) Register(list kernel.CAnyList) error {
-	if c.list == nil {
-		c.list = make(kernel.CAnyList, len(list))
+	for key, factory := range list {
+		f := factory
+		if err := c.ProvideKey(key, func() any { return f() }, kernel.DI_TRANSIENT); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get resolves the value provided under key, or returns nil if key isn't
+// provided or can't be resolved without a request context - see
+// ResolveKey for the error.
+func (c *DIContainer) Get(key string) any {
+	v, err := c.ResolveKey(key, nil)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// ProvideType registers how values of type t are made - see Provide.
+// Fails if t is already provided or ctor doesn't make values of t.
+func (c *DIContainer) ProvideType(t reflect.Type, ctor any, lifetime kernel.DILifetime) error {
+	p, err := newDIProvider(t, ctor, lifetime)
+	if err != nil {
+		return err
+	}
+	return c.provide(t, p)
+}
+
+// ProvideKey registers how the value named key is made - like
+// ProvideType, but the value may be of any type. Fails if key is already
+// provided.
+func (c *DIContainer) ProvideKey(key string, ctor any, lifetime kernel.DILifetime) error {
+	p, err := newDIProvider(anyType, ctor, lifetime)
+	if err != nil {
+		return err
+	}
+	return c.provide(key, p)
+}
+
+// ResolveType resolves a value of type t - see Resolve. Errors name the
+// whole chain of dependencies that led to the failure.
+func (c *DIContainer) ResolveType(t reflect.Type, ctx kernel.IHandleContext) (any, error) {
+	v, err := c.resolve(t, ctx, nil, kernel.DI_TRANSIENT)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// ResolveKey resolves the value named key - see ResolveType.
+func (c *DIContainer) ResolveKey(key string, ctx kernel.IHandleContext) (any, error) {
+	c.mu.Lock()
+	p, ok := c.providers[key]
+	c.mu.Unlock()
+	path := []any{key}
+	if !ok {
+		return nil, diError("no provider for key", path)
+	}
+	v, err := c.instance(p, ctx, path, kernel.DI_TRANSIENT)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// Close closes the singletons created so far, the latest first.
+func (c *DIContainer) Close() error {
+	c.mu.Lock()
+	closers := c.closers
+	c.closers = nil
+	c.mu.Unlock()
+	return closeAll(closers)
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+var (
+	appType       = reflect.TypeFor[kernel.IApp]()
+	containerType = reflect.TypeFor[kernel.IDIContainer]()
+	contextType   = reflect.TypeFor[kernel.IHandleContext]()
+	errorType     = reflect.TypeFor[error]()
+	anyType       = reflect.TypeFor[any]()
+)
+
+type diProvider struct {
+	t        reflect.Type
+	lifetime kernel.DILifetime
+	ctor     reflect.Value
+	params   []reflect.Type
+
+	// mu guards a singleton's creation - value is set once created.
+	mu    sync.Mutex
+	value *reflect.Value
+}
+
+func newDIProvider(t reflect.Type, ctor any, lifetime kernel.DILifetime) (*diProvider, error) {
+	if lifetime < kernel.DI_SINGLETON || lifetime > kernel.DI_REQUEST {
+		return nil, fmt.Errorf("DI: unknown lifetime %d for %s", lifetime, t)
+	}
+	if ctor == nil {
+		return nil, fmt.Errorf("DI: nil constructor for %s", t)
 	}
 
-	for key, constructor := range list {
-		_, exists := c.list[key]
-		if exists {
-			return fmt.Errorf("DI-key '%s' already initialized", key)
+	p := &diProvider{t: t, lifetime: lifetime}
+	v := reflect.ValueOf(ctor)
+	if v.Kind() != reflect.Func || (t.Kind() == reflect.Func && v.Type().AssignableTo(t)) {
+		if !v.Type().AssignableTo(t) {
+			return nil, fmt.Errorf("DI: %s is neither %s nor its constructor", v.Type(), t)
+		}
+		if lifetime != kernel.DI_SINGLETON {
+			return nil, fmt.Errorf("DI: a ready value of %s can only be a singleton", t)
 		}
-		c.list[key] = constructor
+		p.value = &v
+		return p, nil
 	}
 
+	ft := v.Type()
+	if ft.IsVariadic() {
+		return nil, fmt.Errorf("DI: constructor of %s can not be variadic", t)
+	}
+	switch {
+	case ft.NumOut() == 1:
+	case ft.NumOut() == 2 && ft.Out(1) == errorType:
+	default:
+		return nil, fmt.Errorf("DI: constructor of %s must return it, or it and an error", t)
+	}
+	if !ft.Out(0).AssignableTo(t) {
+		return nil, fmt.Errorf("DI: constructor of %s returns %s", t, ft.Out(0))
+	}
+	for i := 0; i < ft.NumIn(); i++ {
+		p.params = append(p.params, ft.In(i))
+	}
+	p.ctor = v
+	return p, nil
+}
+
+// provide registers p under key - a reflect.Type or a string.
+func (c *DIContainer) provide(key any, p *diProvider) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.providers[key]; exists {
+		if _, named := key.(string); named {
+			return fmt.Errorf("DI: key '%s' is already provided", key)
+		}
+		return fmt.Errorf("DI: type %s is already provided", key)
+	}
+	c.providers[key] = p
+	if p.lifetime == kernel.DI_REQUEST && !c.subscribed && c.app != nil && c.app.Events() != nil {
+		c.app.Events().Subscribe(kernel.EVENT_APP_AFTER_HANDLE_REQUEST, func(e kernel.IEvent) {
+			if ctx, ok := e.Payload().Get("context").(kernel.IHandleContext); ok {
+				closeScope(ctx)
+			}
+		})
+		c.subscribed = true
+	}
 	return nil
 }
 
-// Get resolves the value registered under key by calling its factory, or
-// returns nil if key isn't registered.
-func (c *DIContainer) Get(key string) any {
-	f, ok := c.list[key]
+// resolve resolves t for a value of lifetime owner - path is the chain of
+// types (or a leading key) being resolved, for cycle detection and error
+// messages.
+func (c *DIContainer) resolve(
+	t reflect.Type,
+	ctx kernel.IHandleContext,
+	path []any,
+	owner kernel.DILifetime,
+) (reflect.Value, error) {
+	switch t {
+	case appType:
+		if c.app == nil {
+			return reflect.Value{}, diError("no application", append(path, t))
+		}
+		return reflect.ValueOf(&c.app).Elem(), nil
+	case containerType:
+		var ic kernel.IDIContainer = c
+		return reflect.ValueOf(&ic).Elem(), nil
+	case contextType:
+		if owner == kernel.DI_SINGLETON {
+			return reflect.Value{}, diError("a singleton can not depend on the request context", append(path, t))
+		}
+		if ctx == nil {
+			return reflect.Value{}, diError("no request context to resolve with", append(path, t))
+		}
+		return reflect.ValueOf(&ctx).Elem(), nil
+	}
+
+	for i, prev := range path {
+		if prev == t {
+			return reflect.Value{}, diError("dependency cycle", append(path[i:], t))
+		}
+	}
+	path = append(path, t)
+
+	c.mu.Lock()
+	p, ok := c.providers[t]
+	c.mu.Unlock()
 	if !ok {
-		return nil
+		return reflect.Value{}, diError("no provider for "+t.String(), path)
+	}
+	return c.instance(p, ctx, path, owner)
+}
+
+// instance returns p's value for a value of lifetime owner, creating it
+// if its lifetime requires.
+func (c *DIContainer) instance(
+	p *diProvider,
+	ctx kernel.IHandleContext,
+	path []any,
+	owner kernel.DILifetime,
+) (reflect.Value, error) {
+	if p.lifetime == kernel.DI_REQUEST && owner == kernel.DI_SINGLETON {
+		return reflect.Value{}, diError("a singleton can not depend on a request-scoped value", path)
+	}
+
+	switch p.lifetime {
+	case kernel.DI_SINGLETON:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.value != nil {
+			return *p.value, nil
+		}
+		v, err := c.create(p, ctx, path, owner)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p.value = &v
+		c.mu.Lock()
+		c.closers = appendCloser(c.closers, v)
+		c.mu.Unlock()
+		return v, nil
+
+	case kernel.DI_REQUEST:
+		if ctx == nil {
+			return reflect.Value{}, diError("no request context to resolve with", path)
+		}
+		scope := requestScope(ctx)
+		scope.mu.Lock()
+		v, ok := scope.values[p]
+		scope.mu.Unlock()
+		if ok {
+			return v, nil
+		}
+		// Built unlocked - its request-scoped dependencies go through the
+		// same scope.
+		v, err := c.create(p, ctx, path, owner)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		scope.mu.Lock()
+		if existing, ok := scope.values[p]; ok {
+			// Built concurrently for the same request - keep the first.
+			scope.mu.Unlock()
+			closeAll(appendCloser(nil, v))
+			return existing, nil
+		}
+		scope.values[p] = v
+		scope.closers = appendCloser(scope.closers, v)
+		scope.mu.Unlock()
+		return v, nil
+	}
+
+	return c.create(p, ctx, path, owner)
+}
+
+// create calls p's constructor with its dependencies resolved - a
+// transient value's dependencies are bound by the lifetime of owner, the
+// value it is made for.
+func (c *DIContainer) create(
+	p *diProvider,
+	ctx kernel.IHandleContext,
+	path []any,
+	owner kernel.DILifetime,
+) (reflect.Value, error) {
+	if p.lifetime != kernel.DI_TRANSIENT {
+		owner = p.lifetime
+	}
+	args := make([]reflect.Value, len(p.params))
+	for i, param := range p.params {
+		arg, err := c.resolve(param, ctx, path, owner)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = arg
+	}
+
+	out := p.ctor.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, diError(out[1].Interface().(error).Error(), path)
+	}
+	v := reflect.New(p.t).Elem()
+	v.Set(out[0])
+	return v, nil
+}
+
+// diError formats err along the chain of types (or a leading key) that
+// led to it, e.g. "DI: dependency cycle: *A -> *B -> *A".
+func diError(msg string, path []any) error {
+	names := make([]string, len(path))
+	for i, k := range path {
+		names[i] = fmt.Sprint(k)
+	}
+	return fmt.Errorf("DI: %s: %s", msg, strings.Join(names, " -> "))
+}
+
+// diScope holds a request's request-scoped values.
+type diScope struct {
+	mu      sync.Mutex
+	values  map[*diProvider]reflect.Value
+	closers []any
+}
+
+type diScopeKey struct{}
+
+func requestScope(ctx kernel.IHandleContext) *diScope {
+	if scope, ok := ctx.Get(diScopeKey{}).(*diScope); ok {
+		return scope
 	}
+	scope := &diScope{values: make(map[*diProvider]reflect.Value)}
+	ctx.Set(diScopeKey{}, scope)
+	return scope
+}
 
-	result := f()
+// closeScope closes ctx's request-scoped values, if any.
+func closeScope(ctx kernel.IHandleContext) {
+	scope, ok := ctx.Get(diScopeKey{}).(*diScope)
+	if !ok {
+		return
+	}
+	scope.mu.Lock()
+	closers := scope.closers
+	scope.closers = nil
+	scope.mu.Unlock()
+	if err := closeAll(closers); err != nil && ctx.App() != nil {
+		ctx.App().LogError(fmt.Sprintf("Could not close request-scoped values: %v", err), "DI")
+	}
+}
 
-	return result
+func appendCloser(closers []any, v reflect.Value) []any {
+	if !v.IsValid() || (v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer) && v.IsNil() {
+		return closers
+	}
+	switch c := v.Interface().(type) {
+	case io.Closer, interface{ Close() }:
+		return append(closers, c)
+	}
+	return closers
+}
+
+// closeAll closes closers, the latest first.
+func closeAll(closers []any) error {
+	var errs []error
+	for i := len(closers) - 1; i >= 0; i-- {
+		switch c := closers[i].(type) {
+		case io.Closer:
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		case interface{ Close() }:
+			c.Close()
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,292 @@
+package app_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+type diStore struct {
+	app    kernel.IApp
+	closed bool
+}
+
+func (s *diStore) Close() error {
+	s.closed = true
+	return nil
+}
+
+type diService struct {
+	store *diStore
+	ctx   kernel.IHandleContext
+}
+
+func (s *diService) Close() { s.ctx.Set("service closed", true) }
+
+type diResource struct {
+	*lxHttp.Resource
+	run func(ctx kernel.IHandleContext)
+}
+
+func (r *diResource) Run() kernel.IHttpResponse {
+	r.run(r.Context())
+	return r.JsonResponse(kernel.JsonResponseConfig{Data: "ok"})
+}
+
+type diSession struct {
+	ctx kernel.IHandleContext
+}
+
+func (s *diSession) Close() { s.ctx.Set("session closed", true) }
+
+type diCycleA struct{}
+type diCycleB struct{}
+
+func newDIApp(t *testing.T) kernel.IApp {
+	t.Helper()
+	a, err := apptest.New()
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	return a
+}
+
+func TestDIContainer_Lifetimes(t *testing.T) {
+	a := newDIApp(t)
+	c := a.DIContainer()
+	if err := app.Provide[*diStore](c, func(a kernel.IApp) *diStore { return &diStore{app: a} }); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Provide[*diService](c, func(s *diStore) *diService { return &diService{store: s} }, kernel.DI_TRANSIENT); err != nil {
+		t.Fatal(err)
+	}
+
+	s1, err := app.Resolve[*diService](c)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	s2, _ := app.Resolve[*diService](c)
+	if s1 == s2 {
+		t.Fatal("expected a new transient value per resolve")
+	}
+	if s1.store != s2.store || s1.store.app != a {
+		t.Fatal("expected a single auto-wired store")
+	}
+
+	if err := app.Provide[*diStore](c, &diStore{}); err == nil {
+		t.Fatal("expected an error for a type provided twice")
+	}
+	if err := app.Provide[*diStore](c, func() string { return "" }); err == nil {
+		t.Fatal("expected an error for a constructor of another type")
+	}
+
+	a.Final()
+	if !s1.store.closed {
+		t.Fatal("expected the singleton to be closed on Final")
+	}
+}
+
+func TestDIContainer_Errors(t *testing.T) {
+	c := newDIApp(t).DIContainer()
+	app.Provide[*diCycleA](c, func(*diCycleB) *diCycleA { return nil })
+	app.Provide[*diCycleB](c, func(*diCycleA) *diCycleB { return nil })
+	app.Provide[*diService](c, func(ctx kernel.IHandleContext) *diService { return nil })
+	app.Provide[*diStore](c, func(*diService) *diStore { return nil })
+
+	cases := map[string]func() error{
+		"dependency cycle: *app_test.diCycleA -> *app_test.diCycleB -> *app_test.diCycleA": func() error {
+			_, err := app.Resolve[*diCycleA](c)
+			return err
+		},
+		"no provider for string": func() error {
+			_, err := app.Resolve[string](c)
+			return err
+		},
+		"a singleton can not depend on the request context": func() error {
+			_, err := app.Resolve[*diStore](c)
+			return err
+		},
+	}
+	for want, resolve := range cases {
+		if err := resolve(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q, got %v", want, err)
+		}
+	}
+}
+
+func TestDIContainer_RequestScope(t *testing.T) {
+	a := newDIApp(t)
+	c := a.DIContainer()
+	app.Provide[*diStore](c, &diStore{})
+	if err := app.Provide[*diService](c, func(s *diStore, ctx kernel.IHandleContext) *diService {
+		return &diService{store: s, ctx: ctx}
+	}, kernel.DI_REQUEST); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu       sync.Mutex
+		contexts []kernel.IHandleContext
+	)
+	a.Router().RegisterResource("/scoped", "GET", func() kernel.IHttpResource {
+		return &diResource{Resource: lxHttp.NewResource(), run: func(ctx kernel.IHandleContext) {
+			s1, err := app.Resolve[*diService](c, ctx)
+			if err != nil {
+				t.Errorf("Resolve: %v", err)
+				return
+			}
+			s2, _ := app.Resolve[*diService](c, ctx)
+			if s1 != s2 || s1.ctx != ctx {
+				t.Error("expected a single value per request")
+			}
+			mu.Lock()
+			contexts = append(contexts, ctx)
+			mu.Unlock()
+		}}
+	})
+
+	for i := 0; i < 2; i++ {
+		a.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/scoped", nil))
+	}
+	if len(contexts) != 2 || contexts[0] == contexts[1] {
+		t.Fatalf("expected two requests, got %d", len(contexts))
+	}
+	for _, ctx := range contexts {
+		if ctx.Get("service closed") != true {
+			t.Fatal("expected the request-scoped value to be closed after the request")
+		}
+	}
+
+	if _, err := app.Resolve[*diService](c); err == nil {
+		t.Fatal("expected an error resolving a request-scoped value without a request")
+	}
+}
+
+func TestDIContainer_RequestDependsOnRequest(t *testing.T) {
+	a := newDIApp(t)
+	c := a.DIContainer()
+	app.Provide[*diSession](c, func(ctx kernel.IHandleContext) *diSession {
+		return &diSession{ctx: ctx}
+	}, kernel.DI_REQUEST)
+	app.Provide[*diService](c, func(s *diSession, ctx kernel.IHandleContext) *diService {
+		return &diService{ctx: s.ctx}
+	}, kernel.DI_REQUEST)
+
+	var ctx kernel.IHandleContext
+	a.Router().RegisterResource("/scoped", "GET", func() kernel.IHttpResource {
+		return &diResource{Resource: lxHttp.NewResource(), run: func(rCtx kernel.IHandleContext) {
+			if _, err := app.Resolve[*diService](c, rCtx); err != nil {
+				t.Errorf("Resolve: %v", err)
+			}
+			s1, _ := app.Resolve[*diSession](c, rCtx)
+			s2, _ := app.Resolve[*diSession](c, rCtx)
+			if s1 == nil || s1 != s2 {
+				t.Error("expected a single dependency per request")
+			}
+			ctx = rCtx
+		}}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		a.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/scoped", nil))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the request to be handled, it deadlocked")
+	}
+	if ctx == nil || ctx.Get("service closed") != true || ctx.Get("session closed") != true {
+		t.Fatal("expected both request-scoped values to be closed after the request")
+	}
+}
+
+func TestDIContainer_Keys(t *testing.T) {
+	a := newDIApp(t)
+	c := a.DIContainer()
+	c.Register(kernel.CAnyList{"store": func(...any) any { return &diStore{} }})
+	s1, ok := c.Get("store").(*diStore)
+	if !ok {
+		t.Fatal("expected the keyed factory to keep working")
+	}
+	if s2 := c.Get("store"); s2 == s1 {
+		t.Fatal("expected a registered factory to be called on every Get")
+	}
+	if err := c.Register(kernel.CAnyList{"store": func(...any) any { return nil }}); err == nil {
+		t.Fatal("expected an error for a key registered twice")
+	}
+	if c.Get("unknown") != nil {
+		t.Fatal("expected nil for an unknown key")
+	}
+
+	c.Init(kernel.CAnyList{"other": func(...any) any { return "other" }})
+	if c.Get("store") != nil || c.Get("other") != "other" {
+		t.Fatal("expected Init to replace the keyed factories")
+	}
+
+	if err := c.ProvideKey("shared", func(a kernel.IApp) *diStore { return &diStore{app: a} }, kernel.DI_SINGLETON); err != nil {
+		t.Fatal(err)
+	}
+	shared, _ := c.Get("shared").(*diStore)
+	if shared == nil || shared.app != a || c.Get("shared") != shared {
+		t.Fatal("expected a single auto-wired value under the key")
+	}
+	a.Final()
+	if !shared.closed {
+		t.Fatal("expected the keyed singleton to be closed on Final")
+	}
+}
+
+func TestDIContainer_KeysRequestScope(t *testing.T) {
+	a := newDIApp(t)
+	c := a.DIContainer()
+	if err := c.ProvideKey("service", func(ctx kernel.IHandleContext) *diService {
+		return &diService{ctx: ctx}
+	}, kernel.DI_REQUEST); err != nil {
+		t.Fatal(err)
+	}
+
+	var ctx kernel.IHandleContext
+	a.Router().RegisterResource("/scoped", "GET", func() kernel.IHttpResource {
+		return &diResource{Resource: lxHttp.NewResource(), run: func(rCtx kernel.IHandleContext) {
+			s1, err := c.ResolveKey("service", rCtx)
+			if err != nil {
+				t.Errorf("ResolveKey: %v", err)
+				return
+			}
+			if s2, _ := c.ResolveKey("service", rCtx); s1 != s2 {
+				t.Error("expected a single value per request")
+			}
+			ctx = rCtx
+		}}
+	})
+	a.Router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/scoped", nil))
+	if ctx == nil || ctx.Get("service closed") != true {
+		t.Fatal("expected the keyed request-scoped value to be closed after the request")
+	}
+
+	if _, err := c.ResolveKey("service", nil); err == nil {
+		t.Fatal("expected an error resolving a request-scoped value without a request")
+	}
+	if c.Get("service") != nil {
+		t.Fatal("expected nil from Get for a value it can't resolve")
+	}
+}
+
+func TestResolve_NilInterface(t *testing.T) {
+	c := newDIApp(t).DIContainer()
+	app.Provide[kernel.IForm](c, func() kernel.IForm { return nil }, kernel.DI_TRANSIENT)
+
+	f, err := app.Resolve[kernel.IForm](c)
+	if err != nil || f != nil {
+		t.Fatalf("expected a nil value, got %v, %v", f, err)
+	}
+}
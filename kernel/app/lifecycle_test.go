@@ -0,0 +1,160 @@
+package app_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/apptest"
+)
+
+// errorLogger is a kernel.ILogger remembering the errors written.
+type errorLogger struct {
+	errors []string
+}
+
+func (l *errorLogger) Log(msg, category string)        {}
+func (l *errorLogger) LogWarning(msg, category string) {}
+func (l *errorLogger) LogError(msg, category string)   { l.errors = append(l.errors, msg) }
+
+// lifecycleComponent records its Run and Final calls into events.
+type lifecycleComponent struct {
+	*app.AppComponent
+	name    string
+	deps    []any
+	events  *[]string
+	run     func() error
+	timeout time.Duration
+}
+
+func (c *lifecycleComponent) Name() string     { return c.name }
+func (c *lifecycleComponent) DependsOn() []any { return c.deps }
+
+func (c *lifecycleComponent) Run() error {
+	*c.events = append(*c.events, "run "+c.name)
+	if c.run != nil {
+		return c.run()
+	}
+	return nil
+}
+
+func (c *lifecycleComponent) Final() error {
+	*c.events = append(*c.events, "final "+c.name)
+	return nil
+}
+
+type timedComponent struct {
+	*lifecycleComponent
+}
+
+func (c *timedComponent) StartTimeout() time.Duration { return c.timeout }
+
+func newLifecycleApp(t *testing.T, components ...kernel.IAppComponent) (kernel.IApp, *errorLogger) {
+	t.Helper()
+	a, err := apptest.New()
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	logger := &errorLogger{}
+	a.SetLogger(logger)
+	for _, c := range components {
+		a.SetComponent(c.Name(), c)
+	}
+	return a, logger
+}
+
+func TestApp_ComponentOrder(t *testing.T) {
+	var events []string
+	comp := func(name string, deps ...any) *lifecycleComponent {
+		return &lifecycleComponent{AppComponent: app.NewAppComponent(), name: name, deps: deps, events: &events}
+	}
+	db := comp("db")
+	db.run = func() error { return errors.New("connection refused") }
+	a, logger := newLifecycleApp(t, comp("metrics"), comp("ws", "session"), comp("session", "db"), db)
+
+	a.Run()
+	if !slices.Equal(events, []string{"run metrics", "run db"}) {
+		t.Fatalf("expected the dependencies to start first and startup to stop on failure, got %v", events)
+	}
+	want := "Could not start app component 'db' (required by 'ws' -> 'session'): connection refused"
+	if len(logger.errors) != 1 || logger.errors[0] != want {
+		t.Fatalf("expected %q, got %v", want, logger.errors)
+	}
+
+	events = nil
+	a.Final()
+	if !slices.Equal(events, []string{"final ws", "final session", "final db", "final metrics"}) {
+		t.Fatalf("expected the components to stop in reverse, got %v", events)
+	}
+}
+
+func TestApp_ComponentOrderErrors(t *testing.T) {
+	var events []string
+	comp := func(name string, deps ...any) *lifecycleComponent {
+		return &lifecycleComponent{AppComponent: app.NewAppComponent(), name: name, deps: deps, events: &events}
+	}
+
+	cases := map[string][]kernel.IAppComponent{
+		"dependency cycle: 'a' -> 'b' -> 'a'":                    {comp("a", "b"), comp("b", "a")},
+		"component 'db' is not registered (required by 'cache')": {comp("cache", "db")},
+	}
+	for want, components := range cases {
+		events = nil
+		a, logger := newLifecycleApp(t, components...)
+		a.Run()
+		if len(events) != 0 {
+			t.Errorf("expected no component to start, got %v", events)
+		}
+		if len(logger.errors) != 1 || !strings.Contains(logger.errors[0], want) {
+			t.Errorf("expected %q, got %v", want, logger.errors)
+		}
+	}
+}
+
+func TestApp_ComponentStartTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	var events []string
+	slow := &timedComponent{&lifecycleComponent{
+		AppComponent: app.NewAppComponent(),
+		name:         "slow",
+		events:       &events,
+		timeout:      10 * time.Millisecond,
+		run: func() error {
+			<-release
+			return nil
+		},
+	}}
+	a, logger := newLifecycleApp(t, slow)
+
+	a.Run()
+	if len(logger.errors) != 1 || !strings.Contains(logger.errors[0], "'slow': not started within 10ms") {
+		t.Fatalf("expected a start timeout, got %v", logger.errors)
+	}
+
+	if _, err := apptest.New(kernel.Dict{"ComponentStartTimeout": "soon"}); err == nil {
+		t.Fatal("expected an error for an invalid ComponentStartTimeout")
+	}
+}
+
+func TestApp_ComponentRunPanic(t *testing.T) {
+	var events []string
+	broken := &lifecycleComponent{
+		AppComponent: app.NewAppComponent(),
+		name:         "broken",
+		events:       &events,
+		run:          func() error { panic("boom") },
+	}
+	a, logger := newLifecycleApp(t, broken)
+
+	a.Run()
+	if len(logger.errors) != 1 || !strings.Contains(logger.errors[0], "'broken': panic: boom") ||
+		!strings.Contains(logger.errors[0], "runtime/debug.Stack") {
+		t.Fatalf("expected the panic with its stack, got %v", logger.errors)
+	}
+}
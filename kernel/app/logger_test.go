@@ -0,0 +1,116 @@
+package app_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+	"github.com/epicoon/lxgo/kernel/logger"
+)
+
+type loggingResource struct {
+	*lxHttp.Resource
+}
+
+func (r *loggingResource) Run() kernel.IHttpResponse {
+	r.Context().Logger().Info("handled", "user", 7)
+	r.LogError("something failed", "Payments")
+	return r.JsonResponse(kernel.JsonResponseConfig{})
+}
+
+// TestInitApp_Logger checks the whole chain: InitApp builds the logger from
+// the "Logger" config section, components log through it under their own
+// category, and request-scoped entries carry route, method and request ID.
+func TestInitApp_Logger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	a, err := apptest.New(kernel.Dict{
+		"Logger": kernel.Dict{
+			"Level":      "warning",
+			"Format":     "json",
+			"Output":     path,
+			"Categories": kernel.Dict{"HttpHandling": "info", "NamedComponent": "info"},
+		},
+		"Components": kernel.Dict{"Named": kernel.Dict{}},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if _, ok := a.Logger().(*logger.Logger); !ok {
+		t.Fatalf("expected a *logger.Logger, got %T", a.Logger())
+	}
+
+	c := &namedComponent{AppComponent: app.NewAppComponent()}
+	if err := app.RegisterComponent(a, c, "named", "Components.Named"); err != nil {
+		t.Fatalf("RegisterComponent: %v", err)
+	}
+	c.Log("component %s", "started")
+	c.Logger().Info("with fields", "n", 1)
+	a.Log("dropped", "App")
+
+	a.Router().RegisterResource("/orders/{id:int}", "GET", func() kernel.IHttpResource {
+		return &loggingResource{Resource: lxHttp.NewResource()}
+	})
+	srv := apptest.Server(a)
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL+"/orders/5", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Request-ID") != "req-1" {
+		t.Errorf("expected the request ID to be echoed back, got %q", resp.Header.Get("X-Request-ID"))
+	}
+
+	a.Final()
+
+	entries := readJSONLines(t, path)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d: %v", len(entries), entries)
+	}
+
+	expect := []map[string]any{
+		{"level": "info", "category": "NamedComponent", "msg": "component started"},
+		{"level": "info", "category": "NamedComponent", "msg": "with fields", "n": float64(1)},
+		{"level": "info", "category": "HttpHandling", "msg": "handled", "user": float64(7),
+			"route": "/orders/{id:int}", "method": "GET", "request_id": "req-1"},
+		{"level": "error", "category": "Payments", "route": "/orders/{id:int}", "request_id": "req-1",
+			"msg": "Error occurred while '/orders/{id:int}' handling: something failed"},
+	}
+	for i, want := range expect {
+		for k, v := range want {
+			if entries[i][k] != v {
+				t.Errorf("entry %d: %s = %v, want %v", i, k, entries[i][k], v)
+			}
+		}
+	}
+}
+
+func readJSONLines(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open log file: %v", err)
+	}
+	defer f.Close()
+
+	var entries []map[string]any
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("not a JSON line %q: %v", sc.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
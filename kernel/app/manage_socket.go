@@ -16,6 +16,39 @@ import (
 	"github.com/epicoon/lxgo/kernel/internal/manage/trigger"
 )
 
+// FManageCommand handles a manage-socket command registered with
+// RegisterManageCommand - params are the "key=value" pairs sent after the
+// command's name, the returned text is the reply.
+type FManageCommand func(params map[string]string) string
+
+// builtinManageCommands are the commands the manage socket always has.
+var builtinManageCommands = map[string]bool{"status": true, "reconf": true, "inconf": true, "trigger": true}
+
+// RegisterManageCommand adds a command to app's manage socket, for
+// components to be managed from outside the process - e.g. the scheduler's
+// "jobs". Fails if the name is taken or app isn't based on *App.
+func RegisterManageCommand(app kernel.IApp, name string, f FManageCommand) error {
+	a, ok := app.BaseApp().(*App)
+	if !ok {
+		return fmt.Errorf("can not register manage command '%s': the application is not based on *app.App", name)
+	}
+
+	a.manageMu.Lock()
+	defer a.manageMu.Unlock()
+	if _, exists := a.manageCommands[name]; exists || builtinManageCommands[name] {
+		return fmt.Errorf("manage command '%s' already registered", name)
+	}
+	if a.manageCommands == nil {
+		a.manageCommands = make(map[string]FManageCommand)
+	}
+	a.manageCommands[name] = f
+	return nil
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
 type manageSocket struct {
 	app        kernel.IApp
 	socketPath string
@@ -102,11 +135,33 @@ func (m *manageSocket) handleConn(conn net.Conn) {
 		case "trigger":
 			trigger.Run(m.app, conn, cmdList[1:])
 		default:
-			conn.Write([]byte("unknown command\n"))
+			f := m.command(cmd)
+			if f == nil {
+				conn.Write([]byte("unknown command\n"))
+				continue
+			}
+			params := make(map[string]string, len(cmdList)-1)
+			for _, str := range cmdList[1:] {
+				if pair := strings.SplitN(str, "=", 2); len(pair) == 2 {
+					params[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+				}
+			}
+			conn.Write([]byte(strings.TrimRight(f(params), "\n") + "\n"))
 		}
 	}
 }
 
+// command returns the registered command named name, or nil.
+func (m *manageSocket) command(name string) FManageCommand {
+	a, ok := m.app.BaseApp().(*App)
+	if !ok {
+		return nil
+	}
+	a.manageMu.RLock()
+	defer a.manageMu.RUnlock()
+	return a.manageCommands[name]
+}
+
 func (m *manageSocket) Final() {
 	close(m.stopCh)
 	if m.listener != nil {
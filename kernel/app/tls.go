@@ -0,0 +1,176 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/cast"
+	"github.com/epicoon/lxgo/kernel/config"
+)
+
+// TLSConfig configures HTTPS serving - the "TLS" config section. Paths are
+// relative to the app root.
+type TLSConfig struct {
+	// CertFile, KeyFile are the PEM certificate (chain) and private key -
+	// re-read when the config is refreshed via the manage socket.
+	CertFile string
+	KeyFile  string
+	// ClientAuth is the client certificate (mTLS) mode: "none" (default),
+	// "request", "require", "verify" (verify if given) or "require-and-verify".
+	ClientAuth string
+	// ClientCAFile is the PEM bundle client certificates are verified against.
+	ClientCAFile string
+	// MinVersion is "1.2" (default) or "1.3".
+	MinVersion string
+	// RedirectPort, if set, is a plain HTTP port redirecting to HTTPS.
+	RedirectPort int
+}
+
+// ServerConfig configures the app's http.Server - the "Server" config
+// section. Timeouts are in seconds; zero means no timeout.
+type ServerConfig struct {
+	ReadTimeout       int
+	ReadHeaderTimeout int
+	WriteTimeout      int
+	IdleTimeout       int
+}
+
+// AppTLSConfig returns the TLS settings app serves HTTPS with, for other
+// listeners to reuse (e.g. the ws component's) - nil if app has no "TLS"
+// config section or isn't based on *App. The certificate it serves follows
+// the app's, reloads included.
+func AppTLSConfig(app kernel.IApp) *tls.Config {
+	a, ok := app.BaseApp().(*App)
+	if !ok || a.tls == nil {
+		return nil
+	}
+	return a.tls.config.Clone()
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// tlsSettings holds the app's TLS config and its current certificate.
+type tlsSettings struct {
+	app    kernel.IApp
+	conf   TLSConfig
+	config *tls.Config
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"":                   tls.NoClientCert,
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify":             tls.VerifyClientCertIfGiven,
+	"require-and-verify": tls.RequireAndVerifyClientCert,
+}
+
+// newTLSSettings loads the certificate and client CAs the "TLS" config
+// section c points at.
+func newTLSSettings(app kernel.IApp, c kernel.Dict) (*tlsSettings, error) {
+	s := &tlsSettings{app: app}
+	if err := cast.DictToStruct(&c, &s.conf); err != nil {
+		return nil, err
+	}
+	if s.conf.CertFile == "" || s.conf.KeyFile == "" {
+		return nil, errors.New("CertFile and KeyFile are required")
+	}
+
+	clientAuth, ok := clientAuthTypes[strings.ToLower(s.conf.ClientAuth)]
+	if !ok {
+		return nil, fmt.Errorf("unknown ClientAuth mode '%s'", s.conf.ClientAuth)
+	}
+	s.config = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		ClientAuth:     clientAuth,
+		GetCertificate: s.getCertificate,
+	}
+	switch s.conf.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		s.config.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported MinVersion '%s'", s.conf.MinVersion)
+	}
+
+	if s.conf.ClientCAFile != "" {
+		pem, err := os.ReadFile(app.Pathfinder().GetAbsPath(s.conf.ClientCAFile))
+		if err != nil {
+			return nil, fmt.Errorf("can not read ClientCAFile: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("ClientCAFile holds no PEM certificates")
+		}
+		s.config.ClientCAs = pool
+	} else if clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert {
+		return nil, fmt.Errorf("ClientAuth '%s' requires ClientCAFile", s.conf.ClientAuth)
+	}
+
+	if err := s.loadCertificate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// reload re-reads the certificate the app's current config points at,
+// keeping the one in use if that fails.
+func (s *tlsSettings) reload() {
+	if c, err := config.GetParam[kernel.Dict](s.app.Config(), "TLS"); err == nil {
+		var conf TLSConfig
+		if err := cast.DictToStruct(&c, &conf); err == nil && conf.CertFile != "" && conf.KeyFile != "" {
+			s.conf.CertFile, s.conf.KeyFile = conf.CertFile, conf.KeyFile
+		}
+	}
+	if err := s.loadCertificate(); err != nil {
+		s.app.LogError(fmt.Sprintf("Could not reload TLS certificate: %v", err), "App")
+		return
+	}
+	s.app.Log("TLS certificate reloaded", "App")
+}
+
+func (s *tlsSettings) loadCertificate() error {
+	pf := s.app.Pathfinder()
+	cert, err := tls.LoadX509KeyPair(pf.GetAbsPath(s.conf.CertFile), pf.GetAbsPath(s.conf.KeyFile))
+	if err != nil {
+		return fmt.Errorf("can not load TLS certificate: %w", err)
+	}
+	s.mu.Lock()
+	s.cert = &cert
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *tlsSettings) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert, nil
+}
+
+// redirectHandler redirects every request to the same URL over HTTPS on port.
+func redirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,156 @@
+package app_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+// writeCert writes a self-signed localhost certificate with the given
+// serial number into dir, returning it parsed.
+func writeCert(t *testing.T, dir string, serial int64) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600)
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+func TestInitApp_TLS(t *testing.T) {
+	dir := t.TempDir()
+	first := writeCert(t, dir, 1)
+	a, err := apptest.New(kernel.Dict{
+		"TLS": kernel.Dict{
+			"CertFile": filepath.Join(dir, "cert.pem"),
+			"KeyFile":  filepath.Join(dir, "key.pem"),
+		},
+		"Server": kernel.Dict{"ReadHeaderTimeout": 5},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	a.Router().RegisterResource("/text", "GET", func() kernel.IHttpResource {
+		return &textResource{Resource: lxHttp.NewResource()}
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: a.Router(), TLSConfig: app.AppTLSConfig(a)}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	get := func(root *x509.Certificate) (*http.Response, error) {
+		roots := x509.NewCertPool()
+		roots.AddCert(root)
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: roots},
+			ForceAttemptHTTP2: true,
+		}}
+		return client.Get("https://" + ln.Addr().String() + "/text")
+	}
+
+	resp, err := get(first)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+		t.Fatalf("got %d over %s, want 200 over HTTP/2", resp.StatusCode, resp.Proto)
+	}
+
+	t.Run("reload", func(t *testing.T) {
+		second := writeCert(t, dir, 2)
+		a.Events().Trigger(kernel.EVENT_CONFIG_REFRESHED)
+
+		resp, err := get(second)
+		if err != nil {
+			t.Fatalf("expected the reloaded certificate to be served: %v", err)
+		}
+		resp.Body.Close()
+		if _, err := get(first); err == nil {
+			t.Fatal("expected the old certificate to be gone")
+		}
+	})
+}
+
+func TestInitApp_InvalidTLS(t *testing.T) {
+	dir := t.TempDir()
+	writeCert(t, dir, 1)
+	files := kernel.Dict{"CertFile": filepath.Join(dir, "cert.pem"), "KeyFile": filepath.Join(dir, "key.pem")}
+
+	cases := map[string]kernel.Dict{
+		"missing files":   {"CertFile": filepath.Join(dir, "none.pem"), "KeyFile": filepath.Join(dir, "key.pem")},
+		"no CA":           {"ClientAuth": "require-and-verify"},
+		"unknown mode":    {"ClientAuth": "sometimes"},
+		"unknown version": {"MinVersion": "1.1"},
+	}
+	for name, conf := range cases {
+		t.Run(name, func(t *testing.T) {
+			tlsConf := kernel.Dict{}
+			for k, v := range files {
+				tlsConf[k] = v
+			}
+			for k, v := range conf {
+				tlsConf[k] = v
+			}
+			_, err := apptest.New(kernel.Dict{"TLS": tlsConf})
+			if err == nil || !strings.Contains(err.Error(), "TLS") {
+				t.Fatalf("expected a TLS config error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAppTLSConfig_NotConfigured(t *testing.T) {
+	a, err := apptest.New()
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if app.AppTLSConfig(a) != nil {
+		t.Fatal("expected no TLS config")
+	}
+}
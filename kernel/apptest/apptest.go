@@ -2,13 +2,14 @@
 // integration tests - New builds and initializes a ready-to-use app.App
 // from an in-memory config (no config.yaml file needed); register the
 // component under test on it via app.RegisterComponent (or register
-// routes/middleware directly on its Router()), then use Server to get a
-// real net/http test server for HTTP round-trips.
+// routes/middleware directly on its Router()), or build both at once with
+// NewComponent, then use Server to get a real net/http test server for
+// HTTP round-trips.
 package apptest
 
 import (
-	"net/http"
 	"net/http/httptest"
+	"testing"
 
 	"github.com/epicoon/lxgo/kernel"
 	"github.com/epicoon/lxgo/kernel/app"
@@ -35,12 +36,48 @@ func New(cfg ...kernel.Dict) (kernel.IApp, error) {
 }
 
 // Server wraps a's router in a real net/http test server, listening on a
-// free local port - the caller must Close() it when done. Panics if a
-// wasn't built by New (its router doesn't implement http.Handler).
+// free local port - the caller must Close() it when done.
 func Server(a kernel.IApp) *httptest.Server {
-	handler, ok := a.Router().(http.Handler)
-	if !ok {
-		panic("apptest: app's router does not implement http.Handler")
+	return httptest.NewServer(a.Router())
+}
+
+// NewComponent builds an app like New, with conf as its
+// "Components.<name>" section, and sets a component up on it - see
+// SetComponent. t fails on any error:
+//
+//	a, c := apptest.NewComponent(t, "Cache", kernel.Dict{"TTL": 60}, cache.SetAppComponent, cache.AppComponent)
+func NewComponent[T any](
+	t testing.TB,
+	name string,
+	conf kernel.Dict,
+	set func(app kernel.IApp, configKey string) error,
+	get func(app kernel.IApp) (T, error),
+) (kernel.IApp, T) {
+	t.Helper()
+	a, err := New(kernel.Dict{"Components": kernel.Dict{name: conf}})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	return a, SetComponent(t, a, "Components."+name, set, get)
+}
+
+// SetComponent registers a component on a with set (a package's
+// SetAppComponent) from the config under configKey, and returns it as get
+// (the package's AppComponent) does. t fails on any error.
+func SetComponent[T any](
+	t testing.TB,
+	a kernel.IApp,
+	configKey string,
+	set func(app kernel.IApp, configKey string) error,
+	get func(app kernel.IApp) (T, error),
+) T {
+	t.Helper()
+	if err := set(a, configKey); err != nil {
+		t.Fatalf("SetAppComponent: %v", err)
+	}
+	c, err := get(a)
+	if err != nil {
+		t.Fatalf("AppComponent: %v", err)
 	}
-	return httptest.NewServer(handler)
+	return c
 }
@@ -2,7 +2,10 @@ package apptest_test
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/epicoon/lxgo/kernel"
@@ -89,6 +92,52 @@ func TestServer_RoundTrip(t *testing.T) {
 	}
 }
 
+// TestServer_IndependentApps checks that apps in one process don't share
+// routes or assets - each router serves from its own mux.
+func TestServer_IndependentApps(t *testing.T) {
+	get := func(srv string, path string) (int, string) {
+		resp, err := http.Get(srv + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	var urls []string
+	for _, content := range []string{"first", "second"} {
+		a, err := apptest.New()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "app.txt"), []byte(content), 0o644)
+		a.Router().RegisterFileAssets(map[string]string{"/assets/": dir})
+		if content == "first" {
+			a.Router().RegisterResource("/echo", "GET", func() kernel.IHttpResource {
+				return &echoResource{Resource: lxHttp.NewResource()}
+			})
+		}
+
+		srv := apptest.Server(a)
+		defer srv.Close()
+		urls = append(urls, srv.URL)
+	}
+
+	for i, want := range []string{"first", "second"} {
+		if code, body := get(urls[i], "/assets/app.txt"); code != http.StatusOK || body != want {
+			t.Fatalf("app %d: got %d %q, want %q", i, code, body, want)
+		}
+	}
+	if code, _ := get(urls[0], "/echo"); code != http.StatusOK {
+		t.Fatalf("got status %d from the first app, want 200", code)
+	}
+	if code, _ := get(urls[1], "/echo"); code != http.StatusNotFound {
+		t.Fatalf("got status %d from the second app, want 404", code)
+	}
+}
+
 type testComponent struct {
 	*app.AppComponent
 	afterInitCalled bool
@@ -121,3 +170,20 @@ func TestRegisterComponent_OnBuiltApp(t *testing.T) {
 		t.Fatal("expected the component to be bound to the built app")
 	}
 }
+
+func TestNewComponent(t *testing.T) {
+	set := func(a kernel.IApp, configKey string) error {
+		return app.RegisterComponent(a, &testComponent{AppComponent: app.NewAppComponent()}, "test", configKey)
+	}
+	get := func(a kernel.IApp) (*testComponent, error) {
+		return a.Component("test").(*testComponent), nil
+	}
+
+	a, c := apptest.NewComponent(t, "Test", kernel.Dict{"Flag": true}, set, get)
+	if !c.afterInitCalled || c.App() != a {
+		t.Fatal("expected the component to be set up on the built app")
+	}
+	if a.ConfigParam("Components.Test.Flag") != true {
+		t.Fatal("expected the component's config section to be set")
+	}
+}
@@ -0,0 +1,291 @@
+// Package cache provides a caching component for lxgo/kernel applications -
+// values with TTLs and tags, kept in an in-memory LRU (see LRU) or an
+// external backend (see IBackend), Remember to compute a missing value
+// once however many callers want it, and ResponseMiddleware to cache whole
+// responses.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+)
+
+// APP_COMPONENT_KEY is the key Component registers itself under - see
+// SetAppComponent/AppComponent.
+const APP_COMPONENT_KEY = "lxgo_cache"
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Config
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponentConfig */
+
+// Config is Component's app-component configuration.
+type Config struct {
+	*lxApp.ComponentConfig
+	// MaxEntries is the in-memory LRU's entry limit - DefaultMaxEntries if unset.
+	MaxEntries int
+	// MaxBytes is the in-memory LRU's size limit - DefaultMaxBytes if unset.
+	MaxBytes int
+	// TTL is the lifetime of values set without one, in seconds - they
+	// don't expire if unset.
+	TTL int
+	// Prefix is prepended to every key and tag - for apps sharing an
+	// external backend. Values are also tagged with Prefix itself, for Clear
+	// to remove only this app's values.
+	Prefix string
+}
+
+/** @constructor kernel.CAppComponentConfig */
+
+// NewConfig constructs a Config.
+func NewConfig() kernel.IAppComponentConfig {
+	return &Config{ComponentConfig: lxApp.NewComponentConfigStruct()}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Component
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponent */
+
+// Component caches JSON-encoded values in its backend - an LRU sized by
+// Config unless SetBackend says otherwise. See SetAppComponent to register
+// it on an app.
+type Component struct {
+	*lxApp.AppComponent
+
+	backend  IBackend
+	flightMu sync.Mutex
+	flights  map[string]*flight
+}
+
+var _ kernel.IAppComponent = (*Component)(nil)
+
+// SetAppComponent registers a new Component on app under
+// APP_COMPONENT_KEY, configured from the config section named by configKey.
+func SetAppComponent(app kernel.IApp, configKey string) error {
+	if app.HasComponent(APP_COMPONENT_KEY) {
+		return fmt.Errorf("the application already has component: %s", APP_COMPONENT_KEY)
+	}
+
+	c := NewComponent()
+	if err := lxApp.InitComponent(c, app, configKey); err != nil {
+		return fmt.Errorf("can not init cache component: %s", err)
+	}
+
+	app.SetComponent(APP_COMPONENT_KEY, c)
+	return nil
+}
+
+// AppComponent returns the Component registered on app under APP_COMPONENT_KEY.
+func AppComponent(app kernel.IApp) (*Component, error) {
+	c := app.Component(APP_COMPONENT_KEY)
+	if c == nil {
+		return nil, fmt.Errorf("application component '%s' not found", APP_COMPONENT_KEY)
+	}
+
+	cc, ok := c.(*Component)
+	if !ok {
+		return nil, fmt.Errorf("application component '%s' is not '*cache.Component'", APP_COMPONENT_KEY)
+	}
+
+	return cc, nil
+}
+
+/** @constructor */
+
+// NewComponent constructs a Component.
+func NewComponent() *Component {
+	return &Component{
+		AppComponent: lxApp.NewAppComponent(),
+		flights:      make(map[string]*flight),
+	}
+}
+
+// Name returns the component's name - see kernel.IAppComponent.
+func (c *Component) Name() string {
+	return "Cache"
+}
+
+// LogCategory returns the category the component's log methods write under.
+func (c *Component) LogCategory() string {
+	return "Cache"
+}
+
+// CConfig returns Config's constructor - see kernel.IAppComponent.
+func (c *Component) CConfig() kernel.CAppComponentConfig {
+	return NewConfig
+}
+
+// Config returns the component's bound Config.
+func (c *Component) Config() *Config {
+	return (c.GetConfig()).(*Config)
+}
+
+// AfterInit sets up the in-memory LRU backend - see kernel.IAppComponent.
+func (c *Component) AfterInit() {
+	if c.backend == nil {
+		c.backend = NewLRU(c.Config().MaxEntries, c.Config().MaxBytes)
+	}
+}
+
+// Backend returns the backend the values are kept in.
+func (c *Component) Backend() IBackend {
+	return c.backend
+}
+
+// SetBackend replaces the backend the values are kept in.
+func (c *Component) SetBackend(b IBackend) {
+	c.backend = b
+}
+
+// Get decodes the value under key into v, reporting whether there was one.
+func (c *Component) Get(ctx context.Context, key string, v any) (bool, error) {
+	data, ok, err := c.backend.Get(ctx, c.Config().Prefix+key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("can not decode cached '%s': %w", key, err)
+	}
+	return true, nil
+}
+
+// Set stores v under key, JSON-encoded, for ttl (Config.TTL if 0), tagged
+// with tags - see InvalidateTags.
+func (c *Component) Set(ctx context.Context, key string, v any, ttl time.Duration, tags ...string) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("can not encode '%s' to cache: %w", key, err)
+	}
+	return c.set(ctx, key, data, ttl, tags)
+}
+
+// Delete removes the values under keys.
+func (c *Component) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.Config().Prefix + key
+	}
+	return c.backend.Delete(ctx, prefixed...)
+}
+
+// InvalidateTags removes every value tagged with any of tags.
+func (c *Component) InvalidateTags(ctx context.Context, tags ...string) error {
+	return c.backend.InvalidateTags(ctx, c.tags(tags)...)
+}
+
+// Clear removes every value - only the ones under Config.Prefix if it's
+// set, leaving other apps' values in a shared backend alone.
+func (c *Component) Clear(ctx context.Context) error {
+	if prefix := c.Config().Prefix; prefix != "" {
+		return c.backend.InvalidateTags(ctx, prefix)
+	}
+	return c.backend.Clear(ctx)
+}
+
+// Remember returns the value under key, or computes it with fn and stores
+// it for ttl (Config.TTL if 0) with tags. While fn runs, other callers
+// asking for the same key wait for its result rather than run fn
+// themselves. fn's error (or panic) is returned and nothing is stored.
+func Remember[T any](ctx context.Context, c *Component, key string, ttl time.Duration, fn func(ctx context.Context) (T, error), tags ...string) (T, error) {
+	var v T
+	ok, err := c.Get(ctx, key, &v)
+	if err != nil {
+		c.Logger().Warning("cache read failed", "key", key, "error", err.Error())
+	}
+	if ok {
+		return v, nil
+	}
+
+	data, err := c.do(key, func() ([]byte, error) {
+		computed, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(computed)
+		if err != nil {
+			return nil, fmt.Errorf("can not encode '%s' to cache: %w", key, err)
+		}
+		if err := c.set(ctx, key, data, ttl, tags); err != nil {
+			c.Logger().Warning("cache write failed", "key", key, "error", err.Error())
+		}
+		return data, nil
+	})
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, fmt.Errorf("can not decode cached '%s': %w", key, err)
+	}
+	return v, nil
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+type flight struct {
+	wg   sync.WaitGroup
+	data []byte
+	err  error
+}
+
+// do runs fn once for all the concurrent calls with the same key - if fn
+// panics, they all get the panic as an error.
+func (c *Component) do(key string, fn func() ([]byte, error)) (data []byte, err error) {
+	c.flightMu.Lock()
+	if f, ok := c.flights[key]; ok {
+		c.flightMu.Unlock()
+		f.wg.Wait()
+		return f.data, f.err
+	}
+	f := new(flight)
+	f.wg.Add(1)
+	c.flights[key] = f
+	c.flightMu.Unlock()
+
+	defer func() {
+		if r := recover(); r != nil {
+			f.data, f.err = nil, fmt.Errorf("panic computing '%s': %v", key, r)
+			data, err = f.data, f.err
+		}
+		f.wg.Done()
+		c.flightMu.Lock()
+		delete(c.flights, key)
+		c.flightMu.Unlock()
+	}()
+	f.data, f.err = fn()
+	return f.data, f.err
+}
+
+func (c *Component) set(ctx context.Context, key string, data []byte, ttl time.Duration, tags []string) error {
+	if ttl <= 0 {
+		ttl = time.Duration(c.Config().TTL) * time.Second
+	}
+	prefix := c.Config().Prefix
+	if prefix != "" {
+		// See Clear.
+		tags = append(c.tags(tags), prefix)
+	}
+	return c.backend.Set(ctx, prefix+key, data, ttl, tags)
+}
+
+func (c *Component) tags(tags []string) []string {
+	prefix := c.Config().Prefix
+	if prefix == "" || len(tags) == 0 {
+		return tags
+	}
+	prefixed := make([]string, len(tags), len(tags)+1)
+	for i, tag := range tags {
+		prefixed[i] = prefix + tag
+	}
+	return prefixed
+}
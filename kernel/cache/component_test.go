@@ -0,0 +1,177 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	"github.com/epicoon/lxgo/kernel/cache"
+)
+
+func newCache(t *testing.T, conf kernel.Dict) (kernel.IApp, *cache.Component) {
+	t.Helper()
+	return apptest.NewComponent(t, "Cache", conf, cache.SetAppComponent, cache.AppComponent)
+}
+
+func TestComponent_GetSet(t *testing.T) {
+	_, c := newCache(t, kernel.Dict{"MaxEntries": 10, "Prefix": "app:"})
+	ctx := context.Background()
+
+	type user struct {
+		ID   int
+		Name string
+	}
+	if err := c.Set(ctx, "user:1", user{1, "Ann"}, 0, "users"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	var u user
+	if ok, err := c.Get(ctx, "user:1", &u); !ok || err != nil || u.Name != "Ann" {
+		t.Fatalf("expected the cached user, got %+v, %v, %v", u, ok, err)
+	}
+	if _, ok, _ := c.Backend().Get(ctx, "app:user:1"); !ok {
+		t.Fatal("expected the key to be prefixed in the backend")
+	}
+
+	c.InvalidateTags(ctx, "users")
+	if ok, _ := c.Get(ctx, "user:1", &u); ok {
+		t.Fatal("expected the tag to invalidate the value")
+	}
+
+	c.Set(ctx, "user:2", user{2, "Bob"}, 0)
+	c.Delete(ctx, "user:2")
+	if ok, _ := c.Get(ctx, "user:2", &u); ok {
+		t.Fatal("expected the value to be deleted")
+	}
+}
+
+func TestComponent_ClearPrefix(t *testing.T) {
+	_, c := newCache(t, kernel.Dict{"Prefix": "app:"})
+	ctx := context.Background()
+
+	// Another app sharing the backend.
+	c.Backend().Set(ctx, "other:user:1", []byte(`1`), 0, nil)
+	c.Set(ctx, "user:1", 1, 0)
+	c.Set(ctx, "user:2", 2, 0, "users")
+
+	if err := c.Clear(ctx); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	var v int
+	if ok, _ := c.Get(ctx, "user:1", &v); ok {
+		t.Fatal("expected the app's values to be cleared")
+	}
+	if ok, _ := c.Get(ctx, "user:2", &v); ok {
+		t.Fatal("expected the app's tagged values to be cleared")
+	}
+	if _, ok, _ := c.Backend().Get(ctx, "other:user:1"); !ok {
+		t.Fatal("expected the other app's value to be kept")
+	}
+}
+
+func TestComponent_DefaultTTL(t *testing.T) {
+	_, c := newCache(t, kernel.Dict{"TTL": 1})
+	ctx := context.Background()
+
+	c.Set(ctx, "default", 1, 0)
+	c.Set(ctx, "short", 1, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	var v int
+	if ok, _ := c.Get(ctx, "short", &v); ok {
+		t.Fatal("expected the value's own TTL to win")
+	}
+	if ok, _ := c.Get(ctx, "default", &v); !ok {
+		t.Fatal("expected the value to live for the configured TTL")
+	}
+}
+
+func TestRemember(t *testing.T) {
+	_, c := newCache(t, kernel.Dict{})
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	compute := func(ctx context.Context) ([]string, error) {
+		calls.Add(1)
+		<-release
+		return []string{"a", "b"}, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = cache.Remember(ctx, c, "list", time.Minute, compute)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected the value to be computed once, got %d", calls.Load())
+	}
+	for _, r := range results {
+		if len(r) != 2 || r[1] != "b" {
+			t.Fatalf("expected every caller to get the value, got %v", results)
+		}
+	}
+	if v, _ := cache.Remember(ctx, c, "list", time.Minute, compute); len(v) != 2 || calls.Load() != 1 {
+		t.Fatal("expected the stored value to be used")
+	}
+
+	failed := errors.New("db down")
+	if _, err := cache.Remember(ctx, c, "broken", time.Minute, func(context.Context) (int, error) { return 0, failed }); !errors.Is(err, failed) {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	var v int
+	if ok, _ := c.Get(ctx, "broken", &v); ok {
+		t.Fatal("expected nothing to be stored on error")
+	}
+}
+
+func TestRemember_Panic(t *testing.T) {
+	_, c := newCache(t, kernel.Dict{})
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	compute := func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		panic("boom")
+	}
+
+	var runnerErr, waiterErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, runnerErr = cache.Remember(ctx, c, "panicky", time.Minute, compute)
+	}()
+	<-started
+	go func() {
+		defer wg.Done()
+		_, waiterErr = cache.Remember(ctx, c, "panicky", time.Minute, func(context.Context) (int, error) {
+			t.Error("expected the running computation to be shared")
+			return 0, nil
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for name, err := range map[string]error{"runner": runnerErr, "waiter": waiterErr} {
+		if err == nil || !strings.Contains(err.Error(), "panic computing 'panicky': boom") {
+			t.Errorf("%s: expected the panic as an error, got %v", name, err)
+		}
+	}
+}
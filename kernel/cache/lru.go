@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries is the LRU's entry limit unless Config.MaxEntries says
+// otherwise.
+const DefaultMaxEntries = 10000
+
+// DefaultMaxBytes is the LRU's size limit unless Config.MaxBytes says
+// otherwise.
+const DefaultMaxBytes = 64 << 20
+
+// IBackend stores the cache's entries - see LRU for the in-memory one. An
+// external one (Redis, Memcached...) implements it to share the cache
+// between processes.
+type IBackend interface {
+	// Get returns the value under key - ok is false if there's none or it
+	// has expired.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+
+	// Set stores value under key, expiring after ttl (never if 0), tagged
+	// with tags - see InvalidateTags.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+
+	// Delete removes the values under keys.
+	Delete(ctx context.Context, keys ...string) error
+
+	// InvalidateTags removes every value tagged with any of tags.
+	InvalidateTags(ctx context.Context, tags ...string) error
+
+	// Clear removes every value.
+	Clear(ctx context.Context) error
+}
+
+/** @interface IBackend */
+
+// LRU is an in-memory IBackend, evicting the least recently used entries
+// once it holds more than its entry or byte limit.
+type LRU struct {
+	maxEntries int
+	maxBytes   int
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+	tags  map[string]map[string]struct{}
+	size  int
+}
+
+var _ IBackend = (*LRU)(nil)
+
+/** @constructor */
+
+// NewLRU constructs an LRU holding up to maxEntries entries of up to
+// maxBytes bytes in total (keys included) - zero limits take the defaults.
+func NewLRU(maxEntries, maxBytes int) *LRU {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &LRU{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		tags:       make(map[string]map[string]struct{}),
+	}
+}
+
+// Get returns the value under key - see IBackend.
+func (l *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*lruEntry)
+	if e.expired(time.Now()) {
+		l.remove(el)
+		return nil, false, nil
+	}
+	l.order.MoveToFront(el)
+	return e.value, true, nil
+}
+
+// Set stores value under key - see IBackend. A value larger than the
+// byte limit isn't stored.
+func (l *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		l.remove(el)
+	}
+
+	e := &lruEntry{key: key, value: value, tags: tags, size: len(key) + len(value)}
+	if e.size > l.maxBytes {
+		return nil
+	}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	l.items[key] = l.order.PushFront(e)
+	l.size += e.size
+	for _, tag := range tags {
+		if l.tags[tag] == nil {
+			l.tags[tag] = make(map[string]struct{})
+		}
+		l.tags[tag][key] = struct{}{}
+	}
+
+	for len(l.items) > l.maxEntries || l.size > l.maxBytes {
+		l.remove(l.order.Back())
+	}
+	return nil
+}
+
+// Delete removes the values under keys - see IBackend.
+func (l *LRU) Delete(ctx context.Context, keys ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := l.items[key]; ok {
+			l.remove(el)
+		}
+	}
+	return nil
+}
+
+// InvalidateTags removes the values tagged with any of tags - see IBackend.
+func (l *LRU) InvalidateTags(ctx context.Context, tags ...string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, tag := range tags {
+		for key := range l.tags[tag] {
+			if el, ok := l.items[key]; ok {
+				l.remove(el)
+			}
+		}
+	}
+	return nil
+}
+
+// Clear removes every value - see IBackend.
+func (l *LRU) Clear(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.items = make(map[string]*list.Element)
+	l.order.Init()
+	l.tags = make(map[string]map[string]struct{})
+	l.size = 0
+	return nil
+}
+
+// Len returns the number of entries held, expired ones not yet evicted included.
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+// Size returns the entries' size in bytes, keys included.
+func (l *LRU) Size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	tags    []string
+	expires time.Time
+	size    int
+}
+
+func (e *lruEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// remove drops el - l.mu must be held.
+func (l *LRU) remove(el *list.Element) {
+	e := l.order.Remove(el).(*lruEntry)
+	delete(l.items, e.key)
+	l.size -= e.size
+	for _, tag := range e.tags {
+		if keys := l.tags[tag]; keys != nil {
+			delete(keys, e.key)
+			if len(keys) == 0 {
+				delete(l.tags, tag)
+			}
+		}
+	}
+}
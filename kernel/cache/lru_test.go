@@ -0,0 +1,67 @@
+package cache_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel/cache"
+)
+
+func TestLRU_Eviction(t *testing.T) {
+	ctx := context.Background()
+	l := cache.NewLRU(2, 94)
+
+	l.Set(ctx, "a", []byte("1"), 0, nil)
+	l.Set(ctx, "b", []byte("2"), 0, nil)
+	l.Get(ctx, "a")
+	l.Set(ctx, "c", []byte("3"), 0, nil)
+	if _, ok, _ := l.Get(ctx, "b"); ok {
+		t.Fatal("expected the least recently used entry to be evicted")
+	}
+	if _, ok, _ := l.Get(ctx, "a"); !ok {
+		t.Fatal("expected the recently read entry to stay")
+	}
+
+	l.Set(ctx, "big", []byte(strings.Repeat("x", 90)), 0, nil)
+	if l.Len() != 1 || l.Size() != 93 {
+		t.Fatalf("expected the byte limit to evict the rest, got %d entries of %d bytes", l.Len(), l.Size())
+	}
+	l.Set(ctx, "huge", []byte(strings.Repeat("x", 200)), 0, nil)
+	if _, ok, _ := l.Get(ctx, "huge"); ok || l.Len() != 1 {
+		t.Fatal("expected a value over the byte limit not to be stored")
+	}
+}
+
+func TestLRU_TTLAndTags(t *testing.T) {
+	ctx := context.Background()
+	l := cache.NewLRU(0, 0)
+
+	l.Set(ctx, "short", []byte("1"), 10*time.Millisecond, nil)
+	l.Set(ctx, "user:1", []byte("1"), 0, []string{"users"})
+	l.Set(ctx, "user:2", []byte("2"), 0, []string{"users", "admins"})
+	l.Set(ctx, "post:1", []byte("1"), 0, []string{"posts"})
+
+	time.Sleep(20 * time.Millisecond)
+	if _, ok, _ := l.Get(ctx, "short"); ok {
+		t.Fatal("expected the entry to expire")
+	}
+
+	l.InvalidateTags(ctx, "admins")
+	if _, ok, _ := l.Get(ctx, "user:2"); ok {
+		t.Fatal("expected the tagged entry to be invalidated")
+	}
+	if _, ok, _ := l.Get(ctx, "user:1"); !ok {
+		t.Fatal("expected the entry without the tag to stay")
+	}
+	l.InvalidateTags(ctx, "users")
+	if l.Len() != 1 {
+		t.Fatalf("expected only the post to stay, got %d entries", l.Len())
+	}
+
+	l.Clear(ctx)
+	if l.Len() != 0 || l.Size() != 0 {
+		t.Fatal("expected Clear to empty the cache")
+	}
+}
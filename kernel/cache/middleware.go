@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+// CacheHeader is the response header ResponseMiddleware reports a cache
+// "HIT" or "MISS" in.
+const CacheHeader = "X-Cache"
+
+// ResponseCacheConfig configures ResponseMiddleware.
+type ResponseCacheConfig struct {
+	// TTL is how long a response is cached - Config.TTL if 0.
+	TTL time.Duration
+	// Tags tag the cached responses - see Component.InvalidateTags.
+	Tags []string
+	// Vary lists the request headers, besides Accept, responses differ by
+	// (e.g. "Accept-Language").
+	Vary []string
+	// Skip bypasses the cache for the requests it returns true for (e.g.
+	// signed-in users').
+	Skip func(ctx kernel.IHandleContext) bool
+}
+
+// ResponseMiddleware returns a middleware caching the successful JSON and
+// HTML responses to GET and HEAD requests - keyed by route, path params,
+// query and the Accept (and conf.Vary) headers. A response setting a
+// cookie, or a "no-store" or "private" Cache-Control header, isn't cached.
+// Use it with IRouter.AddWrapMiddleware or IRouteGroup.AddWrapMiddleware.
+func (c *Component) ResponseMiddleware(conf ResponseCacheConfig) kernel.FWrapMiddleware {
+	return func(ctx kernel.IHandleContext, next kernel.FNextHandler) kernel.IHttpResponse {
+		r := ctx.Request()
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || (conf.Skip != nil && conf.Skip(ctx)) {
+			return next()
+		}
+
+		key := responseKey(ctx, conf.Vary)
+		var snapshot lxHttp.ResponseSnapshot
+		if ok, err := c.Get(r.Context(), key, &snapshot); ok {
+			resp := snapshot.Response()
+			resp.AddHeader(CacheHeader, "HIT")
+			return resp
+		} else if err != nil {
+			ctx.Logger().Warning("response cache read failed", "error", err.Error())
+		}
+
+		resp := next()
+		if resp == nil || !cacheable(ctx, resp) {
+			return resp
+		}
+		snapshot, ok := lxHttp.SnapshotResponse(ctx, resp)
+		if !ok {
+			return resp
+		}
+		data, err := json.Marshal(snapshot)
+		if err == nil {
+			err = c.set(r.Context(), key, data, conf.TTL, conf.Tags)
+		}
+		if err != nil {
+			ctx.Logger().Warning("response cache write failed", "error", err.Error())
+		}
+		resp.AddHeader(CacheHeader, "MISS")
+		return resp
+	}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// responseKey is "response:<route>:<hash of the rest>".
+func responseKey(ctx kernel.IHandleContext, vary []string) string {
+	r := ctx.Request()
+	h := sha256.New()
+
+	params := ctx.PathParams()
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		h.Write([]byte(name + "=" + params[name] + "\n"))
+	}
+	h.Write([]byte(r.URL.Query().Encode() + "\n"))
+	h.Write([]byte(r.Header.Get("Accept") + "\n"))
+	for _, header := range vary {
+		h.Write([]byte(r.Header.Get(header) + "\n"))
+	}
+	return "response:" + ctx.Route() + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+func cacheable(ctx kernel.IHandleContext, resp kernel.IHttpResponse) bool {
+	if ctx.ResponseWriter().Header().Get("Set-Cookie") != "" {
+		return false
+	}
+	for key, val := range resp.Headers() {
+		switch http.CanonicalHeaderKey(key) {
+		case "Set-Cookie":
+			return false
+		case "Cache-Control":
+			val = strings.ToLower(val)
+			if strings.Contains(val, "no-store") || strings.Contains(val, "private") {
+				return false
+			}
+		}
+	}
+	return true
+}
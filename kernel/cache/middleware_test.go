@@ -0,0 +1,90 @@
+package cache_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	"github.com/epicoon/lxgo/kernel/cache"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+var productRuns atomic.Int32
+
+type productResource struct {
+	*lxHttp.Resource
+}
+
+func (r *productResource) Run() kernel.IHttpResponse {
+	productRuns.Add(1)
+	conf := kernel.JsonResponseConfig{Data: kernel.Dict{
+		"id":   r.Context().PathParam("id"),
+		"lang": r.Context().Request().URL.Query().Get("lang"),
+		"run":  productRuns.Load(),
+	}}
+	if r.Context().Request().URL.Query().Has("private") {
+		conf.Headers = map[string]string{"Cache-Control": "private"}
+	}
+	return r.JsonResponse(conf)
+}
+
+func get(t *testing.T, url string, accept string) (string, string, string) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), resp.Header.Get(cache.CacheHeader), resp.Header.Get("Content-Type")
+}
+
+func TestResponseMiddleware(t *testing.T) {
+	a, c := newCache(t, kernel.Dict{})
+	api := a.Router().Group("/api")
+	api.AddWrapMiddleware(c.ResponseMiddleware(cache.ResponseCacheConfig{TTL: time.Minute, Tags: []string{"products"}}))
+	api.RegisterResource("/products/{id}", "GET", func() kernel.IHttpResource {
+		return &productResource{Resource: lxHttp.NewResource()}
+	})
+	srv := apptest.Server(a)
+	defer srv.Close()
+
+	first, state, _ := get(t, srv.URL+"/api/products/1?lang=en", "")
+	if state != "MISS" {
+		t.Fatalf("expected a miss, got %q", state)
+	}
+	if body, state, ctype := get(t, srv.URL+"/api/products/1?lang=en", ""); body != first || state != "HIT" || ctype != lxHttp.ContentTypeJson {
+		t.Fatalf("expected the cached response, got %q %q %q", body, state, ctype)
+	}
+
+	for _, url := range []string{"/api/products/2?lang=en", "/api/products/1?lang=de"} {
+		if body, state, _ := get(t, srv.URL+url, ""); body == first || state != "MISS" {
+			t.Fatalf("expected %s to be cached apart, got %q %q", url, body, state)
+		}
+	}
+	if _, state, ctype := get(t, srv.URL+"/api/products/1?lang=en", "application/xml"); state != "MISS" || ctype != "application/xml" {
+		t.Fatalf("expected another format to be cached apart, got %q %q", state, ctype)
+	}
+	if _, state, ctype := get(t, srv.URL+"/api/products/1?lang=en", "application/xml"); state != "HIT" || ctype != "application/xml" {
+		t.Fatalf("expected the negotiated response to be cached, got %q %q", state, ctype)
+	}
+
+	c.InvalidateTags(context.Background(), "products")
+	if body, state, _ := get(t, srv.URL+"/api/products/1?lang=en", ""); body == first || state != "MISS" {
+		t.Fatalf("expected the tag to invalidate the response, got %q %q", body, state)
+	}
+
+	get(t, srv.URL+"/api/products/1?private=1", "")
+	if _, state, _ := get(t, srv.URL+"/api/products/1?private=1", ""); state != "" {
+		t.Fatalf("expected a private response not to be cached, got %q", state)
+	}
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/epicoon/lxgo/cmd"
 	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/openapi"
+	"github.com/epicoon/lxgo/kernel/validation"
 )
 
 // ApiDocCommandOptions is ApiDocCommand's cmd.ICommandOptions.
@@ -15,20 +17,38 @@ type ApiDocCommandOptions struct {
 	// Router is the application's router - its registered resources are
 	// what gets documented.
 	Router kernel.IRouter
-	// Output is the file to write the generated markdown to; printed to
+	// Output is the file to write the generated document to; printed to
 	// stdout instead if empty.
 	Output string
+	// Format is the output format: FormatMarkdown (the default) or
+	// FormatOpenAPI - an OpenAPI 3.1 document, as JSON if Output ends with
+	// ".json" and as YAML otherwise.
+	Format string
+	// Title and Version fill in the OpenAPI document's "info" - ignored
+	// for markdown.
+	Title   string
+	Version string
 }
 
+const (
+	// FormatMarkdown makes ApiDocCommand generate markdown tables.
+	FormatMarkdown = "markdown"
+	// FormatOpenAPI makes ApiDocCommand generate an OpenAPI 3.1 document.
+	FormatOpenAPI = "openapi"
+)
+
 /** @interface cmd.ICommand */
 
-// ApiDocCommand generates markdown API documentation from an application's
-// registered HTTP resources and their request/response/fail forms - see
-// NewApiDocCommand.
+// ApiDocCommand generates API documentation - markdown or an OpenAPI
+// document - from an application's registered HTTP resources and their
+// request/response/fail forms - see NewApiDocCommand.
 type ApiDocCommand struct {
 	*cmd.Command
-	router kernel.IRouter
-	output string
+	router  kernel.IRouter
+	output  string
+	format  string
+	title   string
+	version string
 }
 
 var _ cmd.ICommand = (*ApiDocCommand)(nil)
@@ -43,6 +63,9 @@ func NewApiDocCommand(opt ...cmd.ICommandOptions) cmd.ICommand {
 		Command: cmd.NewCommand(),
 		router:  options.Router,
 		output:  options.Output,
+		format:  options.Format,
+		title:   options.Title,
+		version: options.Version,
 	}
 	c.RegisterActions(cmd.ActionsList{
 		"gen": gen,
@@ -60,6 +83,14 @@ type endpoint struct {
 func gen(com cmd.ICommand) error {
 	c := com.(*ApiDocCommand)
 
+	switch c.format {
+	case "", FormatMarkdown:
+	case FormatOpenAPI:
+		return genOpenAPI(c)
+	default:
+		return fmt.Errorf("unknown API doc format '%s'", c.format)
+	}
+
 	counter := 1
 	var sb strings.Builder
 	sb.WriteString("# API\n")
@@ -85,6 +116,39 @@ func gen(com cmd.ICommand) error {
 	return nil
 }
 
+func genOpenAPI(c *ApiDocCommand) error {
+	info := openapi.Info{Title: c.title, Version: c.version}
+	if info.Title == "" {
+		info.Title = "API"
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+	doc := openapi.Generate(c.router, info)
+
+	var data []byte
+	var err error
+	if strings.HasSuffix(c.output, ".json") {
+		data, err = doc.JSON()
+	} else {
+		data, err = doc.YAML()
+	}
+	if err != nil {
+		return fmt.Errorf("can not encode OpenAPI document: %v", err)
+	}
+
+	if c.output == "" {
+		fmt.Println(string(data))
+	} else {
+		if err := os.WriteFile(c.output, data, 0644); err != nil {
+			fmt.Printf("Can not write file '%s': %v\n", c.output, err)
+		}
+		fmt.Println("Done")
+	}
+
+	return nil
+}
+
 func generateEndpointMD(ep *endpoint, commonSB *strings.Builder, counter int) {
 	commonSB.WriteString(fmt.Sprintf("* [%s `%s`](#r%d)\n", ep.path, ep.method, counter))
 
@@ -143,7 +207,14 @@ func generateFormMD(f kernel.IForm, sb *strings.Builder) {
 			obligate = "yes"
 		}
 
-		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", fieldName, fieldType, obligate, cfg.Description))
+		description := cfg.Description
+		if rules, err := validation.FieldRules(field, cfg); err == nil && rules != nil {
+			// "|" separates enum values, but also table cells.
+			rulesDoc := fmt.Sprintf("(rules: `%s`)", strings.ReplaceAll(rules.String(), "|", "\\|"))
+			description = strings.TrimSpace(description + " " + rulesDoc)
+		}
+
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", fieldName, fieldType, obligate, description))
 	}
 }
 
@@ -19,7 +19,7 @@ type ManageCommandOptions struct {
 /** @interface cmd.ICommand */
 
 // ManageCommand talks to a running application's manage socket - status/
-// refresh-config/inject-config/trigger - see NewManageCommand.
+// refresh-config/inject-config/trigger/jobs - see NewManageCommand.
 type ManageCommand struct {
 	*cmd.Command
 	SocketPath string
@@ -39,8 +39,8 @@ func NewManageCommand(opt ...cmd.ICommandOptions) cmd.ICommand {
 	})
 }
 
-// Config declares the "status", "refresh-config", "inject-config" and
-// "trigger" actions - see cmd.ICommand.
+// Config declares the "status", "refresh-config", "inject-config", "trigger"
+// and "jobs" actions - see cmd.ICommand.
 func (c *ManageCommand) Config() *cmd.Config {
 	return &cmd.Config{
 		Description: "Command for local app managing by socket file defined in the config param 'ManageSocket'",
@@ -104,6 +104,17 @@ func (c *ManageCommand) Config() *cmd.Config {
 					},
 				},
 			},
+			"jobs": cmd.ActionConfig{
+				Description: "List the scheduler component's jobs, or run one right away",
+				Executor:    jobs,
+				Params: cmd.ParamsConfig{
+					"run": cmd.ParamConfig{
+						Description: "Name of the job to run",
+						Type:        cmd.ParamTypeString,
+						Required:    false,
+					},
+				},
+			},
 		},
 	}
 }
@@ -138,6 +149,12 @@ func trigger(c cmd.ICommand) error {
 	return nil
 }
 
+/** @handler cmd.FAction */
+func jobs(c cmd.ICommand) error {
+	sendToSocket(c.(*ManageCommand).SocketPath, prepareMsg("jobs", c.Params()))
+	return nil
+}
+
 func prepareMsg(command string, params map[string]any) string {
 	msg := command
 	for key, val := range params {
@@ -155,7 +172,7 @@ func sendToSocket(socketPath, msg string) {
 	defer conn.Close()
 
 	_, _ = conn.Write([]byte(msg + "\n"))
-	buf := make([]byte, 1024)
+	buf := make([]byte, 64*1024)
 	n, _ := conn.Read(buf)
 	fmt.Println(string(buf[:n]))
 }
@@ -6,8 +6,12 @@
 package kernel
 
 import (
+	"context"
 	"database/sql"
+	"io"
 	"net/http"
+	"reflect"
+	"time"
 )
 
 /* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
@@ -80,6 +84,10 @@ type IApp interface {
 	// SetConnection sets the application's DB connection.
 	SetConnection(c IConnection)
 
+	// SetNamedConnection sets an additional DB connection under name
+	// (e.g. a read replica).
+	SetNamedConnection(name string, c IConnection)
+
 	// Pathfinder returns the application's IPathfinder.
 	Pathfinder() IPathfinder
 
@@ -89,6 +97,14 @@ type IApp interface {
 	// Connection returns the application's DB connection.
 	Connection() IConnection
 
+	// NamedConnection returns the DB connection set under name, or nil - the
+	// main one if name is empty.
+	NamedConnection(name string) IConnection
+
+	// ConnectionNames returns the names the additional DB connections are
+	// set under, sorted.
+	ConnectionNames() []string
+
 	// Router returns the application's router.
 	Router() IRouter
 
@@ -165,13 +181,36 @@ type IAppComponent interface {
 	LogError(msg string, params ...any)
 
 	// Run starts the component, if it needs its own lifecycle (e.g. a
-	// background server).
+	// background server). It must not block: start the long-running work
+	// in goroutines and stop them in Final - a Run that outlasts its start
+	// timeout (see IAppComponentStartTimeout) fails the app's startup and
+	// is left running.
 	Run() error
 
 	// Final runs the component's shutdown/cleanup.
 	Final() error
 }
 
+// IAppComponentDependent is an IAppComponent that needs other components up
+// before it: the app runs the components it depends on first and finalizes
+// them after it.
+type IAppComponentDependent interface {
+	IAppComponent
+
+	// DependsOn returns the keys the components it depends on are
+	// registered under - see IApp.SetComponent.
+	DependsOn() []any
+}
+
+// IAppComponentStartTimeout is an IAppComponent with its own limit on how
+// long its Run may take, instead of the app's "ComponentStartTimeout".
+type IAppComponentStartTimeout interface {
+	IAppComponent
+
+	// StartTimeout returns how long Run may take - zero means no limit.
+	StartTimeout() time.Duration
+}
+
 // IAppComponentConfig is an IAppComponent's configuration - either a
 // section of the app's YAML config (IsMap true) or a hand-built value.
 type IAppComponentConfig interface {
@@ -205,6 +244,9 @@ type IConnection interface {
 	// SetConfig sets the connection's config.
 	SetConfig(cfg IDict)
 
+	// Driver returns the connection's driver name (e.g. "postgres").
+	Driver() string
+
 	// DB returns the underlying *sql.DB.
 	DB() *sql.DB
 
@@ -218,17 +260,53 @@ type IConnection interface {
 // CAnyList maps names to factory functions - see IDIContainer.
 type CAnyList map[string]func(...any) any
 
-// IDIContainer is a simple dependency-injection container: register named
-// factories, then resolve values by name.
+// DILifetime is how long a value provided by type to IDIContainer lives.
+type DILifetime int
+
+const (
+	// DI_SINGLETON values are created once, on first resolve, and closed
+	// with the container.
+	DI_SINGLETON DILifetime = iota
+	// DI_TRANSIENT values are created on every resolve.
+	DI_TRANSIENT
+	// DI_REQUEST values are created once per request (IHandleContext) and
+	// closed once it's handled.
+	DI_REQUEST
+)
+
+// IDIContainer is a dependency-injection container: provide constructors
+// by the type they construct (see app.Provide and app.Resolve for the
+// typed helpers), or by name and resolve values by name.
 type IDIContainer interface {
-	// Init registers list, replacing any previously registered factories.
+	// Init registers list's factories as DI_TRANSIENT named values,
+	// replacing any previously provided by name.
 	Init(list CAnyList)
 
-	// Register adds list's factories to the container.
+	// Register adds list's factories as DI_TRANSIENT named values.
 	Register(list CAnyList) error
 
-	// Get resolves the value registered under key.
+	// Get resolves the value named key, nil if it can't be resolved.
 	Get(key string) any
+
+	// ProvideKey registers how the value named key is made - like
+	// ProvideType, but the value may be of any type.
+	ProvideKey(key string, ctor any, lifetime DILifetime) error
+
+	// ResolveKey resolves the value named key - ctx scopes DI_REQUEST
+	// values, it may be nil if none are involved.
+	ResolveKey(key string, ctx IHandleContext) (any, error)
+
+	// ProvideType registers how values of type t are made - ctor is either
+	// a value of t or a constructor returning one (and optionally an
+	// error), whose parameters are resolved from the container.
+	ProvideType(t reflect.Type, ctor any, lifetime DILifetime) error
+
+	// ResolveType resolves a value of type t - ctx scopes DI_REQUEST
+	// values, it may be nil if none are involved.
+	ResolveType(t reflect.Type, ctx IHandleContext) (any, error)
+
+	// Close closes the singletons created so far.
+	Close() error
 }
 
 // ITemplateHolder resolves a namespace's layout template - see ITemplateRenderer.
@@ -293,6 +371,32 @@ type ILogger interface {
 	LogError(msg string, category string)
 }
 
+// IStructuredLogger is an ILogger that also writes leveled entries carrying
+// key/value fields - kv is a flat list of alternating keys and values
+// ("user", id, "took", d). See the kernel/logger package for the default
+// implementation, and IHandleContext.Logger for a request-scoped one.
+type IStructuredLogger interface {
+	ILogger
+
+	// Debug writes a debug entry.
+	Debug(msg string, kv ...any)
+
+	// Info writes an informational entry.
+	Info(msg string, kv ...any)
+
+	// Warning writes a warning entry.
+	Warning(msg string, kv ...any)
+
+	// Error writes an error entry.
+	Error(msg string, kv ...any)
+
+	// With returns a logger adding kv to every entry it writes.
+	With(kv ...any) IStructuredLogger
+
+	// WithCategory returns a logger writing its entries under category.
+	WithCategory(category string) IStructuredLogger
+}
+
 /* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
  * HTTP ROUTING
  * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
@@ -313,6 +417,17 @@ type AssetsList map[string]string
 // error aborts the request. See IRouter.AddMiddleware.
 type FMiddleware func(IHandleContext) error
 
+// FNextHandler continues a request down the middleware chain to its
+// resource, returning the response built there - nil if it was already
+// written to the response writer directly. See FWrapMiddleware.
+type FNextHandler func() IHttpResponse
+
+// FWrapMiddleware wraps the rest of a request's handling - call next and
+// return (possibly after altering) its response, or return a response of
+// its own without calling next to short-circuit the request. See
+// IRouter.AddWrapMiddleware.
+type FWrapMiddleware func(ctx IHandleContext, next FNextHandler) IHttpResponse
+
 // CSerializer constructs an ISerializer.
 type CSerializer func() ISerializer
 
@@ -357,6 +472,156 @@ type JsonResponseConfig struct {
 	Form    IForm
 }
 
+// IStreamWriter writes a streamed response's body - see
+// StreamResponseConfig.Write.
+type IStreamWriter interface {
+	io.Writer
+
+	// Flush sends what's been written so far to the client.
+	Flush() error
+
+	// Context returns the request's context - done once the client disconnects.
+	Context() context.Context
+}
+
+// FStreamWrite writes a streamed response's body - see StreamResponseConfig.
+type FStreamWrite func(w IStreamWriter) error
+
+// StreamResponseConfig configures a streamed response - see
+// IHttpResource.StreamResponse.
+type StreamResponseConfig struct {
+	Code    int
+	Headers map[string]string
+	// ContentType is the body's content type - "application/octet-stream" if unset.
+	ContentType string
+	// Body is copied to the client - and closed, if it's an io.Closer.
+	Body io.Reader
+	// Write writes the body if there's no Body, flushing as it goes.
+	Write FStreamWrite
+}
+
+// FileResponseConfig configures a file download, Range and conditional
+// requests included - see IHttpResource.FileResponse.
+type FileResponseConfig struct {
+	Headers map[string]string
+	// Path is the file's path, relative to the app root.
+	Path string
+	// Content is the file's content if there's no Path - closed, if it's an io.Closer.
+	Content io.ReadSeeker
+	// Name is the file name the client saves it under - Path's base name if unset.
+	Name string
+	// ModTime is Content's modification time, for conditional requests -
+	// Path's own is used.
+	ModTime time.Time
+	// Inline lets the browser display the file rather than download it.
+	Inline bool
+}
+
+// SSEEvent is a Server-Sent Event - see ISSEStream.Send.
+type SSEEvent struct {
+	// ID is the event's ID - the client sends the last one it got back on
+	// reconnection, see ISSEStream.LastEventID.
+	ID string
+	// Event is the event's type - "message" if unset.
+	Event string
+	// Data is the event's data - a string or []byte as is, JSON otherwise.
+	Data any
+	// Retry is the client's reconnection delay hint.
+	Retry time.Duration
+}
+
+// ISSEStream sends Server-Sent Events - see SSEResponseConfig.Run.
+type ISSEStream interface {
+	// Send sends event to the client - failing once it has disconnected.
+	Send(event SSEEvent) error
+
+	// LastEventID returns the ID of the last event the client got before
+	// reconnecting, if it is.
+	LastEventID() string
+
+	// Context returns the request's context - done once the client disconnects.
+	Context() context.Context
+}
+
+// FSSERun sends a Server-Sent Events response's events - see SSEResponseConfig.
+type FSSERun func(stream ISSEStream) error
+
+// SSEResponseConfig configures a Server-Sent Events response - see
+// IHttpResource.SSEResponse.
+type SSEResponseConfig struct {
+	Headers map[string]string
+	// Retry is the client's reconnection delay hint, sent first - none if 0.
+	Retry time.Duration
+	// Heartbeat is the interval of the comments keeping an idle connection
+	// alive through proxies - http.DefaultSSEHeartbeat if 0, none if negative.
+	Heartbeat time.Duration
+	// Run sends the events - the response ends once it returns. It should
+	// return once the stream's context is done.
+	Run FSSERun
+}
+
+// CompressionConfig configures response compression - see
+// IRouter.SetCompression.
+type CompressionConfig struct {
+	// MinSize is the smallest body compressed, in bytes - smaller ones
+	// aren't worth it. Defaults to http.DefaultCompressionMinSize.
+	MinSize int
+	// Types lists the content types compressed, "text/*" wildcards
+	// allowed. Defaults to http.DefaultCompressionTypes.
+	Types []string
+	// Level is the gzip/deflate compression level (1-9), the default if 0.
+	Level int
+}
+
+// CorsConfig configures cross-origin resource sharing - see IRouter.SetCors.
+type CorsConfig struct {
+	// AllowOrigins lists the origins allowed to call the app, e.g.
+	// "https://app.example.com" - "*" allows any, and "https://*.example.com"
+	// any subdomain.
+	AllowOrigins []string
+	// AllowMethods lists the methods a preflight allows. Defaults to the
+	// route's registered methods (http.DefaultCorsMethods for a route
+	// registered for any method).
+	AllowMethods []string
+	// AllowHeaders lists the request headers a preflight allows. Defaults
+	// to the ones the preflight asks for.
+	AllowHeaders []string
+	// ExposeHeaders lists the response headers scripts may read besides
+	// the CORS-safelisted ones.
+	ExposeHeaders []string
+	// AllowCredentials allows requests with cookies or HTTP authentication
+	// - only from listed origins, it can't be combined with "*".
+	AllowCredentials bool
+	// MaxAge is how long a preflight's result may be cached, in seconds.
+	MaxAge int
+}
+
+// SecurityHeadersConfig configures the security headers set on every
+// response - a header is only set if its field is. See
+// IRouter.SetSecurityHeaders.
+type SecurityHeadersConfig struct {
+	// ContentSecurityPolicy is the Content-Security-Policy header, e.g.
+	// "default-src 'self'".
+	ContentSecurityPolicy string
+	// CSPReportOnly sends ContentSecurityPolicy as
+	// Content-Security-Policy-Report-Only instead.
+	CSPReportOnly bool
+	// HSTSMaxAge is the Strict-Transport-Security max-age in seconds - the
+	// header is only sent over HTTPS.
+	HSTSMaxAge int
+	// HSTSIncludeSubdomains adds includeSubDomains to Strict-Transport-Security.
+	HSTSIncludeSubdomains bool
+	// HSTSPreload adds preload to Strict-Transport-Security.
+	HSTSPreload bool
+	// FrameOptions is the X-Frame-Options header - "DENY" or "SAMEORIGIN".
+	FrameOptions string
+	// ReferrerPolicy is the Referrer-Policy header, e.g.
+	// "strict-origin-when-cross-origin".
+	ReferrerPolicy string
+	// NoSniff sets "X-Content-Type-Options: nosniff".
+	NoSniff bool
+}
+
 // CForm constructs an IForm - see HttpResourceConfig.
 type CForm func() IForm
 
@@ -367,6 +632,13 @@ type FormConfig map[string]FormFieldConfig
 type FormFieldConfig struct {
 	Description string
 	Required    bool
+	// Rules is the field's validation rule set, e.g. "min=3,max=20,email" -
+	// checked whenever the form is filled, see the validation package. It
+	// adds to the rules of the field's "validate" struct tag.
+	Rules string
+	// MaxSize is, for a file upload field, the max size of each file in
+	// bytes - overrides the app's default, see http.UploadLimits.
+	MaxSize int64
 }
 
 // IRouter dispatches incoming requests to registered resources - see
@@ -375,6 +647,14 @@ type IRouter interface {
 	// AddMiddleware registers a middleware, run before every request.
 	AddMiddleware(FMiddleware)
 
+	// AddWrapMiddleware registers a middleware wrapping every request's
+	// handling, able to short-circuit it or post-process its response.
+	AddWrapMiddleware(FWrapMiddleware)
+
+	// Group starts a group of routes registered under prefix, with
+	// middleware that only runs for the group's own routes.
+	Group(prefix string, mw ...FMiddleware) IRouteGroup
+
 	// Resources returns all registered resources, keyed by HTTP method then route.
 	Resources() map[string]HttpResourcesList
 
@@ -384,8 +664,10 @@ type IRouter interface {
 	// RegisterResources registers a batch of routes.
 	RegisterResources(routes HttpResourcesList)
 
-	// RegisterResource registers a single route/method.
-	RegisterResource(route string, method string, cResource CHttpResource)
+	// RegisterResource registers a single route/method - route may be a
+	// pattern with "{name}", "{name:type}" and trailing "{name...}" segments.
+	// mw, if given, runs for this route/method only.
+	RegisterResource(route string, method string, cResource CHttpResource, mw ...FMiddleware)
 
 	// RegisterFileAssets registers static file routes.
 	RegisterFileAssets(assets map[string]string)
@@ -393,14 +675,65 @@ type IRouter interface {
 	// RegisterProxy registers routes proxied through to another server.
 	RegisterProxy(conf HttpProxyConfig)
 
+	// SetCompression enables gzip/deflate compression of responses.
+	SetCompression(conf CompressionConfig)
+
+	// SetAssetsCacheControl sets the Cache-Control header of assets per URL
+	// prefix, as registered via RegisterFileAssets.
+	SetAssetsCacheControl(rules map[string]string)
+
+	// SetCors enables CORS - allowed cross-origin requests get the
+	// Access-Control-* headers, and preflights to registered routes are
+	// answered automatically.
+	SetCors(conf CorsConfig)
+
+	// SetSecurityHeaders sets the security headers (CSP, HSTS...) of every
+	// response.
+	SetSecurityHeaders(conf SecurityHeadersConfig)
+
+	// RegisterSerializer registers a serializer, replacing the one of the
+	// same content type - responses are serialized per the request's
+	// Accept header and request bodies decoded per their Content-Type.
+	RegisterSerializer(c CSerializer)
+
+	// Serializers returns the registered serializers, the default one
+	// (used when the Accept header doesn't pick one) first.
+	Serializers() []ISerializer
+
 	// GetAssetRoute returns the registered route for a file path, if any.
 	GetAssetRoute(path string) string
 
 	// Handle runs res for the given route/writer/request and returns its response.
 	Handle(res IHttpResource, route string, w http.ResponseWriter, r *http.Request) IHttpResponse
 
-	// Start begins serving requests.
-	Start()
+	// ServeHTTP serves a request - the router is the app's whole
+	// http.Handler, its assets included.
+	ServeHTTP(w http.ResponseWriter, r *http.Request)
+}
+
+// IRouteGroup registers routes under a shared path prefix, with middleware
+// that only runs for them - see IRouter.Group.
+type IRouteGroup interface {
+	// Prefix returns the group's full path prefix, its parents' included.
+	Prefix() string
+
+	// AddMiddleware registers a middleware run only for the group's routes,
+	// nested groups included.
+	AddMiddleware(FMiddleware)
+
+	// AddWrapMiddleware registers a middleware wrapping the handling of the
+	// group's routes, nested groups included.
+	AddWrapMiddleware(FWrapMiddleware)
+
+	// Group starts a nested group under prefix, inside this group's own.
+	Group(prefix string, mw ...FMiddleware) IRouteGroup
+
+	// RegisterResources registers a batch of routes under the group's prefix.
+	RegisterResources(routes HttpResourcesList)
+
+	// RegisterResource registers a single route/method under the group's
+	// prefix - mw, if given, runs for this route/method only.
+	RegisterResource(route string, method string, cResource CHttpResource, mw ...FMiddleware)
 }
 
 // IHandleContext carries one request's state through middleware and its
@@ -418,7 +751,8 @@ type IHandleContext interface {
 	// App returns the owning application.
 	App() IApp
 
-	// Route returns the matched route.
+	// Route returns the matched route - the registered pattern (e.g.
+	// "/users/{id:int}") for a pattern route, not the requested path.
 	Route() string
 
 	// Method returns the HTTP method.
@@ -430,6 +764,25 @@ type IHandleContext interface {
 	// Request returns the underlying *http.Request.
 	Request() *http.Request
 
+	// SetPathParams sets the values captured by the matched route's pattern.
+	SetPathParams(params map[string]string)
+
+	// PathParams returns the values captured by the matched route's
+	// pattern, keyed by param name - empty for an exact route.
+	PathParams() map[string]string
+
+	// PathParam returns a single captured value by name, or "".
+	PathParam(name string) string
+
+	// RequestID returns the request's ID - the client's "X-Request-ID"
+	// header if it sent a valid one (up to 64 letters, digits, '.', '_' and
+	// '-'), a generated ID otherwise.
+	RequestID() string
+
+	// Logger returns a logger scoped to this request - its entries carry
+	// the route, method and request ID.
+	Logger() IStructuredLogger
+
 	// Resource returns the resource handling this request.
 	Resource() IHttpResource
 
@@ -534,6 +887,15 @@ type IHttpResource interface {
 	// FailResponse builds a failed JSON response.
 	FailResponse(conf JsonResponseConfig) IHttpResponse
 
+	// StreamResponse builds a response streaming its body.
+	StreamResponse(conf StreamResponseConfig) IHttpResponse
+
+	// FileResponse builds a file download response, supporting Range requests.
+	FileResponse(conf FileResponseConfig) IHttpResponse
+
+	// SSEResponse builds a Server-Sent Events response.
+	SSEResponse(conf SSEResponseConfig) IHttpResponse
+
 	// ErrorResponse builds a JSON error response with the given HTTP code and message.
 	ErrorResponse(code int, msg string) IHttpResponse
 
@@ -602,7 +964,9 @@ type IFormFiller interface {
 	SetForm(f IForm) IFormFiller
 
 	// SetContext sets the request context to fill the form from (an HTTP
-	// request's GET query or JSON/urlencoded body) - mutually exclusive with SetDict.
+	// request's GET query, urlencoded/multipart body or a body decoded by
+	// the router's serializer for its Content-Type, plus the route's path
+	// params) - mutually exclusive with SetDict.
 	SetContext(ctx IHandleContext) IFormFiller
 
 	// SetDict sets an already-parsed kernel.Dict to fill the form from -
@@ -615,10 +979,18 @@ type IFormFiller interface {
 	Fill() error
 }
 
-// ISerializer writes an IHttpResponse's data in a specific format.
+// ISerializer encodes response data to, and decodes request bodies from,
+// one media type - see IRouter.RegisterSerializer.
 type ISerializer interface {
-	// Serialize writes r's data in this serializer's format.
-	Serialize(r IHttpResponse)
+	// ContentType returns the media type, e.g. "application/json".
+	ContentType() string
+
+	// Serialize encodes response data - returns an error for data the
+	// format can't represent (e.g. a non-list for CSV).
+	Serialize(data any) ([]byte, error)
+
+	// Deserialize decodes a request body.
+	Deserialize(body []byte) (Dict, error)
 }
 
 // IError is an application error carrying a numeric code alongside its message.
@@ -658,9 +1030,22 @@ const EVENT_APP_BEFORE_HANDLE_REQUEST = "appBeforeHandleRequest"
 // EVENT_APP_BEFORE_SEND_RESPONSE fires before the app sends a response.
 const EVENT_APP_BEFORE_SEND_RESPONSE = "appBeforeSendResponse"
 
+// EVENT_APP_AFTER_HANDLE_REQUEST fires once the app has handled an incoming
+// request, the response sent - its payload carries "route" (the matched
+// route, "" if none matched), "method", "status" (int), "duration"
+// (time.Duration) and "context" (the request's IHandleContext, nil if no
+// resource matched).
+const EVENT_APP_AFTER_HANDLE_REQUEST = "appAfterHandleRequest"
+
 // EVENT_APP_BEFORE_SEND_ASSET fires before the app sends a static asset.
 const EVENT_APP_BEFORE_SEND_ASSET = "appBeforeSendAsset"
 
+// EVENT_APP_BEFORE_SHUTDOWN fires once App.Run receives SIGINT/SIGTERM,
+// before it waits out the app's "ShutdownDrainDelay" and stops accepting
+// requests - e.g. for the app to report itself not ready, so load
+// balancers move traffic away first.
+const EVENT_APP_BEFORE_SHUTDOWN = "appBeforeShutdown"
+
 // EVENT_APP_BEFORE_FINAL fires before the app runs its shutdown/cleanup.
 const EVENT_APP_BEFORE_FINAL = "appBeforeFinal"
 
@@ -676,16 +1061,44 @@ const EVENT_CONFIG_REFRESHED = "configRefreshed"
 // FEventHandler handles a single IEvent - see IEventManager.Subscribe.
 type FEventHandler func(e IEvent)
 
+// EventToken identifies a subscription - see IEventManager.Unsubscribe.
+type EventToken uint64
+
+// EventSubscribeConfig configures a subscription - see IEventManager.Subscribe.
+type EventSubscribeConfig struct {
+	// Priority orders the handlers of an event - higher ones run first,
+	// equal ones in the order they subscribed in. 0 by default.
+	Priority int
+	// Async runs the handler on the manager's worker pool rather than in
+	// the goroutine triggering the event - Trigger doesn't wait for it, and
+	// a panic in it is logged instead of reaching the trigger.
+	Async bool
+}
+
 // IEventManager dispatches named events to subscribed handlers - see IApp.Events.
+// Handlers subscribe to an event name or a pattern of them, "*" matching
+// any run of characters - e.g. "app*" for all the app's own events, or
+// "orders.*" for a namespace.
 type IEventManager interface {
-	// Subscribe registers a function to run when eventName fires.
-	Subscribe(eventName string, handler FEventHandler)
+	// Subscribe registers a function to run when an event matching pattern
+	// fires - synchronously, with priority 0, unless conf says otherwise.
+	Subscribe(pattern string, handler FEventHandler, conf ...EventSubscribeConfig) EventToken
+
+	// Handle registers an IEventHandler to run when an event matching
+	// pattern fires - see Subscribe.
+	Handle(pattern string, handler IEventHandler, conf ...EventSubscribeConfig) EventToken
 
-	// Handle registers an IEventHandler to run when eventName fires.
-	Handle(eventName string, handler IEventHandler)
+	// Unsubscribe removes the subscription token identifies, reporting
+	// whether there was one.
+	Unsubscribe(token EventToken) bool
 
-	// Trigger fires eventName with the given payload data.
+	// Trigger fires eventName with the given payload data, running its
+	// synchronous handlers before returning - until one stops propagation.
 	Trigger(eventName string, d ...IDict)
+
+	// Close waits for the async handlers already dispatched and stops the
+	// worker pool - async handlers run synchronously from then on.
+	Close()
 }
 
 // IEvent is a single firing of a named event, carrying an optional payload.
@@ -701,6 +1114,13 @@ type IEvent interface {
 
 	// Payload returns the event's payload data.
 	Payload() IDict
+
+	// StopPropagation keeps the event from reaching the handlers not run
+	// (or dispatched, for async ones) yet.
+	StopPropagation()
+
+	// IsPropagationStopped reports whether StopPropagation was called.
+	IsPropagationStopped() bool
 }
 
 // IEventHandler is a reusable, app-bound handler for one or more events -
@@ -3,7 +3,12 @@
 // an EventManager (see kernel.IApp.Events), fire them with Trigger.
 package events
 
-import "github.com/epicoon/lxgo/kernel"
+import (
+	"maps"
+	"sync/atomic"
+
+	"github.com/epicoon/lxgo/kernel"
+)
 
 /** @interface kernel.IEvent */
 
@@ -12,6 +17,7 @@ type Event struct {
 	app     kernel.IApp
 	name    string
 	payload kernel.IDict
+	stopped atomic.Bool
 }
 
 var _ kernel.IEvent = (*Event)(nil)
@@ -46,3 +52,27 @@ func (e *Event) SetPayload(d kernel.IDict) {
 func (e *Event) Payload() kernel.IDict {
 	return e.payload
 }
+
+// StopPropagation keeps the event from reaching the handlers not run yet.
+func (e *Event) StopPropagation() {
+	e.stopped.Store(true)
+}
+
+// IsPropagationStopped reports whether StopPropagation was called.
+func (e *Event) IsPropagationStopped() bool {
+	return e.stopped.Load()
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// clone copies e for an async handler - its payload's values are shared,
+// but not the payload itself.
+func (e *Event) clone() *Event {
+	c := NewEvent(e.app, e.name)
+	if e.payload != nil {
+		c.payload = kernel.Dict(maps.Clone(e.payload.ToMap()))
+	}
+	return c
+}
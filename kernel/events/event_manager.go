@@ -1,14 +1,50 @@
 package events
 
-import "github.com/epicoon/lxgo/kernel"
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+// DefaultWorkers is the number of goroutines running async handlers unless
+// PoolConfig.Workers says otherwise.
+const DefaultWorkers = 4
+
+// DefaultQueueSize is how many async handler runs may wait for a worker
+// unless PoolConfig.QueueSize says otherwise.
+const DefaultQueueSize = 256
+
+// PoolConfig configures the worker pool async handlers run on - the
+// "Events" config section, see EventManager.SetPoolConfig.
+type PoolConfig struct {
+	Workers   int
+	QueueSize int
+}
 
 /** @interface kernel.IEventManager */
 
-// EventManager is the default kernel.IEventManager implementation.
+// EventManager is the default kernel.IEventManager implementation. Its
+// worker pool starts with the first async dispatch; once its queue is full,
+// Trigger runs an async handler itself.
 type EventManager struct {
-	app           kernel.IApp
-	events        map[string][]kernel.FEventHandler
-	eventHandlers map[string][]kernel.IEventHandler
+	app kernel.IApp
+
+	mu        sync.RWMutex
+	exact     map[string][]*subscription
+	wildcards []*subscription
+	byToken   map[kernel.EventToken]*subscription
+	lastToken kernel.EventToken
+
+	poolConf  PoolConfig
+	poolOnce  sync.Once
+	poolMu    sync.RWMutex
+	queue     chan asyncRun
+	closed    bool
+	workersWg sync.WaitGroup
 }
 
 var _ kernel.IEventManager = (*EventManager)(nil)
@@ -17,49 +53,236 @@ var _ kernel.IEventManager = (*EventManager)(nil)
 
 // NewEventManager constructs an EventManager bound to app.
 func NewEventManager(app kernel.IApp) *EventManager {
-	return &EventManager{app: app}
+	return &EventManager{
+		app:      app,
+		poolConf: PoolConfig{Workers: DefaultWorkers, QueueSize: DefaultQueueSize},
+	}
 }
 
-// Subscribe registers a function to run when eventName fires.
-func (em *EventManager) Subscribe(eventName string, handler kernel.FEventHandler) {
-	if em.events == nil {
-		em.events = make(map[string][]kernel.FEventHandler)
+// SetPoolConfig sets the async handlers' worker pool size - it only takes
+// effect before the first async dispatch. Zero fields keep their defaults.
+func (em *EventManager) SetPoolConfig(c PoolConfig) {
+	if c.Workers > 0 {
+		em.poolConf.Workers = c.Workers
 	}
-	if em.events[eventName] == nil {
-		em.events[eventName] = make([]kernel.FEventHandler, 0, 1)
+	if c.QueueSize > 0 {
+		em.poolConf.QueueSize = c.QueueSize
 	}
-	em.events[eventName] = append(em.events[eventName], handler)
 }
 
-// Handle registers an IEventHandler to run when eventName fires, binding it to the manager's app.
-func (em *EventManager) Handle(eventName string, handler kernel.IEventHandler) {
-	if em.eventHandlers == nil {
-		em.eventHandlers = make(map[string][]kernel.IEventHandler)
+// Subscribe registers a function to run when an event matching pattern fires.
+func (em *EventManager) Subscribe(pattern string, handler kernel.FEventHandler, conf ...kernel.EventSubscribeConfig) kernel.EventToken {
+	var c kernel.EventSubscribeConfig
+	if len(conf) > 0 {
+		c = conf[0]
+	}
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	if em.exact == nil {
+		em.exact = make(map[string][]*subscription)
+		em.byToken = make(map[kernel.EventToken]*subscription)
 	}
-	if em.eventHandlers[eventName] == nil {
-		em.eventHandlers[eventName] = make([]kernel.IEventHandler, 0, 1)
+
+	em.lastToken++
+	sub := &subscription{
+		token:    em.lastToken,
+		pattern:  pattern,
+		priority: c.Priority,
+		async:    c.Async,
+		handler:  handler,
 	}
+	if strings.Contains(pattern, "*") {
+		em.wildcards = append(em.wildcards, sub)
+	} else {
+		em.exact[pattern] = append(em.exact[pattern], sub)
+	}
+	em.byToken[sub.token] = sub
+	return sub.token
+}
+
+// Handle registers an IEventHandler to run when an event matching pattern
+// fires, binding it to the manager's app.
+func (em *EventManager) Handle(pattern string, handler kernel.IEventHandler, conf ...kernel.EventSubscribeConfig) kernel.EventToken {
 	handler.SetApp(em.app)
-	em.eventHandlers[eventName] = append(em.eventHandlers[eventName], handler)
+	return em.Subscribe(pattern, handler.Run, conf...)
 }
 
-// Trigger fires eventName, running every subscribed function and IEventHandler with the given payload data.
+// Unsubscribe removes the subscription token identifies.
+func (em *EventManager) Unsubscribe(token kernel.EventToken) bool {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	sub, ok := em.byToken[token]
+	if !ok {
+		return false
+	}
+	delete(em.byToken, token)
+	if strings.Contains(sub.pattern, "*") {
+		em.wildcards = without(em.wildcards, sub)
+	} else if subs := without(em.exact[sub.pattern], sub); len(subs) > 0 {
+		em.exact[sub.pattern] = subs
+	} else {
+		delete(em.exact, sub.pattern)
+	}
+	return true
+}
+
+// Trigger fires eventName, running its handlers with the given payload
+// data in priority order - synchronous ones before returning, async ones
+// dispatched to the worker pool - until one stops propagation. An async
+// handler gets its own copy of the event, with the payload as it was when
+// it was dispatched - stopping its propagation affects no other handler.
 func (em *EventManager) Trigger(eventName string, d ...kernel.IDict) {
+	subs := em.matching(eventName)
+	if len(subs) == 0 {
+		return
+	}
+
 	e := NewEvent(em.app, eventName)
 	if len(d) == 1 {
 		e.SetPayload(d[0])
 	}
 
-	funcs, ok := em.events[eventName]
-	if ok {
-		for _, f := range funcs {
-			f(e)
+	for _, sub := range subs {
+		if e.IsPropagationStopped() {
+			return
+		}
+		if sub.async {
+			em.dispatch(asyncRun{sub: sub, event: e.clone()})
+		} else {
+			sub.handler(e)
 		}
 	}
-	handlers, ok := em.eventHandlers[eventName]
-	if ok {
-		for _, handler := range handlers {
-			handler.Run(e)
+}
+
+// Close waits for the async handlers already dispatched and stops the
+// worker pool.
+func (em *EventManager) Close() {
+	em.poolMu.Lock()
+	if em.closed {
+		em.poolMu.Unlock()
+		return
+	}
+	em.closed = true
+	if em.queue != nil {
+		close(em.queue)
+	}
+	em.poolMu.Unlock()
+	em.workersWg.Wait()
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+type subscription struct {
+	token    kernel.EventToken
+	pattern  string
+	priority int
+	async    bool
+	handler  kernel.FEventHandler
+}
+
+type asyncRun struct {
+	sub   *subscription
+	event kernel.IEvent
+}
+
+// matching returns the subscriptions eventName reaches, by priority and
+// then in subscription order.
+func (em *EventManager) matching(eventName string) []*subscription {
+	em.mu.RLock()
+	subs := make([]*subscription, 0, len(em.exact[eventName]))
+	subs = append(subs, em.exact[eventName]...)
+	for _, sub := range em.wildcards {
+		if matchPattern(sub.pattern, eventName) {
+			subs = append(subs, sub)
+		}
+	}
+	em.mu.RUnlock()
+
+	sort.SliceStable(subs, func(i, j int) bool {
+		if subs[i].priority != subs[j].priority {
+			return subs[i].priority > subs[j].priority
+		}
+		return subs[i].token < subs[j].token
+	})
+	return subs
+}
+
+// dispatch queues run for the worker pool, starting it if needed - or runs
+// it right away once the pool is closed or its queue is full. Never waiting
+// for room under poolMu keeps a handler triggering async events from
+// blocking Close, and Close from blocking the workers.
+func (em *EventManager) dispatch(run asyncRun) {
+	em.poolOnce.Do(em.startPool)
+
+	em.poolMu.RLock()
+	if !em.closed {
+		select {
+		case em.queue <- run:
+			em.poolMu.RUnlock()
+			return
+		default:
+		}
+	}
+	em.poolMu.RUnlock()
+	em.runIsolated(run)
+}
+
+func (em *EventManager) startPool() {
+	em.poolMu.Lock()
+	defer em.poolMu.Unlock()
+	if em.closed {
+		return
+	}
+	em.queue = make(chan asyncRun, em.poolConf.QueueSize)
+	for i := 0; i < em.poolConf.Workers; i++ {
+		em.workersWg.Add(1)
+		go func() {
+			defer em.workersWg.Done()
+			for run := range em.queue {
+				em.runIsolated(run)
+			}
+		}()
+	}
+}
+
+// runIsolated runs an async handler, logging a panic in it rather than
+// letting it take the process down.
+func (em *EventManager) runIsolated(run asyncRun) {
+	defer func() {
+		if r := recover(); r != nil && em.app != nil {
+			em.app.LogError(fmt.Sprintf("Panic in '%s' event handler: %v\n%s", run.event.Name(), r, debug.Stack()), "Events")
+		}
+	}()
+	run.sub.handler(run.event)
+}
+
+func without(subs []*subscription, sub *subscription) []*subscription {
+	for i, s := range subs {
+		if s == sub {
+			return append(subs[:i:i], subs[i+1:]...)
+		}
+	}
+	return subs
+}
+
+// matchPattern reports whether name matches pattern, each "*" in it
+// matching any run of characters.
+func matchPattern(pattern, name string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(name, parts[0]) {
+		return false
+	}
+	name = name[len(parts[0]):]
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		i := strings.Index(name, part)
+		if i < 0 {
+			return false
 		}
+		name = name[i+len(part):]
 	}
+	return strings.HasSuffix(name, parts[last])
 }
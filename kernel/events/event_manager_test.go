@@ -0,0 +1,196 @@
+package events_test
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	"github.com/epicoon/lxgo/kernel/events"
+)
+
+type recordingHandler struct {
+	app  kernel.IApp
+	runs *[]string
+}
+
+func (h *recordingHandler) SetApp(app kernel.IApp) { h.app = app }
+func (h *recordingHandler) App() kernel.IApp       { return h.app }
+func (h *recordingHandler) Run(e kernel.IEvent)    { *h.runs = append(*h.runs, "handler") }
+
+// panicLogger is a kernel.ILogger remembering the errors written.
+type panicLogger struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func (l *panicLogger) Log(msg, category string)        {}
+func (l *panicLogger) LogWarning(msg, category string) {}
+func (l *panicLogger) LogError(msg, category string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, msg)
+}
+
+func TestEventManager_PriorityAndPropagation(t *testing.T) {
+	em := events.NewEventManager(nil)
+	var runs []string
+	record := func(name string) kernel.FEventHandler {
+		return func(e kernel.IEvent) { runs = append(runs, name) }
+	}
+
+	em.Subscribe("orders.created", record("default"))
+	em.Subscribe("orders.created", record("high"), kernel.EventSubscribeConfig{Priority: 10})
+	em.Handle("orders.created", &recordingHandler{runs: &runs})
+	em.Subscribe("orders.created", record("low"), kernel.EventSubscribeConfig{Priority: -1})
+
+	em.Trigger("orders.created")
+	if !slices.Equal(runs, []string{"high", "default", "handler", "low"}) {
+		t.Fatalf("expected priority, then subscription order, got %v", runs)
+	}
+
+	runs = nil
+	em.Subscribe("orders.created", func(e kernel.IEvent) { e.StopPropagation() }, kernel.EventSubscribeConfig{Priority: 5})
+	em.Trigger("orders.created")
+	if !slices.Equal(runs, []string{"high"}) {
+		t.Fatalf("expected propagation to stop, got %v", runs)
+	}
+}
+
+func TestEventManager_WildcardsAndUnsubscribe(t *testing.T) {
+	em := events.NewEventManager(nil)
+	var names []string
+	token := em.Subscribe("app*", func(e kernel.IEvent) { names = append(names, e.Name()) })
+	em.Subscribe("orders.*.failed", func(e kernel.IEvent) { names = append(names, e.Name()) })
+
+	for _, name := range []string{
+		kernel.EVENT_APP_BEFORE_FINAL, "orders.payment.failed", "orders.failed", "rendererBeforeRender",
+	} {
+		em.Trigger(name)
+	}
+	if !slices.Equal(names, []string{kernel.EVENT_APP_BEFORE_FINAL, "orders.payment.failed"}) {
+		t.Fatalf("unexpected matches %v", names)
+	}
+
+	if !em.Unsubscribe(token) || em.Unsubscribe(token) {
+		t.Fatal("expected a subscription to be removed exactly once")
+	}
+	names = nil
+	em.Trigger(kernel.EVENT_APP_BEFORE_FINAL)
+	if len(names) != 0 {
+		t.Fatalf("expected no handler after Unsubscribe, got %v", names)
+	}
+}
+
+func TestEventManager_Async(t *testing.T) {
+	a, err := apptest.New(kernel.Dict{"Events": kernel.Dict{"Workers": 2, "QueueSize": 1}})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	logger := &panicLogger{}
+	a.SetLogger(logger)
+	em := a.Events()
+
+	var (
+		mu    sync.Mutex
+		count int
+	)
+	release := make(chan struct{})
+	em.Subscribe("job.*", func(e kernel.IEvent) {
+		<-release
+		mu.Lock()
+		count++
+		mu.Unlock()
+	}, kernel.EventSubscribeConfig{Async: true})
+	em.Subscribe("job.broken", func(e kernel.IEvent) { panic("boom") }, kernel.EventSubscribeConfig{Async: true})
+
+	var syncRan bool
+	em.Subscribe("job.done", func(e kernel.IEvent) { syncRan = true })
+	em.Trigger("job.done")
+	if !syncRan {
+		t.Fatal("expected the synchronous handler to run before Trigger returns")
+	}
+
+	close(release)
+	for i := 0; i < 4; i++ {
+		em.Trigger("job.done")
+	}
+	em.Trigger("job.broken")
+	em.Close()
+
+	if count != 6 {
+		t.Fatalf("expected every async handler to have run by Close, got %d", count)
+	}
+	if len(logger.errors) != 1 || !strings.Contains(logger.errors[0], "Panic in 'job.broken' event handler: boom") {
+		t.Fatalf("expected the panic to be logged, got %v", logger.errors)
+	}
+
+	em.Trigger("job.done")
+	if count != 7 {
+		t.Fatal("expected async handlers to run synchronously once closed")
+	}
+}
+
+func TestEventManager_AsyncTriggersAsync(t *testing.T) {
+	em := events.NewEventManager(nil)
+	em.SetPoolConfig(events.PoolConfig{Workers: 1, QueueSize: 1})
+
+	var count atomic.Int32
+	triggered := make(chan struct{})
+	em.Subscribe("b", func(e kernel.IEvent) { count.Add(1) }, kernel.EventSubscribeConfig{Async: true})
+	em.Subscribe("a", func(e kernel.IEvent) {
+		// The only worker is busy here - the second one finds the queue full.
+		for i := 0; i < 3; i++ {
+			em.Trigger("b")
+		}
+		close(triggered)
+	}, kernel.EventSubscribeConfig{Async: true})
+
+	done := make(chan struct{})
+	go func() {
+		em.Trigger("a")
+		<-triggered
+		em.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Close to return, the pool deadlocked")
+	}
+	if count.Load() != 3 {
+		t.Fatalf("expected every nested handler to run, got %d", count.Load())
+	}
+}
+
+func TestEventManager_AsyncGetsOwnEvent(t *testing.T) {
+	em := events.NewEventManager(nil)
+
+	release := make(chan struct{})
+	seen := make(chan any, 1)
+	em.Subscribe("order.created", func(e kernel.IEvent) {
+		<-release
+		e.StopPropagation()
+		seen <- e.Payload().Get("status")
+	}, kernel.EventSubscribeConfig{Async: true, Priority: 1})
+	var syncRan bool
+	em.Subscribe("order.created", func(e kernel.IEvent) {
+		syncRan = true
+		e.Payload().Set("status", "changed")
+	})
+
+	em.Trigger("order.created", kernel.Dict{"status": "new"})
+	close(release)
+	em.Close()
+
+	if !syncRan {
+		t.Fatal("expected the async handler not to stop the synchronous one")
+	}
+	if got := <-seen; got != "new" {
+		t.Fatalf("expected the async handler's own payload, got %v", got)
+	}
+}
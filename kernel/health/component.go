@@ -0,0 +1,351 @@
+// Package health provides liveness and readiness endpoints for lxgo/kernel
+// applications - Component serves the results of the checks registered on
+// it, by the app itself or by other components (DB connections, the ws
+// server, session storage, the auth client), as JSON for orchestrators and
+// load balancers to poll.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/cast"
+	"github.com/epicoon/lxgo/kernel/config"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+// APP_COMPONENT_KEY is the key Component registers itself under - see
+// SetAppComponent/AppComponent.
+const APP_COMPONENT_KEY = "lxgo_health"
+
+// DefaultLivenessRoute is the route liveness is served on unless
+// Config.LivenessRoute says otherwise.
+const DefaultLivenessRoute = "/healthz"
+
+// DefaultReadinessRoute is the route readiness is served on unless
+// Config.ReadinessRoute says otherwise.
+const DefaultReadinessRoute = "/readyz"
+
+// DefaultTimeout is how long a single check may run unless Config.Timeout
+// says otherwise.
+const DefaultTimeout = 5 * time.Second
+
+// Report statuses.
+const (
+	STATUS_OK       = "ok"
+	STATUS_FAIL     = "fail"
+	STATUS_DRAINING = "draining"
+)
+
+// Check reports whether whatever it checks works - ctx is canceled once
+// the check's timeout runs out.
+type Check func(ctx context.Context) error
+
+// CheckResult is a single check's outcome in a Report.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Duration is how long the check took, in milliseconds.
+	Duration int64 `json:"duration_ms"`
+}
+
+// Report is what the liveness/readiness routes respond with.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Ok reports whether r's status is STATUS_OK.
+func (r Report) Ok() bool {
+	return r.Status == STATUS_OK
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Config
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponentConfig */
+
+// Config is Component's app-component configuration.
+type Config struct {
+	*lxApp.ComponentConfig
+	// LivenessRoute is the route liveness is served on, DefaultLivenessRoute if unset.
+	LivenessRoute string
+	// ReadinessRoute is the route readiness is served on, DefaultReadinessRoute if unset.
+	ReadinessRoute string
+	// Timeout is how long a single check may run, in seconds - DefaultTimeout if unset.
+	Timeout int
+}
+
+/** @constructor kernel.CAppComponentConfig */
+
+// NewConfig constructs a Config.
+func NewConfig() kernel.IAppComponentConfig {
+	return &Config{ComponentConfig: lxApp.NewComponentConfigStruct()}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Component
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponent */
+
+// Component runs the app's liveness and readiness checks and serves their
+// results on Config.LivenessRoute and Config.ReadinessRoute - 200 if every
+// check passes, 503 otherwise. It checks the app's DB connections itself;
+// readiness also fails from the moment the app starts shutting down (see
+// kernel.EVENT_APP_BEFORE_SHUTDOWN). See SetAppComponent to register it on
+// an app.
+type Component struct {
+	*lxApp.AppComponent
+
+	mu        sync.RWMutex
+	liveness  map[string]Check
+	readiness map[string]Check
+	draining  atomic.Bool
+}
+
+var _ kernel.IAppComponent = (*Component)(nil)
+
+// SetAppComponent registers a new Component on app under
+// APP_COMPONENT_KEY, configured from the config section named by configKey.
+func SetAppComponent(app kernel.IApp, configKey string) error {
+	if app.HasComponent(APP_COMPONENT_KEY) {
+		return fmt.Errorf("the application already has component: %s", APP_COMPONENT_KEY)
+	}
+
+	c := NewComponent()
+	if err := lxApp.InitComponent(c, app, configKey); err != nil {
+		return fmt.Errorf("can not init health component: %s", err)
+	}
+
+	app.SetComponent(APP_COMPONENT_KEY, c)
+	return nil
+}
+
+// AppComponent returns the Component registered on app under APP_COMPONENT_KEY.
+func AppComponent(app kernel.IApp) (*Component, error) {
+	c := app.Component(APP_COMPONENT_KEY)
+	if c == nil {
+		return nil, fmt.Errorf("application component '%s' not found", APP_COMPONENT_KEY)
+	}
+
+	h, ok := c.(*Component)
+	if !ok {
+		return nil, fmt.Errorf("application component '%s' is not '*health.Component'", APP_COMPONENT_KEY)
+	}
+
+	return h, nil
+}
+
+/** @constructor */
+
+// NewComponent constructs a Component with no checks.
+func NewComponent() *Component {
+	return &Component{
+		AppComponent: lxApp.NewAppComponent(),
+		liveness:     make(map[string]Check),
+		readiness:    make(map[string]Check),
+	}
+}
+
+// Name returns the component's name - see kernel.IAppComponent.
+func (c *Component) Name() string {
+	return "Health"
+}
+
+// LogCategory returns the category the component's log methods write under.
+func (c *Component) LogCategory() string {
+	return "Health"
+}
+
+// CConfig returns Config's constructor - see kernel.IAppComponent.
+func (c *Component) CConfig() kernel.CAppComponentConfig {
+	return NewConfig
+}
+
+// Config returns the component's bound Config.
+func (c *Component) Config() *Config {
+	return (c.GetConfig()).(*Config)
+}
+
+// AfterInit registers the DB connection checks, the routes and the
+// shutdown handler - see kernel.IAppComponent.
+func (c *Component) AfterInit() {
+	if c.App().Connection() != nil {
+		c.AddReadinessCheck("db", dbCheck(c.App(), ""))
+	}
+	if dbConf, err := config.GetParam[kernel.Dict](c.App().Config(), "Database"); err == nil {
+		if names, err := cast.To[kernel.Dict](dbConf["Connections"]); err == nil {
+			for name := range names {
+				c.AddReadinessCheck("db:"+name, dbCheck(c.App(), name))
+			}
+		}
+	}
+
+	c.App().Events().Subscribe(kernel.EVENT_APP_BEFORE_SHUTDOWN, func(kernel.IEvent) {
+		c.draining.Store(true)
+	})
+
+	liveness, readiness := c.Config().LivenessRoute, c.Config().ReadinessRoute
+	if liveness == "" {
+		liveness = DefaultLivenessRoute
+	}
+	if readiness == "" {
+		readiness = DefaultReadinessRoute
+	}
+	router := c.App().Router()
+	router.RegisterResource(liveness, http.MethodGet, func() kernel.IHttpResource {
+		return &reportResource{Resource: lxHttp.NewResource(), report: c.Liveness}
+	})
+	router.RegisterResource(readiness, http.MethodGet, func() kernel.IHttpResource {
+		return &reportResource{Resource: lxHttp.NewResource(), report: c.Readiness}
+	})
+}
+
+// AddLivenessCheck registers a check of whether the app works at all - one
+// failing means it should be restarted. Replaces a check of the same name.
+func (c *Component) AddLivenessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness[name] = check
+}
+
+// AddReadinessCheck registers a check of whether the app can serve
+// requests right now - one failing means traffic should go elsewhere for a
+// while. Replaces a check of the same name.
+func (c *Component) AddReadinessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness[name] = check
+}
+
+// Liveness runs the liveness checks.
+func (c *Component) Liveness(ctx context.Context) Report {
+	return c.run(ctx, c.checks(c.liveness))
+}
+
+// Readiness runs the liveness and readiness checks - or none, reporting
+// STATUS_DRAINING, once the app is shutting down.
+func (c *Component) Readiness(ctx context.Context) Report {
+	if c.draining.Load() {
+		return Report{Status: STATUS_DRAINING}
+	}
+	checks := c.checks(c.liveness)
+	for name, check := range c.checks(c.readiness) {
+		checks[name] = check
+	}
+	return c.run(ctx, checks)
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+func (c *Component) checks(from map[string]Check) map[string]Check {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	checks := make(map[string]Check, len(from))
+	for name, check := range from {
+		checks[name] = check
+	}
+	return checks
+}
+
+// run runs checks concurrently, each within the configured timeout.
+func (c *Component) run(ctx context.Context, checks map[string]Check) Report {
+	timeout := DefaultTimeout
+	if c.Config().Timeout > 0 {
+		timeout = time.Duration(c.Config().Timeout) * time.Second
+	}
+
+	report := Report{Status: STATUS_OK, Checks: make(map[string]CheckResult, len(checks))}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := runCheck(ctx, check, timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != STATUS_OK {
+				report.Status = STATUS_FAIL
+			}
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+func runCheck(ctx context.Context, check Check, timeout time.Duration) (result CheckResult) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+	result.Duration = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Status = STATUS_FAIL
+		result.Error = err.Error()
+	} else {
+		result.Status = STATUS_OK
+	}
+	return result
+}
+
+// dbCheck pings the app's connection named name ("" for the main one).
+func dbCheck(app kernel.IApp, name string) Check {
+	return func(ctx context.Context) error {
+		conn := app.NamedConnection(name)
+		if conn == nil || conn.DB() == nil {
+			return fmt.Errorf("not connected")
+		}
+		return conn.DB().PingContext(ctx)
+	}
+}
+
+/** @interface kernel.IHttpResource */
+
+// reportResource serves a Report as JSON.
+type reportResource struct {
+	*lxHttp.Resource
+	report func(ctx context.Context) Report
+}
+
+func (r *reportResource) Run() kernel.IHttpResponse {
+	report := r.report(r.Request().Context())
+	code := http.StatusOK
+	if !report.Ok() {
+		code = http.StatusServiceUnavailable
+	}
+	return r.JsonResponse(kernel.JsonResponseConfig{
+		Code:    code,
+		Headers: map[string]string{"Cache-Control": "no-store"},
+		Data:    report,
+	})
+}
@@ -0,0 +1,120 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	"github.com/epicoon/lxgo/kernel/health"
+)
+
+func getReport(t *testing.T, url string) (int, health.Report) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	var report health.Report
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("can not decode the report: %v", err)
+	}
+	return resp.StatusCode, report
+}
+
+func TestComponent_ServesChecks(t *testing.T) {
+	a, err := apptest.New(kernel.Dict{
+		"Components": kernel.Dict{"Health": kernel.Dict{"ReadinessRoute": "/ready", "Timeout": 1}},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if err := health.SetAppComponent(a, "Components.Health"); err != nil {
+		t.Fatalf("SetAppComponent: %v", err)
+	}
+	h, err := health.AppComponent(a)
+	if err != nil {
+		t.Fatalf("AppComponent: %v", err)
+	}
+
+	var cacheErr error
+	h.AddLivenessCheck("loop", func(context.Context) error { return nil })
+	h.AddReadinessCheck("cache", func(context.Context) error { return cacheErr })
+
+	srv := apptest.Server(a)
+	defer srv.Close()
+
+	code, report := getReport(t, srv.URL+health.DefaultLivenessRoute)
+	if code != http.StatusOK || !report.Ok() || len(report.Checks) != 1 {
+		t.Fatalf("expected a passing liveness report, got %d %+v", code, report)
+	}
+	code, report = getReport(t, srv.URL+"/ready")
+	if code != http.StatusOK || report.Checks["cache"].Status != health.STATUS_OK || len(report.Checks) != 2 {
+		t.Fatalf("expected a passing readiness report, got %d %+v", code, report)
+	}
+
+	cacheErr = errors.New("connection refused")
+	code, report = getReport(t, srv.URL+"/ready")
+	if code != http.StatusServiceUnavailable || report.Status != health.STATUS_FAIL ||
+		report.Checks["cache"].Error != "connection refused" || report.Checks["loop"].Status != health.STATUS_OK {
+		t.Fatalf("expected a failing readiness report, got %d %+v", code, report)
+	}
+	if code, _ := getReport(t, srv.URL+health.DefaultLivenessRoute); code != http.StatusOK {
+		t.Fatalf("a readiness check must not fail liveness, got %d", code)
+	}
+
+	cacheErr = nil
+	a.Events().Trigger(kernel.EVENT_APP_BEFORE_SHUTDOWN)
+	code, report = getReport(t, srv.URL+"/ready")
+	if code != http.StatusServiceUnavailable || report.Status != health.STATUS_DRAINING {
+		t.Fatalf("expected readiness to fail once shutting down, got %d %+v", code, report)
+	}
+}
+
+func TestComponent_CheckTimeout(t *testing.T) {
+	a, err := apptest.New(kernel.Dict{"Components": kernel.Dict{"Health": kernel.Dict{"Timeout": 1}}})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if err := health.SetAppComponent(a, "Components.Health"); err != nil {
+		t.Fatalf("SetAppComponent: %v", err)
+	}
+	h, _ := health.AppComponent(a)
+	h.AddLivenessCheck("stuck", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := h.Liveness(context.Background())
+	if report.Ok() || report.Checks["stuck"].Error != "timed out after 1s" || time.Since(start) > 1500*time.Millisecond {
+		t.Fatalf("expected the check to time out, got %+v", report)
+	}
+}
+
+func TestComponent_DBCheck(t *testing.T) {
+	a, err := apptest.New(kernel.Dict{
+		"Database":   kernel.Dict{"Connections": kernel.Dict{"reports": kernel.Dict{}}},
+		"Components": kernel.Dict{"Health": kernel.Dict{}},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if err := health.SetAppComponent(a, "Components.Health"); err != nil {
+		t.Fatalf("SetAppComponent: %v", err)
+	}
+	h, _ := health.AppComponent(a)
+
+	report := h.Readiness(context.Background())
+	for _, name := range []string{"db", "db:reports"} {
+		if report.Checks[name].Error != "not connected" {
+			t.Errorf("expected %s to be checked and not connected, got %+v", name, report.Checks[name])
+		}
+	}
+}
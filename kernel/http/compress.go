@@ -0,0 +1,256 @@
+package http
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+// DefaultCompressionMinSize is the smallest body compressed, in bytes, if
+// kernel.CompressionConfig.MinSize isn't set.
+const DefaultCompressionMinSize = 1024
+
+// DefaultCompressionTypes are the content types compressed if
+// kernel.CompressionConfig.Types isn't set.
+var DefaultCompressionTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// compressWriter compresses the body written through it with gzip or
+// deflate - once the body reaches the min size, or is complete, it's
+// compressed if its content type is allowed. Call Close once done.
+type compressWriter struct {
+	http.ResponseWriter
+	conf     kernel.CompressionConfig
+	encoding string
+
+	status  int
+	buf     []byte
+	decided bool
+	w       io.WriteCloser
+}
+
+// newCompressWriter wraps w if conf enables compression and r accepts one
+// of its encodings - returns w as is otherwise.
+func newCompressWriter(w http.ResponseWriter, r *http.Request, conf *kernel.CompressionConfig) http.ResponseWriter {
+	if conf == nil || r.Method == http.MethodHead {
+		return w
+	}
+	encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return w
+	}
+	c := *conf
+	if c.MinSize <= 0 {
+		c.MinSize = DefaultCompressionMinSize
+	}
+	if len(c.Types) == 0 {
+		c.Types = DefaultCompressionTypes
+	}
+	return &compressWriter{ResponseWriter: w, conf: c, encoding: encoding}
+}
+
+// closeCompressWriter finishes the body if w is a compressWriter.
+func closeCompressWriter(w http.ResponseWriter) {
+	if cw, ok := w.(*compressWriter); ok {
+		cw.Close()
+	}
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status == 0 {
+		cw.status = code
+	}
+	// Informational responses don't hold the final header back.
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		cw.status = 0
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.w != nil {
+			return cw.w.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.conf.MinSize {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what's buffered so far - compressed only if it already
+// reached the min size.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide()
+	}
+	if f, ok := cw.w.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		cw.decided = true
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http: response writer does not support hijacking")
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close sends the rest of the body.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// Nothing was written - leave the response to net/http.
+			cw.decided = true
+			return nil
+		}
+		if err := cw.decide(); err != nil {
+			return err
+		}
+	}
+	if cw.w != nil {
+		return cw.w.Close()
+	}
+	return nil
+}
+
+// decide writes the header, compressing the body if it's worth it, then
+// the buffered part of the body.
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	h := cw.Header()
+	contentType := h.Get("Content-Type")
+	if contentType == "" && len(cw.buf) > 0 {
+		contentType = http.DetectContentType(cw.buf)
+		h.Set("Content-Type", contentType)
+	}
+
+	if cw.compressible(contentType) {
+		h.Add("Vary", "Accept-Encoding")
+		if len(cw.buf) >= cw.conf.MinSize {
+			h.Set("Content-Encoding", cw.encoding)
+			h.Del("Content-Length")
+			if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				// The compressed body is a different representation.
+				h.Set("ETag", "W/"+etag)
+			}
+			var err error
+			if cw.encoding == "gzip" {
+				cw.w, err = gzip.NewWriterLevel(cw.ResponseWriter, cw.level())
+			} else {
+				cw.w, err = flate.NewWriter(cw.ResponseWriter, cw.level())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.w != nil {
+		_, err = cw.w.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) compressible(contentType string) bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if cw.status < 200 || cw.status == http.StatusNoContent ||
+		cw.status == http.StatusNotModified || cw.status == http.StatusPartialContent {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/event-stream" {
+		// Compressed events would sit in the compressor instead of
+		// reaching the client as they're sent.
+		return false
+	}
+	for _, t := range cw.conf.Types {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (cw *compressWriter) level() int {
+	if cw.conf.Level == 0 {
+		return flate.DefaultCompression
+	}
+	return cw.conf.Level
+}
+
+// acceptedEncoding returns the encoding an Accept-Encoding header value
+// prefers of gzip and deflate, or "".
+func acceptedEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "gzip" && name != "deflate" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		// gzip wins a tie - it's the better supported one.
+		if q > bestQ || (q == bestQ && name == "gzip") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+type sizedResource struct {
+	*Resource
+}
+
+func (r *sizedResource) Run() kernel.IHttpResponse {
+	size := len(r.Request().URL.Query().Get("size"))
+	return r.JsonResponse(kernel.JsonResponseConfig{Data: kernel.Dict{"text": strings.Repeat("a", size*100)}})
+}
+
+func newCompressionRouter() *Router {
+	router := NewRouter(nil).(*Router)
+	router.SetCompression(kernel.CompressionConfig{MinSize: 500})
+	router.RegisterResource("/data", "ALL", func() kernel.IHttpResource {
+		return &sizedResource{Resource: NewResource()}
+	})
+	return router
+}
+
+func serve(router http.Handler, method, url string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	for key, val := range headers {
+		req.Header.Set(key, val)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouter_Compression(t *testing.T) {
+	router := newCompressionRouter()
+	want := `{"text":"` + strings.Repeat("a", 1000) + `"}`
+
+	t.Run("gzip", func(t *testing.T) {
+		rec := serve(router, "GET", "/data?size=xxxxxxxxxx", map[string]string{"Accept-Encoding": "deflate;q=0.5, gzip"})
+		vary := strings.Join(rec.Header().Values("Vary"), ", ")
+		if rec.Header().Get("Content-Encoding") != "gzip" || vary != "Accept, Accept-Encoding" {
+			t.Fatalf("expected a gzipped response, got headers %v", rec.Header())
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := io.ReadAll(zr); string(body) != want {
+			t.Fatalf("got body %q", body)
+		}
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		rec := serve(router, "GET", "/data?size=xxxxxxxxxx", map[string]string{"Accept-Encoding": "deflate"})
+		if rec.Header().Get("Content-Encoding") != "deflate" {
+			t.Fatalf("expected a deflated response, got headers %v", rec.Header())
+		}
+		if body, _ := io.ReadAll(flate.NewReader(rec.Body)); string(body) != want {
+			t.Fatalf("got body %q", body)
+		}
+	})
+
+	t.Run("below min size", func(t *testing.T) {
+		rec := serve(router, "GET", "/data?size=x", map[string]string{"Accept-Encoding": "gzip"})
+		if rec.Header().Get("Content-Encoding") != "" || !strings.HasPrefix(rec.Body.String(), `{"text"`) {
+			t.Fatalf("expected an uncompressed response, got %v %q", rec.Header(), rec.Body.String())
+		}
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		rec := serve(router, "GET", "/data?size=xxxxxxxxxx", map[string]string{"Accept-Encoding": "br"})
+		if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != want {
+			t.Fatalf("expected an uncompressed response, got %v", rec.Header())
+		}
+	})
+}
+
+func TestRouter_ConditionalGet(t *testing.T) {
+	router := newCompressionRouter()
+
+	rec := serve(router, "GET", "/data?size=x", nil)
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected a weak ETag, got %q", etag)
+	}
+
+	rec = serve(router, "GET", "/data?size=x", map[string]string{"If-None-Match": `"other", ` + etag})
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+		t.Fatalf("expected an empty 304, got %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	rec = serve(router, "GET", "/data?size=xx", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expected a fresh response for changed data, got %d", rec.Code)
+	}
+
+	rec = serve(router, "POST", "/data?size=x", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != "" {
+		t.Fatalf("expected no conditional handling for POST, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestRouter_AssetsCacheControl(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte(strings.Repeat("console.log(1);\n", 200)), 0o644)
+	os.WriteFile(filepath.Join(dir, "logo.png"), []byte(strings.Repeat("\x89PNG", 500)), 0o644)
+
+	router := NewRouter(nil).(*Router)
+	router.SetCompression(kernel.CompressionConfig{})
+	router.SetAssetsCacheControl(map[string]string{"/assets/": "public, max-age=86400"})
+	router.RegisterFileAssets(map[string]string{"/assets/": dir})
+
+	rec := serve(router, "GET", "/assets/app.js", map[string]string{"Accept-Encoding": "gzip"})
+	if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != "public, max-age=86400" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("Content-Length") != "" {
+		t.Fatalf("expected a gzipped asset, got headers %v", rec.Header())
+	}
+
+	rec = serve(router, "GET", "/assets/logo.png", map[string]string{"Accept-Encoding": "gzip"})
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("an image must not be compressed, got headers %v", rec.Header())
+	}
+}
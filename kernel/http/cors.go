@@ -0,0 +1,157 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+// DefaultCorsMethods are the methods a preflight to a route registered for
+// any method allows, if kernel.CorsConfig.AllowMethods isn't set.
+var DefaultCorsMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// CheckCorsConfig reports an error for a CORS config that would be unsafe
+// to enable: AllowOrigins "*" with AllowCredentials would let any site
+// make credentialed requests and read the answers - the origins must be
+// listed instead.
+func CheckCorsConfig(conf kernel.CorsConfig) error {
+	if conf.AllowCredentials && slices.Contains(conf.AllowOrigins, "*") {
+		return errors.New(`AllowOrigins "*" can not be combined with AllowCredentials - list the allowed origins`)
+	}
+	return nil
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// serveCors sets the CORS headers of a cross-origin request and answers it
+// if it's a preflight to a registered route without its own OPTIONS
+// resource - reporting whether it did. A preflight from an origin not
+// allowed gets a 403.
+func (router *Router) serveCors(w http.ResponseWriter, r *http.Request) bool {
+	conf := router.cors
+	origin := r.Header.Get("Origin")
+	if conf == nil || origin == "" {
+		return false
+	}
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	var methods []string
+	if preflight {
+		methods = router.corsMethods(r.URL.Path)
+		if methods == nil {
+			return false
+		}
+	}
+
+	h := w.Header()
+	anyOrigin := slices.Contains(conf.AllowOrigins, "*")
+	if !anyOrigin {
+		h.Add("Vary", "Origin")
+	}
+	if !corsOriginAllowed(conf.AllowOrigins, origin) {
+		if preflight {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+		return preflight
+	}
+
+	if anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if conf.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if !preflight {
+		if len(conf.ExposeHeaders) > 0 {
+			h.Set("Access-Control-Expose-Headers", strings.Join(conf.ExposeHeaders, ", "))
+		}
+		return false
+	}
+
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	if len(conf.AllowMethods) > 0 {
+		methods = conf.AllowMethods
+	}
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(conf.AllowHeaders) > 0 && !slices.Contains(conf.AllowHeaders, "*") {
+		h.Set("Access-Control-Allow-Headers", strings.Join(conf.AllowHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	}
+	if conf.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(conf.MaxAge))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// corsMethods returns the methods registered for path - nil if there's no
+// route for it, or it has its own OPTIONS resource to answer preflights.
+func (router *Router) corsMethods(path string) []string {
+	for urlPrefix := range router.assetsMap {
+		if strings.HasPrefix(path, urlPrefix) {
+			return []string{http.MethodGet, http.MethodHead}
+		}
+	}
+
+	if path != "/" {
+		path, _ = strings.CutSuffix(path, "/")
+	}
+	hList, ok := router.resources[path]
+	if !ok || isPatternRoute(path) {
+		if router.patterns == nil {
+			return nil
+		}
+		route, _ := router.patterns.match(path)
+		if route == "" {
+			return nil
+		}
+		hList = router.resources[route]
+	}
+	if _, ok := hList[http.MethodOptions]; ok {
+		return nil
+	}
+	if _, ok := hList["ALL"]; ok {
+		return DefaultCorsMethods
+	}
+
+	methods := make([]string, 0, len(hList))
+	for method := range hList {
+		methods = append(methods, method)
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// corsOriginAllowed reports whether origin matches one of allowed - "*"
+// matches any, and "https://*.example.com" any subdomain.
+func corsOriginAllowed(allowed []string, origin string) bool {
+	for _, pattern := range allowed {
+		if pattern == "*" || strings.EqualFold(pattern, origin) {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(pattern, "*")
+		if ok && len(origin) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+			strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+func newCorsRouter(conf kernel.CorsConfig) *Router {
+	router := NewRouter(nil).(*Router)
+	router.SetCors(conf)
+	cResource := func() kernel.IHttpResource {
+		return &sizedResource{Resource: NewResource()}
+	}
+	router.RegisterResource("/items", "GET", cResource)
+	router.RegisterResource("/items", "POST", cResource)
+	router.RegisterResource("/items/{id:int}", "", cResource)
+	router.RegisterResource("/own", "OPTIONS", cResource)
+	return router
+}
+
+func preflight(router *Router, path, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, X-Token")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouter_CorsPreflight(t *testing.T) {
+	router := newCorsRouter(kernel.CorsConfig{
+		AllowOrigins: []string{"https://app.example.com", "https://*.plugins.example.com"},
+		MaxAge:       600,
+	})
+
+	rec := preflight(router, "/items", "https://app.example.com")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	h := rec.Header()
+	if h.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("unexpected Allow-Origin: %q", h.Get("Access-Control-Allow-Origin"))
+	}
+	if h.Get("Access-Control-Allow-Methods") != "GET, POST" {
+		t.Errorf("expected the route's methods, got %q", h.Get("Access-Control-Allow-Methods"))
+	}
+	if h.Get("Access-Control-Allow-Headers") != "Content-Type, X-Token" {
+		t.Errorf("expected the requested headers, got %q", h.Get("Access-Control-Allow-Headers"))
+	}
+	if h.Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("unexpected Max-Age: %q", h.Get("Access-Control-Max-Age"))
+	}
+
+	rec = preflight(router, "/items/7", "https://jspp.plugins.example.com")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for a wildcard origin, got %d", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Methods") != "GET, HEAD, POST, PUT, PATCH, DELETE" {
+		t.Errorf("expected the default methods for an any-method route, got %q", rec.Header().Get("Access-Control-Allow-Methods"))
+	}
+
+	if rec := preflight(router, "/items", "https://evil.com"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for an origin not allowed, got %d", rec.Code)
+	}
+	if rec := preflight(router, "/missing", "https://app.example.com"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown route, got %d", rec.Code)
+	}
+	if rec := preflight(router, "/own", "https://app.example.com"); rec.Code != http.StatusOK {
+		t.Errorf("expected the route's own OPTIONS resource to answer, got %d", rec.Code)
+	}
+}
+
+func TestRouter_CorsRequest(t *testing.T) {
+	router := newCorsRouter(kernel.CorsConfig{
+		AllowOrigins:     []string{"https://other.com"},
+		AllowCredentials: true,
+		ExposeHeaders:    []string{"X-Request-Id"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Origin", "https://other.com")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	h := rec.Header()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if h.Get("Access-Control-Allow-Origin") != "https://other.com" {
+		t.Errorf("expected the origin echoed with credentials, got %q", h.Get("Access-Control-Allow-Origin"))
+	}
+	if h.Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("expected Allow-Credentials")
+	}
+	if h.Get("Access-Control-Expose-Headers") != "X-Request-Id" {
+		t.Errorf("unexpected Expose-Headers: %q", h.Get("Access-Control-Expose-Headers"))
+	}
+	if !slices.Contains(h.Values("Vary"), "Origin") {
+		t.Errorf("expected Vary: Origin, got %q", h.Values("Vary"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/items", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected no CORS headers for a same-origin request")
+	}
+}
+
+func TestRouter_CorsAnyOriginWithCredentials(t *testing.T) {
+	conf := kernel.CorsConfig{AllowOrigins: []string{"*"}, AllowCredentials: true}
+	if err := CheckCorsConfig(conf); err == nil {
+		t.Fatal(`expected an error for "*" with credentials`)
+	}
+	router := newCorsRouter(conf)
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("expected CORS left disabled, got %v", rec.Header())
+	}
+
+	if err := CheckCorsConfig(kernel.CorsConfig{AllowOrigins: []string{"*"}}); err != nil {
+		t.Errorf(`expected "*" alone to be allowed, got %v`, err)
+	}
+}
+
+func TestRouter_SecurityHeaders(t *testing.T) {
+	router := newCorsRouter(kernel.CorsConfig{})
+	router.SetSecurityHeaders(kernel.SecurityHeadersConfig{
+		ContentSecurityPolicy: "default-src 'self'",
+		HSTSMaxAge:            31536000,
+		HSTSIncludeSubdomains: true,
+		FrameOptions:          "DENY",
+		ReferrerPolicy:        "no-referrer",
+		NoSniff:               true,
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	h := rec.Header()
+	if h.Get("Content-Security-Policy") != "default-src 'self'" {
+		t.Errorf("unexpected CSP: %q", h.Get("Content-Security-Policy"))
+	}
+	if h.Get("X-Frame-Options") != "DENY" || h.Get("Referrer-Policy") != "no-referrer" || h.Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("missing security headers: %v", h)
+	}
+	if h.Get("Strict-Transport-Security") != "" {
+		t.Errorf("expected no HSTS over plain HTTP")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Header().Get("Strict-Transport-Security") != "max-age=31536000; includeSubDomains" {
+		t.Errorf("unexpected HSTS: %q", rec.Header().Get("Strict-Transport-Security"))
+	}
+}
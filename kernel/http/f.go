@@ -49,3 +49,11 @@ func HtmlResponse(app kernel.IApp, conf kernel.HtmlResponseConfig) (kernel.IHttp
 	response.SetHtmlData(html)
 	return response, nil
 }
+
+// ErrorResponse builds an error IHttpResponse with the given HTTP code and
+// message - e.g. for a kernel.FWrapMiddleware short-circuiting a request.
+func ErrorResponse(code int, msg string) kernel.IHttpResponse {
+	response := new(Response)
+	response.SetError(code, msg)
+	return response
+}
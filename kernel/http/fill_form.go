@@ -1,14 +1,15 @@
 package http
 
 import (
-	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"reflect"
 	"strings"
 
 	"github.com/epicoon/lxgo/kernel"
 	"github.com/epicoon/lxgo/kernel/cast"
+	"github.com/epicoon/lxgo/kernel/validation"
 )
 
 /** @interface kernel.IFormFiller */
@@ -22,7 +23,9 @@ type formFiller struct {
 var _ kernel.IFormFiller = (*formFiller)(nil)
 
 // FormFiller starts a fluent form-filling call: chain SetForm with either
-// SetContext (fill from an HTTP request, GET query or JSON/urlencoded body)
+// SetContext (fill from an HTTP request, GET query, urlencoded/multipart
+// body or a body the router has a serializer for, plus the route's path
+// params)
 // or SetDict (fill from an already-parsed kernel.Dict), then call Fill.
 func FormFiller() kernel.IFormFiller {
 	return &formFiller{}
@@ -35,7 +38,10 @@ func (ff *formFiller) SetForm(f kernel.IForm) kernel.IFormFiller {
 }
 
 // SetContext sets the request context to fill the form from (an HTTP
-// request's GET query or JSON/urlencoded body) - mutually exclusive with SetDict.
+// request's GET query, urlencoded/multipart body - files bound to
+// UploadedFile fields - or a body decoded by the serializer for its
+// Content-Type, see Router.RegisterSerializer) - mutually exclusive with
+// SetDict.
 func (ff *formFiller) SetContext(ctx kernel.IHandleContext) kernel.IFormFiller {
 	ff.ctx = ctx
 	return ff
@@ -48,9 +54,10 @@ func (ff *formFiller) SetDict(d kernel.Dict) kernel.IFormFiller {
 	return ff
 }
 
-// Fill fills the form from whichever of SetContext/SetDict was called,
-// returning an error (without touching the form) if SetForm wasn't called,
-// if neither SetContext nor SetDict was, or was called both.
+// Fill fills the form from whichever of SetContext/SetDict was called -
+// checking its fields' rules, see validation.CheckForm - returning an
+// error (without touching the form) if SetForm wasn't called, if neither
+// SetContext nor SetDict was, or was called both.
 func (ff *formFiller) Fill() error {
 	if ff.form == nil {
 		return errors.New("form filler: no form set, call SetForm first")
@@ -77,26 +84,52 @@ func (ff *formFiller) Fill() error {
 func fillFormByHandleContext(f kernel.IForm, ctx kernel.IHandleContext) {
 	r := ctx.Request()
 
-	// GET-requests
+	var data kernel.Dict
+	var err error
 	if r.Method == http.MethodGet {
-		fillGetParams(f, r)
-		if !f.HasErrors() {
-			f.AfterFill()
+		// GET-requests
+		data = valuesToDict(r.URL.Query())
+	} else {
+		contentType := r.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = "plane/text"
 		}
+
+		if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+			data, err = parseForm(r)
+		} else if strings.HasPrefix(contentType, "multipart/form-data") {
+			data, err = parseMultipart(f, ctx)
+		} else if s := SerializerFor(contextSerializers(ctx), contentType); s != nil {
+			data, err = parseBody(r, s)
+		}
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			f.CollectCodifiedErrorf(ERR_BODY_TOO_LARGE, "request body is too large (max %d bytes)", tooLarge.Limit)
+		} else {
+			f.CollectErrorf("invalid request params")
+		}
+		return
+	}
+	if f.HasErrors() {
 		return
 	}
 
-	contentType := r.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "plane/text"
+	// Path params are part of the route itself, so they win over a
+	// same-named query/body param.
+	if pathParams := ctx.PathParams(); len(pathParams) > 0 {
+		if data == nil {
+			data = make(kernel.Dict, len(pathParams))
+		}
+		for key, val := range pathParams {
+			data[key] = val
+		}
 	}
 
-	if strings.HasPrefix(contentType, "application/json") {
-		parseJSON(f, r)
-	} else if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
-		parseForm(f, r)
+	if data != nil {
+		fillFormByDict(f, data)
 	}
-	//TODO more variants?
 
 	if !f.HasErrors() {
 		f.AfterFill()
@@ -114,6 +147,7 @@ func fillFormByDict(f kernel.IForm, dict kernel.Dict) {
 		return
 	}
 
+	validation.CheckForm(f, dict, f, "")
 	fillNestedForms(f, dict)
 }
 
@@ -125,9 +159,10 @@ var formInterfaceType = reflect.TypeOf((*kernel.IForm)(nil)).Elem()
 // own namespace). cast.DictToStruct deliberately skips kernel.IForm-typed
 // fields (see its own doc comment), so this fills each one in place (onto
 // the already-constructed instance, preserving its embedded
-// ErrorsCollector), then runs its own required-fields check, AfterFill/
-// Validate, and folds any errors it collects into f's own collection -
-// recursively, for however many levels deep the nesting goes.
+// ErrorsCollector), then runs its own required-fields check, the rules
+// of its fields (see validation.CheckForm), AfterFill/Validate, and folds
+// any errors it collects into f's own collection - recursively, for
+// however many levels deep the nesting goes.
 func fillNestedForms(f kernel.IForm, dict kernel.Dict) {
 	v := reflect.ValueOf(f)
 	if v.Kind() == reflect.Pointer {
@@ -191,6 +226,11 @@ func collectNestedForms(v reflect.Value, dict kernel.Dict, into kernel.IErrorsCo
 			continue
 		}
 
+		// Rule errors go to into directly, keeping their codes.
+		if !validation.CheckForm(nested, subDict, into, fullName) {
+			continue
+		}
+
 		nested.AfterFill()
 		if !nested.Validate() {
 			if nested.HasErrors() {
@@ -244,46 +284,76 @@ func asDict(v any) (kernel.Dict, bool) {
 	return nil, false
 }
 
-func fillGetParams(f kernel.IForm, r *http.Request) {
-	queryParams := r.URL.Query()
+func valuesToDict(values map[string][]string) kernel.Dict {
 	data := make(kernel.Dict)
-	for key, values := range queryParams {
-		if len(values) > 0 {
-			if len(values) == 1 {
-				data[key] = values[0]
+	for key, vals := range values {
+		if len(vals) > 0 {
+			if len(vals) == 1 {
+				data[key] = vals[0]
 			} else {
-				data[key] = values
+				data[key] = vals
 			}
 		}
 	}
-	fillFormByDict(f, data)
+	return data
 }
 
-func parseJSON(f kernel.IForm, r *http.Request) {
-	data := make(kernel.Dict)
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		f.CollectErrorf("invalid request params")
-		return
+// parseBody decodes the request body with s.
+func parseBody(r *http.Request, s kernel.ISerializer) (kernel.Dict, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
 	}
-	fillFormByDict(f, data)
+	return s.Deserialize(body)
 }
 
-func parseForm(f kernel.IForm, r *http.Request) {
+func parseForm(r *http.Request) (kernel.Dict, error) {
 	if err := r.ParseForm(); err != nil {
-		f.CollectErrorf("invalid request params")
-		return
+		return nil, err
 	}
-	data := make(kernel.Dict)
-	for key, values := range r.Form {
-		if len(values) > 0 {
-			if len(values) == 1 {
-				data[key] = values[0]
-			} else {
-				data[key] = values
+	return valuesToDict(r.Form), nil
+}
+
+// parseMultipart reads a multipart/form-data body within the app's
+// UploadLimits: its values as parseForm does, its files as *UploadedFile -
+// or []*UploadedFile, for a form field of a slice type. A file over its
+// field's size limit is collected into f as an error.
+func parseMultipart(f kernel.IForm, ctx kernel.IHandleContext) (kernel.Dict, error) {
+	r := ctx.Request()
+	limits := AppUploadLimits(ctx.App())
+	r.Body = http.MaxBytesReader(ctx.ResponseWriter(), r.Body, limits.MaxBodySize)
+	if err := r.ParseMultipartForm(limits.MaxMemory); err != nil {
+		return nil, err
+	}
+
+	data := valuesToDict(r.MultipartForm.Value)
+	conf := f.Config()
+	fields := buildFieldMap(reflect.ValueOf(f))
+	for name, headers := range r.MultipartForm.File {
+		maxSize := limits.MaxFileSize
+		if c, ok := conf[name]; ok && c.MaxSize > 0 {
+			maxSize = c.MaxSize
+		}
+
+		files := make([]*UploadedFile, 0, len(headers))
+		for _, fh := range headers {
+			if fh.Size > maxSize {
+				f.CollectCodifiedErrorf(ERR_FILE_TOO_LARGE, "%s: file %q is too large (max %d bytes)", name, fh.Filename, maxSize)
+				continue
 			}
+			files = append(files, NewUploadedFile(fh))
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		if field, ok := fields[name]; ok && field.Kind() == reflect.Slice {
+			data[name] = files
+		} else {
+			data[name] = files[0]
 		}
 	}
-	fillFormByDict(f, data)
+	return data, nil
 }
 
 func checkMissingParams(f kernel.IForm, data kernel.Dict) {
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/validation"
 )
 
 type testForm struct {
@@ -387,3 +388,61 @@ func TestFillNestedForms_DeepNesting_ErrorPathIsFullyDotted(t *testing.T) {
 		t.Fatalf("expected the full dotted path 'mid.nested' in the error, got %q", msg)
 	}
 }
+
+// rulesTestForm declares rules both ways - via Config and via a struct tag.
+type rulesTestForm struct {
+	*Form
+	Email string         `json:"email"`
+	Age   int            `json:"age" validate:"min=18"`
+	Child rulesChildForm `json:"child"`
+}
+
+func (f *rulesTestForm) Config() kernel.FormConfig {
+	return kernel.FormConfig{
+		"email": {Rules: "email"},
+	}
+}
+
+type rulesChildForm struct {
+	*Form
+	Kind string `json:"kind" validate:"enum=a|b"`
+}
+
+func newRulesTestForm() *rulesTestForm {
+	return &rulesTestForm{Form: NewForm(), Child: rulesChildForm{Form: NewForm()}}
+}
+
+func TestFormFiller_Fill_Rules(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     kernel.Dict
+		wantCode uint
+		wantMsg  string
+	}{
+		{"valid", kernel.Dict{"email": "a@b.c", "age": 20, "child": kernel.Dict{"kind": "a"}}, 0, ""},
+		{"absent_fields_are_not_checked", kernel.Dict{}, 0, ""},
+		{"config_rule", kernel.Dict{"email": "nope"}, validation.ERR_EMAIL, "email: must be a valid email address"},
+		{"tag_rule", kernel.Dict{"age": "17"}, validation.ERR_MIN, "age: must be at least 18"},
+		{"nested_form_rule", kernel.Dict{"child": kernel.Dict{"kind": "c"}}, validation.ERR_ENUM, "child.kind: must be one of: a, b"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newRulesTestForm()
+			if err := FormFiller().SetForm(f).SetDict(tc.data).Fill(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.wantCode == 0 {
+				if f.HasErrors() {
+					t.Fatalf("unexpected form error: %v", f.GetFirstError())
+				}
+				return
+			}
+			if !f.HasErrors() {
+				t.Fatal("expected a rule error")
+			}
+			if err := f.GetFirstError(); err.Code() != tc.wantCode || err.Error() != tc.wantMsg {
+				t.Fatalf("got %d %q, want %d %q", err.Code(), err.Error(), tc.wantCode, tc.wantMsg)
+			}
+		})
+	}
+}
@@ -5,23 +5,33 @@
 package http
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 
 	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/logger"
 )
 
+// RequestIDHeader is the header a request's ID is read from (if the client
+// sent one) and echoed back in - see IHandleContext.RequestID.
+const RequestIDHeader = "X-Request-ID"
+
 /** @interface kernel.IHandleContext */
 
 // HandleContext is the default kernel.IHandleContext implementation.
 type HandleContext struct {
-	app      kernel.IApp
-	route    string
-	method   string
-	writer   http.ResponseWriter
-	request  *http.Request
-	resource kernel.IHttpResource
-	params   map[string]any
-	metaData map[any]any
+	app        kernel.IApp
+	route      string
+	method     string
+	writer     http.ResponseWriter
+	request    *http.Request
+	resource   kernel.IHttpResource
+	pathParams map[string]string
+	requestID  string
+	logger     kernel.IStructuredLogger
+	params     map[string]any
+	metaData   map[any]any
 }
 
 var _ kernel.IHandleContext = (*HandleContext)(nil)
@@ -49,6 +59,14 @@ func (h *HandleContext) Init(
 	h.method = method
 	h.writer = writer
 	h.request = request
+	h.logger = nil
+	h.requestID = ""
+	if request != nil {
+		h.requestID = request.Header.Get(RequestIDHeader)
+	}
+	if !validRequestID(h.requestID) {
+		h.requestID = newRequestID()
+	}
 }
 
 // App returns the owning application.
@@ -84,6 +102,45 @@ func (c *HandleContext) Request() *http.Request {
 	return c.request
 }
 
+// SetPathParams sets the values captured by the matched route's pattern.
+func (c *HandleContext) SetPathParams(params map[string]string) {
+	c.pathParams = params
+}
+
+// PathParams returns the values captured by the matched route's pattern,
+// keyed by param name - empty for an exact route.
+func (c *HandleContext) PathParams() map[string]string {
+	if c.pathParams == nil {
+		return map[string]string{}
+	}
+	return c.pathParams
+}
+
+// PathParam returns a single captured value by name, or "".
+func (c *HandleContext) PathParam(name string) string {
+	return c.pathParams[name]
+}
+
+// RequestID returns the request's ID - the client's "X-Request-ID" header
+// if it sent one, a generated ID otherwise.
+func (c *HandleContext) RequestID() string {
+	return c.requestID
+}
+
+// Logger returns a logger scoped to this request, writing under the
+// "HttpHandling" category - its entries carry the route, method and
+// request ID.
+func (c *HandleContext) Logger() kernel.IStructuredLogger {
+	if c.logger == nil {
+		c.logger = logger.For(c.app).WithCategory("HttpHandling").With(
+			"route", c.Route(),
+			"method", c.Method(),
+			"request_id", c.requestID,
+		)
+	}
+	return c.logger
+}
+
 // Has reports whether key is set in the context.
 func (c *HandleContext) Has(key any) bool {
 	_, exists := c.metaData[key]
@@ -116,3 +173,34 @@ func (c *HandleContext) Get(key any) any {
 	}
 	return val
 }
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// newRequestID returns a random 16-hex-digit ID.
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+const maxRequestIDLength = 64
+
+// validRequestID reports whether a client-sent request ID is safe to log and
+// echo back - up to maxRequestIDLength letters, digits, '.', '_' and '-'.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package http
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleContext_RequestID(t *testing.T) {
+	tests := []struct {
+		header string
+		keep   bool
+	}{
+		{"", false},
+		{"req-1", true},
+		{"a1.B2_c3-D4", true},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+		{"req 1", false},
+		{"req\n{\"level\":\"error\"}", false},
+		{"заказ-1", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, tt.header)
+		ctx := NewHandleContext(nil, "/", nil).(*HandleContext)
+		ctx.Init(nil, "/", "GET", httptest.NewRecorder(), req)
+
+		id := ctx.RequestID()
+		if tt.keep && id != tt.header {
+			t.Errorf("%q: expected the client's ID to be kept, got %q", tt.header, id)
+		}
+		if !tt.keep && (id == tt.header || len(id) != 16) {
+			t.Errorf("%q: expected a generated ID, got %q", tt.header, id)
+		}
+	}
+}
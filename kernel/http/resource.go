@@ -156,19 +156,22 @@ func (r *Resource) RequestForm() kernel.IForm {
 	return r.requestForm
 }
 
-// Log writes an informational message under category, prefixed with the resource's route.
+// Log writes an informational message under category, prefixed with the resource's route,
+// through the request's logger - see HandleContext.Logger.
 func (r *Resource) Log(msg string, category string) {
-	r.App().Log(fmt.Sprintf("Message from '%s' handling: %s", r.Route(), msg), category)
+	r.Context().Logger().WithCategory(category).Info(fmt.Sprintf("Message from '%s' handling: %s", r.Route(), msg))
 }
 
-// LogWarning writes a warning message under category, prefixed with the resource's route.
+// LogWarning writes a warning message under category, prefixed with the resource's route,
+// through the request's logger - see HandleContext.Logger.
 func (r *Resource) LogWarning(msg string, category string) {
-	r.App().Log(fmt.Sprintf("Warning from '%s' handling: %s", r.Route(), msg), category)
+	r.Context().Logger().WithCategory(category).Warning(fmt.Sprintf("Warning from '%s' handling: %s", r.Route(), msg))
 }
 
-// LogError writes an error message under category, prefixed with the resource's route.
+// LogError writes an error message under category, prefixed with the resource's route,
+// through the request's logger - see HandleContext.Logger.
 func (r *Resource) LogError(msg string, category string) {
-	r.App().Log(fmt.Sprintf("Error occurred while '%s' handling: %s", r.Route(), msg), category)
+	r.Context().Logger().WithCategory(category).Error(fmt.Sprintf("Error occurred while '%s' handling: %s", r.Route(), msg))
 }
 
 // HtmlResponse builds an HTML response - see the package-level HtmlResponse.
@@ -195,11 +198,31 @@ func (r *Resource) FailResponse(conf kernel.JsonResponseConfig) kernel.IHttpResp
 	return jsonResponse(r, conf, r.cFailForm)
 }
 
+// StreamResponse builds a response streaming conf.Body, or what conf.Write
+// writes - see NewStreamResponse.
+func (r *Resource) StreamResponse(conf kernel.StreamResponseConfig) kernel.IHttpResponse {
+	return NewStreamResponse(r.Context(), conf)
+}
+
+// FileResponse builds a file download response, supporting Range requests
+// - see NewFileResponse. A file that can't be opened gets a 404.
+func (r *Resource) FileResponse(conf kernel.FileResponseConfig) kernel.IHttpResponse {
+	resp, err := NewFileResponse(r.Context(), conf)
+	if err != nil {
+		r.LogError(err.Error(), "HttpHandling")
+		return ErrorResponse(http.StatusNotFound, "Not Found")
+	}
+	return resp
+}
+
+// SSEResponse builds a Server-Sent Events response - see NewSSEResponse.
+func (r *Resource) SSEResponse(conf kernel.SSEResponseConfig) kernel.IHttpResponse {
+	return NewSSEResponse(r.Context(), conf)
+}
+
 // ErrorResponse builds a JSON error response with the given HTTP code and message.
 func (r *Resource) ErrorResponse(code int, msg string) kernel.IHttpResponse {
-	response := new(Response)
-	response.SetError(code, msg)
-	return response
+	return ErrorResponse(code, msg)
 }
 
 // Redirect builds an HTTP redirect response to URL with params appended as query string.
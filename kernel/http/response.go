@@ -2,7 +2,10 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"net/http"
+	"strings"
 
 	"github.com/epicoon/lxgo/kernel"
 )
@@ -19,10 +22,13 @@ const (
 // one of SetError/SetHtmlData/SetJsonData, usually through
 // kernel.IHttpResource's response-building methods rather than directly.
 type Response struct {
-	code     int
-	headers  map[string]string
-	dataType string
-	data     string
+	code        int
+	headers     map[string]string
+	dataType    string
+	data        string
+	value       any
+	contentType string
+	negotiated  bool
 }
 
 var _ kernel.IHttpResponse = (*Response)(nil)
@@ -34,6 +40,9 @@ func (r *Response) SetCode(code int) {
 
 // AddHeader adds a response header.
 func (r *Response) AddHeader(key, val string) {
+	if r.headers == nil {
+		r.headers = make(map[string]string)
+	}
 	r.headers[key] = val
 }
 
@@ -50,7 +59,9 @@ func (r *Response) SetHtmlData(data string) {
 	r.dataType = typeHtml
 }
 
-// SetJsonData sets the response body by marshaling data to JSON.
+// SetJsonData sets the response body by marshaling data to JSON - the
+// router re-serializes data to the format the request's Accept header
+// prefers, if it isn't JSON (see Router.RegisterSerializer).
 func (r *Response) SetJsonData(data any) error {
 	jsonBody, err := json.Marshal(data)
 	if err != nil {
@@ -59,9 +70,17 @@ func (r *Response) SetJsonData(data any) error {
 
 	r.data = string(jsonBody)
 	r.dataType = typeJson
+	r.value = data
+	r.contentType = ContentTypeJson
 	return nil
 }
 
+// ContentType returns the body's content type, "" for an error or HTML
+// response.
+func (r *Response) ContentType() string {
+	return r.contentType
+}
+
 // Code returns the HTTP status code, defaulting to 200 OK if none was set.
 func (r *Response) Code() int {
 	if r.code == 0 {
@@ -87,18 +106,154 @@ func (r *Response) Send(w http.ResponseWriter) {
 		return
 	}
 
-	for key, val := range r.headers {
-		w.Header().Set(key, val)
-	}
+	r.writeHeaders(w)
 
 	switch r.dataType {
 	case typeHtml:
 		w.Header().Set("Content-Type", "text/html")
 	case typeJson:
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Type", r.contentType)
 	}
 
 	w.WriteHeader(r.Code())
 
 	w.Write([]byte(r.data))
 }
+
+// ResponseSnapshot is a successful JSON or HTML response as sent for a
+// request, in a form that can be stored (e.g. JSON-encoded in a cache) -
+// see SnapshotResponse.
+type ResponseSnapshot struct {
+	Code        int
+	Headers     map[string]string
+	Html        bool
+	ContentType string
+	Body        string
+}
+
+// SnapshotResponse negotiates resp for ctx's request (see
+// Router.RegisterSerializer) and returns its snapshot - ok is false unless
+// it's a *Response with a 200 JSON or HTML body.
+func SnapshotResponse(ctx kernel.IHandleContext, resp kernel.IHttpResponse) (snapshot ResponseSnapshot, ok bool) {
+	r, isResponse := resp.(*Response)
+	if !isResponse || r.Code() != http.StatusOK || (r.dataType != typeJson && r.dataType != typeHtml) {
+		return snapshot, false
+	}
+	if !r.negotiated {
+		r.negotiate(contextSerializers(ctx), ctx.Request().Header.Get("Accept"))
+		r.negotiated = true
+	}
+
+	headers := make(map[string]string, len(r.headers))
+	for key, val := range r.headers {
+		headers[key] = val
+	}
+	return ResponseSnapshot{
+		Code:        r.Code(),
+		Headers:     headers,
+		Html:        r.dataType == typeHtml,
+		ContentType: r.contentType,
+		Body:        r.data,
+	}, true
+}
+
+// Response rebuilds the response s was taken from - already negotiated,
+// it's sent as is.
+func (s ResponseSnapshot) Response() kernel.IHttpResponse {
+	r := &Response{
+		code:        s.Code,
+		data:        s.Body,
+		dataType:    typeJson,
+		contentType: s.ContentType,
+		negotiated:  true,
+	}
+	if s.Html {
+		r.dataType = typeHtml
+	}
+	for key, val := range s.Headers {
+		r.AddHeader(key, val)
+	}
+	return r
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// negotiate re-serializes a data response with the first of serializers the
+// Accept header prefers that can serialize it - it stays JSON if none can.
+func (r *Response) negotiate(serializers []kernel.ISerializer, accept string) {
+	if r.dataType != typeJson || r.negotiated {
+		return
+	}
+	r.AddHeader("Vary", "Accept")
+
+	for _, s := range NegotiateSerializers(serializers, accept) {
+		if _, ok := s.(*JsonSerializer); ok {
+			// Already marshaled the same way.
+			return
+		}
+		data, err := s.Serialize(r.value)
+		if err != nil {
+			continue
+		}
+		r.data = string(data)
+		r.contentType = s.ContentType()
+		return
+	}
+}
+
+// conditional sets the ETag header of a successful data or HTML response
+// to a GET/HEAD request (unless the resource set its own), reporting
+// whether the request's If-None-Match already has it - see sendNotModified.
+func (r *Response) conditional(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if (r.dataType != typeJson && r.dataType != typeHtml) || r.Code() != http.StatusOK {
+		return false
+	}
+
+	etag, ok := r.headers["ETag"]
+	if !ok {
+		h := fnv.New64a()
+		h.Write([]byte(r.contentType))
+		h.Write([]byte(r.data))
+		etag = fmt.Sprintf(`W/"%x"`, h.Sum64())
+		r.AddHeader("ETag", etag)
+	}
+	return etagMatches(req.Header.Get("If-None-Match"), etag)
+}
+
+// sendNotModified writes a 304 response with the response's headers.
+func (r *Response) sendNotModified(w http.ResponseWriter) {
+	r.writeHeaders(w)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// writeHeaders sets r's headers on w - Vary adds to what's already there
+// (e.g. the CORS "Origin").
+func (r *Response) writeHeaders(w http.ResponseWriter) {
+	for key, val := range r.headers {
+		if http.CanonicalHeaderKey(key) == "Vary" {
+			w.Header().Add(key, val)
+		} else {
+			w.Header().Set(key, val)
+		}
+	}
+}
+
+// etagMatches weakly compares etag with an If-None-Match header value.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, item := range strings.Split(ifNoneMatch, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.TrimPrefix(item, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
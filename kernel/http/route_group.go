@@ -0,0 +1,105 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+/** @interface kernel.IRouteGroup */
+
+// RouteGroup is the default kernel.IRouteGroup implementation - see
+// Router.Group.
+type RouteGroup struct {
+	router     *Router
+	parent     *RouteGroup
+	prefix     string
+	middleware []kernel.FWrapMiddleware
+}
+
+var _ kernel.IRouteGroup = (*RouteGroup)(nil)
+
+/** @constructor */
+
+func newRouteGroup(router *Router, parent *RouteGroup, prefix string, mw []kernel.FMiddleware) *RouteGroup {
+	if parent != nil {
+		prefix = joinRoute(parent.prefix, prefix)
+	} else {
+		prefix = joinRoute("", prefix)
+	}
+	g := &RouteGroup{
+		router: router,
+		parent: parent,
+		prefix: prefix,
+	}
+	for _, m := range mw {
+		g.AddMiddleware(m)
+	}
+	return g
+}
+
+// Prefix returns the group's full path prefix, its parents' included.
+func (g *RouteGroup) Prefix() string {
+	return g.prefix
+}
+
+// AddMiddleware registers a middleware run only for the group's routes
+// (nested groups included) - routes registered before the call are
+// affected too.
+func (g *RouteGroup) AddMiddleware(mw kernel.FMiddleware) {
+	g.AddWrapMiddleware(beforeHook(mw))
+}
+
+// AddWrapMiddleware registers a middleware wrapping the handling of the
+// group's routes (nested groups included) - see Router.AddWrapMiddleware.
+func (g *RouteGroup) AddWrapMiddleware(mw kernel.FWrapMiddleware) {
+	g.middleware = append(g.middleware, mw)
+}
+
+// Group starts a nested group under prefix, inside this group's own prefix
+// - its middleware runs after this group's.
+func (g *RouteGroup) Group(prefix string, mw ...kernel.FMiddleware) kernel.IRouteGroup {
+	return newRouteGroup(g.router, g, prefix, mw)
+}
+
+// RegisterResources registers a batch of routes under the group's prefix -
+// each key may embed its method as "path[METHOD]", same as
+// Router.RegisterResources.
+func (g *RouteGroup) RegisterResources(routes kernel.HttpResourcesList) {
+	for route, cResource := range routes {
+		path, method := parseRoute(route)
+		g.RegisterResource(path, method, cResource)
+	}
+}
+
+// RegisterResource registers a single route/method under the group's
+// prefix - mw, if given, runs for this route/method only, after the
+// group's middleware.
+func (g *RouteGroup) RegisterResource(route string, method string, cResource kernel.CHttpResource, mw ...kernel.FMiddleware) {
+	g.router.registerResource(joinRoute(g.prefix, route), method, cResource, g, mw)
+}
+
+// chain returns the middleware of g and all of its parents, outermost first.
+func (g *RouteGroup) chain() []kernel.FWrapMiddleware {
+	var chain []kernel.FWrapMiddleware
+	if g.parent != nil {
+		chain = g.parent.chain()
+	}
+	return append(chain, g.middleware...)
+}
+
+// joinRoute appends route to prefix, normalizing the slashes between them -
+// an empty or "/" route maps to the prefix itself.
+func joinRoute(prefix, route string) string {
+	prefix = strings.Trim(prefix, "/")
+	route = strings.Trim(route, "/")
+	switch {
+	case prefix == "" && route == "":
+		return "/"
+	case prefix == "":
+		return "/" + route
+	case route == "":
+		return "/" + prefix
+	}
+	return "/" + prefix + "/" + route
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+func TestJoinRoute(t *testing.T) {
+	cases := []struct{ prefix, route, want string }{
+		{"", "", "/"},
+		{"/", "/", "/"},
+		{"/admin", "", "/admin"},
+		{"/admin", "/", "/admin"},
+		{"admin/", "users", "/admin/users"},
+		{"/admin", "/users/{id:int}", "/admin/users/{id:int}"},
+	}
+	for _, tc := range cases {
+		if got := joinRoute(tc.prefix, tc.route); got != tc.want {
+			t.Errorf("joinRoute(%q, %q) = %q, want %q", tc.prefix, tc.route, got, tc.want)
+		}
+	}
+}
+
+func TestRouter_Group_ScopedMiddleware(t *testing.T) {
+	router := NewRouter(nil)
+	var calls []string
+	record := func(name string) kernel.FMiddleware {
+		return func(kernel.IHandleContext) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	router.AddMiddleware(record("global"))
+	router.RegisterResource("/public", "GET", func() kernel.IHttpResource { return NewResource() })
+
+	admin := router.Group("/admin", record("admin"))
+	admin.RegisterResources(kernel.HttpResourcesList{
+		"/users[GET]": func() kernel.IHttpResource { return NewResource() },
+	})
+	admin.Group("/reports", record("reports")).
+		RegisterResource("/{id:int}", "GET", func() kernel.IHttpResource { return NewResource() }, record("route"))
+	// Added after the routes were registered - must still apply to them.
+	admin.AddMiddleware(record("admin-late"))
+
+	cases := []struct {
+		path string
+		want []string
+	}{
+		{"/public", []string{"global"}},
+		{"/admin/users", []string{"global", "admin", "admin-late"}},
+		{"/admin/reports/7", []string{"global", "admin", "admin-late", "reports", "route"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			calls = nil
+			rec := httptest.NewRecorder()
+			router.(*Router).ServeHTTP(rec, httptest.NewRequest("GET", tc.path, nil))
+			if rec.Code != 200 {
+				t.Fatalf("expected 200, got %d", rec.Code)
+			}
+			if !reflect.DeepEqual(calls, tc.want) {
+				t.Fatalf("got middleware calls %v, want %v", calls, tc.want)
+			}
+		})
+	}
+
+	if _, ok := router.Resources()["/admin/reports/{id:int}"]; !ok {
+		t.Fatal("expected the group's route to be listed in Resources() under its full path")
+	}
+}
+
+func TestRouter_RegisterResource_RouteMiddlewareFallsBackToAnyMethod(t *testing.T) {
+	router := NewRouter(nil)
+	var called bool
+	router.RegisterResource("/any", "", func() kernel.IHttpResource { return NewResource() }, func(kernel.IHandleContext) error {
+		called = true
+		return nil
+	})
+
+	router.(*Router).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PUT", "/any", nil))
+	if !called {
+		t.Fatal("expected the any-method route's middleware to run")
+	}
+}
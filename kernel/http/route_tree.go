@@ -0,0 +1,196 @@
+package http
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// routeParamTypes maps the type names allowed in a "{name:type}" route
+// segment to the check a captured value must pass - a segment with no type
+// ("{name}") accepts any non-empty value.
+var routeParamTypes = map[string]func(string) bool{
+	"int":   regexp.MustCompile(`^-?\d+$`).MatchString,
+	"uint":  regexp.MustCompile(`^\d+$`).MatchString,
+	"alpha": regexp.MustCompile(`^[a-zA-Z]+$`).MatchString,
+	"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+}
+
+// isPatternRoute reports whether route has any "{...}" segments - plain
+// routes never enter the tree, they're resolved by an exact map lookup.
+func isPatternRoute(route string) bool {
+	return strings.Contains(route, "{")
+}
+
+// routeNode is one path segment of a routeTree. Children are tried in
+// priority order - static segments first, then typed params, then untyped
+// params, then a trailing wildcard - backtracking when a branch dead-ends.
+type routeNode struct {
+	static   map[string]*routeNode
+	params   []*routeNode
+	wildcard *routeNode
+
+	// paramName/paramCheck describe a param node ("{id:int}" -> "id",
+	// routeParamTypes["int"]); paramCheck is nil for an untyped param.
+	paramName  string
+	paramType  string
+	paramCheck func(string) bool
+
+	// route is the registered pattern ending at this node, "" if none does.
+	route string
+}
+
+// routeTree matches request paths against the registered pattern routes -
+// e.g. "/users/{id:int}/posts/{slug}" or "/files/{path...}".
+type routeTree struct {
+	root *routeNode
+}
+
+func newRouteTree() *routeTree {
+	return &routeTree{root: &routeNode{}}
+}
+
+// insert adds pattern to the tree, failing on a malformed segment (unknown
+// param type, empty param name, a wildcard that isn't the last segment) or
+// a pattern that collides with one already registered under another name.
+func (t *routeTree) insert(pattern string) error {
+	segments := splitRoute(pattern)
+	node := t.root
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+			if strings.ContainsAny(seg, "{}") {
+				return fmt.Errorf("route '%s': malformed segment '%s'", pattern, seg)
+			}
+			if node.static == nil {
+				node.static = make(map[string]*routeNode)
+			}
+			child, exists := node.static[seg]
+			if !exists {
+				child = &routeNode{}
+				node.static[seg] = child
+			}
+			node = child
+			continue
+		}
+
+		inner := seg[1 : len(seg)-1]
+		if name, ok := strings.CutSuffix(inner, "..."); ok {
+			if i != len(segments)-1 {
+				return fmt.Errorf("route '%s': wildcard '%s' must be the last segment", pattern, seg)
+			}
+			if name == "" {
+				return fmt.Errorf("route '%s': wildcard has no name", pattern)
+			}
+			if node.wildcard == nil {
+				node.wildcard = &routeNode{paramName: name}
+			} else if node.wildcard.paramName != name {
+				return fmt.Errorf("route '%s': wildcard '%s' conflicts with '%s'", pattern, name, node.wildcard.paramName)
+			}
+			node = node.wildcard
+			continue
+		}
+
+		name, tp, _ := strings.Cut(inner, ":")
+		if name == "" {
+			return fmt.Errorf("route '%s': param has no name", pattern)
+		}
+		var check func(string) bool
+		if tp != "" {
+			c, known := routeParamTypes[tp]
+			if !known {
+				return fmt.Errorf("route '%s': unknown param type '%s'", pattern, tp)
+			}
+			check = c
+		}
+
+		var child *routeNode
+		for _, p := range node.params {
+			if p.paramType == tp {
+				if p.paramName != name {
+					return fmt.Errorf("route '%s': param '%s' conflicts with '%s'", pattern, name, p.paramName)
+				}
+				child = p
+				break
+			}
+		}
+		if child == nil {
+			child = &routeNode{paramName: name, paramType: tp, paramCheck: check}
+			// Typed params are stricter, so they're tried before an untyped
+			// one at the same position.
+			if check != nil {
+				node.params = append([]*routeNode{child}, node.params...)
+			} else {
+				node.params = append(node.params, child)
+			}
+		}
+		node = child
+	}
+
+	if node.route != "" && node.route != pattern {
+		return fmt.Errorf("route '%s' conflicts with already registered '%s'", pattern, node.route)
+	}
+	node.route = pattern
+	return nil
+}
+
+// match returns the pattern matching path and the values it captured, or
+// "" if no registered pattern matches.
+func (t *routeTree) match(path string) (string, map[string]string) {
+	params := make(map[string]string)
+	route := t.root.match(splitRoute(path), params)
+	if route == "" {
+		return "", nil
+	}
+	return route, params
+}
+
+func (n *routeNode) match(segments []string, params map[string]string) string {
+	if len(segments) == 0 {
+		if n.route != "" {
+			return n.route
+		}
+		// "/files/{path...}" also matches "/files" itself, with an empty path.
+		if n.wildcard != nil && n.wildcard.route != "" {
+			params[n.wildcard.paramName] = ""
+			return n.wildcard.route
+		}
+		return ""
+	}
+
+	seg, rest := segments[0], segments[1:]
+
+	if child, ok := n.static[seg]; ok {
+		if route := child.match(rest, params); route != "" {
+			return route
+		}
+	}
+
+	if seg != "" {
+		for _, child := range n.params {
+			if child.paramCheck != nil && !child.paramCheck(seg) {
+				continue
+			}
+			if route := child.match(rest, params); route != "" {
+				params[child.paramName] = seg
+				return route
+			}
+		}
+	}
+
+	if n.wildcard != nil && n.wildcard.route != "" {
+		params[n.wildcard.paramName] = strings.Join(segments, "/")
+		return n.wildcard.route
+	}
+
+	return ""
+}
+
+// splitRoute splits route into its path segments, ignoring the leading
+// slash - "/" and "" both have no segments at all.
+func splitRoute(route string) []string {
+	route = strings.TrimPrefix(route, "/")
+	if route == "" {
+		return nil
+	}
+	return strings.Split(route, "/")
+}
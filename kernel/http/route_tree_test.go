@@ -0,0 +1,187 @@
+package http
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+func TestRouteTree_Match(t *testing.T) {
+	tree := newRouteTree()
+	for _, p := range []string{
+		"/users/{id:int}",
+		"/users/{name}",
+		"/users/me",
+		"/users/{id:int}/posts/{slug}",
+		"/files/{path...}",
+	} {
+		if err := tree.insert(p); err != nil {
+			t.Fatalf("insert %q: %v", p, err)
+		}
+	}
+
+	cases := []struct {
+		path   string
+		route  string
+		params map[string]string
+	}{
+		{"/users/42", "/users/{id:int}", map[string]string{"id": "42"}},
+		{"/users/bob", "/users/{name}", map[string]string{"name": "bob"}},
+		{"/users/me", "/users/me", map[string]string{}},
+		{"/users/42/posts/hello-world", "/users/{id:int}/posts/{slug}", map[string]string{"id": "42", "slug": "hello-world"}},
+		{"/files/css/main.css", "/files/{path...}", map[string]string{"path": "css/main.css"}},
+		{"/files", "/files/{path...}", map[string]string{"path": ""}},
+		{"/users/bob/posts/x", "", nil},
+		{"/unknown", "", nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			route, params := tree.match(tc.path)
+			if route != tc.route {
+				t.Fatalf("got route %q, want %q", route, tc.route)
+			}
+			if tc.params != nil && !reflect.DeepEqual(params, tc.params) {
+				t.Fatalf("got params %v, want %v", params, tc.params)
+			}
+		})
+	}
+}
+
+func TestRouteTree_Insert_Errors(t *testing.T) {
+	cases := []string{
+		"/users/{id:float}",
+		"/users/{}",
+		"/files/{path...}/tail",
+		"/users/{id",
+	}
+	for _, p := range cases {
+		t.Run(p, func(t *testing.T) {
+			if err := newRouteTree().insert(p); err == nil {
+				t.Fatalf("expected an error for %q", p)
+			}
+		})
+	}
+
+	t.Run("conflicting_param_names", func(t *testing.T) {
+		tree := newRouteTree()
+		if err := tree.insert("/users/{id}"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := tree.insert("/users/{name}/posts"); err == nil {
+			t.Fatal("expected an error for a param name conflicting at the same position")
+		}
+	})
+}
+
+type pathParamsForm struct {
+	*Form
+	ID   int    `json:"id"`
+	Slug string `json:"slug"`
+	Page int    `json:"page"`
+}
+
+type pathParamsResource struct {
+	*Resource
+	got *pathParamsResource
+}
+
+func (r *pathParamsResource) Run() kernel.IHttpResponse {
+	*r.got = *r
+	return r.JsonResponse(kernel.JsonResponseConfig{Data: r.Context().PathParams()})
+}
+
+func TestRouter_ServeHTTP_PatternRoute(t *testing.T) {
+	router := NewRouter(nil)
+	got := &pathParamsResource{}
+	router.RegisterResource("/users/{id:int}/posts/{slug}", "GET", func() kernel.IHttpResource {
+		return &pathParamsResource{
+			Resource: NewResource(kernel.HttpResourceConfig{
+				CRequestForm: func() kernel.IForm { return &pathParamsForm{Form: NewForm()} },
+			}),
+			got: got,
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	router.(*Router).ServeHTTP(rec, httptest.NewRequest("GET", "/users/42/posts/hello?page=3", nil))
+
+	if rec.Code != 200 {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got.Route() != "/users/{id:int}/posts/{slug}" {
+		t.Fatalf("expected the pattern as the context's route, got %q", got.Route())
+	}
+	if got.Context().PathParam("slug") != "hello" {
+		t.Fatalf("got slug %q, want 'hello'", got.Context().PathParam("slug"))
+	}
+	form := got.RequestForm().(*pathParamsForm)
+	if form.ID != 42 || form.Slug != "hello" || form.Page != 3 {
+		t.Fatalf("form not filled from path/query params: %+v", form)
+	}
+
+	rec = httptest.NewRecorder()
+	router.(*Router).ServeHTTP(rec, httptest.NewRequest("GET", "/users/abc/posts/hello", nil))
+	if rec.Code != 404 {
+		t.Fatalf("expected 404 for a non-int id, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.(*Router).ServeHTTP(rec, httptest.NewRequest("POST", "/users/42/posts/hello", nil))
+	if rec.Code != 405 {
+		t.Fatalf("expected 405 for an unregistered method, got %d", rec.Code)
+	}
+}
+
+func TestRouter_ServeHTTP_ExactRouteWinsOverPattern(t *testing.T) {
+	router := NewRouter(nil)
+	var hit string
+	router.RegisterResource("/users/{id}", "GET", func() kernel.IHttpResource {
+		hit = "pattern"
+		return NewResource()
+	})
+	router.RegisterResource("/users/me", "GET", func() kernel.IHttpResource {
+		hit = "exact"
+		return NewResource()
+	})
+
+	router.(*Router).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/me", nil))
+	if hit != "exact" {
+		t.Fatalf("expected the exact route to win, got %q", hit)
+	}
+
+	// A literal request for the pattern string itself must not hit the
+	// pattern's resources map entry directly.
+	hit = ""
+	router.(*Router).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/%7Bid%7D", nil))
+	if hit != "pattern" {
+		t.Fatalf("expected the literal path to go through the pattern matcher, got %q", hit)
+	}
+}
+
+func TestRouter_ServeHTTP_ExactRouteFallsBackToPattern(t *testing.T) {
+	router := NewRouter(nil)
+	var hit string
+	router.RegisterResource("/users/me", "GET", func() kernel.IHttpResource {
+		hit = "exact"
+		return NewResource()
+	})
+	router.RegisterResource("/users/{id}", "DELETE", func() kernel.IHttpResource {
+		hit = "pattern"
+		return NewResource()
+	})
+
+	rec := httptest.NewRecorder()
+	router.(*Router).ServeHTTP(rec, httptest.NewRequest("DELETE", "/users/me", nil))
+	if rec.Code == 405 || hit != "pattern" {
+		t.Fatalf("expected the pattern route to take the method, got %d, %q", rec.Code, hit)
+	}
+
+	rec = httptest.NewRecorder()
+	router.(*Router).ServeHTTP(rec, httptest.NewRequest("POST", "/users/me", nil))
+	if rec.Code != 405 {
+		t.Fatalf("expected 405 for a method neither route takes, got %d", rec.Code)
+	}
+}
@@ -7,18 +7,37 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/epicoon/lxgo/kernel"
 )
 
 /** @interface kernel.IRouter */
 
-// Router is the default kernel.IRouter implementation - also implements
-// http.Handler, so it can be registered directly with net/http (see Start).
+// Router is the default kernel.IRouter implementation - an http.Handler
+// with its own mux, so several routers can serve in one process and any
+// server can mount one.
 type Router struct {
-	app        kernel.IApp
-	resources  map[string]kernel.HttpResourcesList
-	assetsMap  map[string]string
+	app         kernel.IApp
+	mux         *http.ServeMux
+	resources   map[string]kernel.HttpResourcesList
+	patterns    *routeTree
+	bindings    map[string]map[string]*routeBinding
+	assetsMap   map[string]string
+	middleware  []kernel.FWrapMiddleware
+	serializers []kernel.ISerializer
+	compression *kernel.CompressionConfig
+	assetsCache map[string]string
+	cors        *kernel.CorsConfig
+	security    *kernel.SecurityHeadersConfig
+}
+
+// routeBinding is what a single route/method was registered with besides
+// its resource - the group it belongs to (nil for a top-level route) and
+// its own middleware. The group is kept by reference, so middleware added
+// to it after the route was registered still applies.
+type routeBinding struct {
+	group      *RouteGroup
 	middleware []kernel.FMiddleware
 }
 
@@ -29,19 +48,35 @@ var _ kernel.IRouter = (*Router)(nil)
 // NewRouter constructs an empty Router bound to app (may be nil for
 // standalone use outside a kernel.IApp).
 func NewRouter(app kernel.IApp) kernel.IRouter {
-	return &Router{
+	router := &Router{
 		app:       app,
 		resources: make(map[string]kernel.HttpResourcesList),
+		patterns:  newRouteTree(),
 		assetsMap: make(map[string]string),
+		mux:       http.NewServeMux(),
+	}
+	router.mux.HandleFunc("/", router.serveResource)
+	for _, c := range DefaultSerializers {
+		router.RegisterSerializer(c)
 	}
+	return router
 }
 
-// AddMiddleware registers a middleware, run before every request.
+// AddMiddleware registers a middleware, run before every request - see
+// Group and RegisterResource for middleware scoped to some routes only.
 func (router *Router) AddMiddleware(mh kernel.FMiddleware) {
+	router.AddWrapMiddleware(beforeHook(mh))
+}
+
+// AddWrapMiddleware registers a middleware wrapping every request's
+// handling - it runs in registration order along with the ones added via
+// AddMiddleware, and can short-circuit with its own response or
+// post-process the one returned by next.
+func (router *Router) AddWrapMiddleware(mw kernel.FWrapMiddleware) {
 	if router.middleware == nil {
-		router.middleware = make([]kernel.FMiddleware, 0, 1)
+		router.middleware = make([]kernel.FWrapMiddleware, 0, 1)
 	}
-	router.middleware = append(router.middleware, mh)
+	router.middleware = append(router.middleware, mw)
 }
 
 // Resources returns all registered resources, keyed by route then HTTP method.
@@ -52,21 +87,13 @@ func (router *Router) Resources() map[string]kernel.HttpResourcesList {
 // RegisterTemplates registers named templates, each served via GET at its
 // own route with the template/params made available through the request context.
 func (router *Router) RegisterTemplates(tpls kernel.HttpTemplatesList) {
-	for url := range tpls {
-		router.RegisterResource(url, "GET", newStdHandler)
-	}
-
-	router.AddMiddleware(func(ctx kernel.IHandleContext) error {
-		r := ctx.Route()
-		options, exists := tpls[r]
-		if !exists {
+	for url, options := range tpls {
+		router.RegisterResource(url, "GET", newStdHandler, func(ctx kernel.IHandleContext) error {
+			ctx.Set("Template", options.Template)
+			ctx.Set("Params", options.Params)
 			return nil
-		}
-
-		ctx.Set("Template", options.Template)
-		ctx.Set("Params", options.Params)
-		return nil
-	})
+		})
+	}
 }
 
 // RegisterResources registers a batch of routes - each key may embed its
@@ -79,29 +106,40 @@ func (router *Router) RegisterResources(routes kernel.HttpResourcesList) {
 }
 
 // RegisterResource registers a single route/method - an empty method
-// matches any HTTP method not otherwise registered for the route.
-func (router *Router) RegisterResource(route string, method string, cResource kernel.CHttpResource) {
-	method = strings.ToUpper(method)
-
-	_, exists := router.resources[route]
-	if !exists {
-		router.resources[route] = make(kernel.HttpResourcesList)
-	}
-
-	if method == "" {
-		method = "ALL"
-	}
+// matches any HTTP method not otherwise registered for the route. route may
+// be a pattern: "{name}" captures one path segment, "{name:type}" captures
+// one only if it fits type (int, uint, alpha, uuid), and a trailing
+// "{name...}" captures the rest of the path - e.g.
+// "/users/{id:int}/posts/{slug}". Captured values are available through
+// IHandleContext.PathParams and fill the request form like query params do.
+// mw, if given, runs for this route/method only, after every router-wide
+// middleware.
+func (router *Router) RegisterResource(route string, method string, cResource kernel.CHttpResource, mw ...kernel.FMiddleware) {
+	router.registerResource(route, method, cResource, nil, mw)
+}
 
-	router.resources[route][method] = cResource
+// Group starts a group of routes registered under prefix - mw, and any
+// middleware added to the group later, runs only for the group's own routes
+// (nested groups included), after every router-wide middleware.
+func (router *Router) Group(prefix string, mw ...kernel.FMiddleware) kernel.IRouteGroup {
+	return newRouteGroup(router, nil, prefix, mw)
 }
 
 // RegisterFileAssets registers static file routes: each key is a URL
 // prefix, each value the directory it's served from (resolved via the
-// app's pathfinder, if any).
+// app's pathfinder, if any). Assets are compressed like any other
+// response (see SetCompression) and get the Cache-Control header set for
+// their prefix (see SetAssetsCacheControl).
 func (router *Router) RegisterFileAssets(assets map[string]string) {
 	maps.Copy(router.assetsMap, assets)
 	for urlPrefix, dir := range assets {
-		http.Handle(urlPrefix, http.StripPrefix(urlPrefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.mux.Handle(urlPrefix, http.StripPrefix(urlPrefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w = newCompressWriter(w, r, router.compression)
+			defer closeCompressWriter(w)
+			if cacheControl, ok := router.assetsCache[urlPrefix]; ok {
+				w.Header().Set("Cache-Control", cacheControl)
+			}
+
 			var filePath string
 			if router.app == nil {
 				filePath = filepath.Join(dir, r.URL.Path)
@@ -117,30 +155,79 @@ func (router *Router) RegisterFileAssets(assets map[string]string) {
 	}
 }
 
+// SetCompression enables gzip/deflate compression of responses (assets
+// included) for clients accepting it, per conf.
+func (router *Router) SetCompression(conf kernel.CompressionConfig) {
+	router.compression = &conf
+}
+
+// SetAssetsCacheControl sets the Cache-Control header of assets, per URL
+// prefix as registered via RegisterFileAssets, e.g.
+// {"/js/": "public, max-age=86400"}.
+func (router *Router) SetAssetsCacheControl(rules map[string]string) {
+	if router.assetsCache == nil {
+		router.assetsCache = make(map[string]string, len(rules))
+	}
+	maps.Copy(router.assetsCache, rules)
+}
+
+// SetCors enables CORS per conf: cross-origin requests from an allowed
+// origin get the Access-Control-* headers, and preflights (OPTIONS
+// requests with Access-Control-Request-Method) to a registered route or
+// asset are answered with a 204 - unless the route has its own OPTIONS
+// resource. A preflight from an origin not allowed gets a 403. A config
+// CheckCorsConfig rejects is logged and leaves CORS disabled.
+func (router *Router) SetCors(conf kernel.CorsConfig) {
+	if err := CheckCorsConfig(conf); err != nil {
+		router.logError(fmt.Sprintf("can not enable CORS: %s", err))
+		router.cors = nil
+		return
+	}
+	router.cors = &conf
+}
+
+// SetSecurityHeaders sets conf's headers on every response, assets
+// included - a resource can still override them with its own response
+// headers.
+func (router *Router) SetSecurityHeaders(conf kernel.SecurityHeadersConfig) {
+	router.security = &conf
+}
+
 // RegisterProxy registers conf.Routes/conf.Map's routes to be proxied
 // through to conf.Server.
 func (router *Router) RegisterProxy(conf kernel.HttpProxyConfig) {
 	for _, path := range conf.Routes {
-		router.RegisterResource(path, "", newProxyHandler)
-	}
-	for path := range conf.Map {
-		router.RegisterResource(path, "", newProxyHandler)
-	}
-
-	router.AddMiddleware(func(ctx kernel.IHandleContext) error {
-		r := ctx.Route()
-		if slices.Contains(conf.Routes, r) {
+		router.RegisterResource(path, "", newProxyHandler, func(ctx kernel.IHandleContext) error {
 			ctx.Set("Server", conf.Server)
 			return nil
-		}
-		path, exists := conf.Map[r]
-		if exists {
+		})
+	}
+	for path, target := range conf.Map {
+		router.RegisterResource(path, "", newProxyHandler, func(ctx kernel.IHandleContext) error {
 			ctx.Set("Server", conf.Server)
-			ctx.Set("Path", path)
+			ctx.Set("Path", target)
 			return nil
+		})
+	}
+}
+
+// RegisterSerializer registers the serializer c constructs, replacing the
+// one of the same content type - see DefaultSerializers for the ones a
+// Router starts with.
+func (router *Router) RegisterSerializer(c kernel.CSerializer) {
+	s := c()
+	for i, registered := range router.serializers {
+		if registered.ContentType() == s.ContentType() {
+			router.serializers[i] = s
+			return
 		}
-		return nil
-	})
+	}
+	router.serializers = append(router.serializers, s)
+}
+
+// Serializers returns the registered serializers, the default one first.
+func (router *Router) Serializers() []kernel.ISerializer {
+	return router.serializers
 }
 
 // GetAssetRoute returns the URL prefix registered for the directory path,
@@ -158,37 +245,54 @@ func (router *Router) GetAssetRoute(path string) string {
 // EVENT_APP_BEFORE_HANDLE_REQUEST, and runs it through the router's
 // middleware/form-filling pipeline.
 func (router *Router) Handle(res kernel.IHttpResource, route string, w http.ResponseWriter, r *http.Request) kernel.IHttpResponse {
-	res.Context().Init(
-		router.app,
-		route,
-		r.Method,
-		w,
-		r,
-	)
+	return router.handle(res, route, nil, w, r)
+}
 
-	if router.app != nil {
-		router.app.Events().Trigger(kernel.EVENT_APP_BEFORE_HANDLE_REQUEST, kernel.Dict{
-			"context": res.Context(),
-		})
+// ServeHTTP implements http.Handler: sets the security headers and answers
+// CORS preflights (see SetSecurityHeaders/SetCors), serves an asset if the
+// request's path is under a prefix registered via RegisterFileAssets,
+// otherwise resolves
+// the matching resource, runs it, and sends its response - a 304 if the
+// request's If-None-Match has the response's ETag, compressed if enabled
+// (see SetCompression) - then fires EVENT_APP_AFTER_HANDLE_REQUEST.
+func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	setSecurityHeaders(w, r, router.security)
+	if router.serveCors(w, r) {
+		return
 	}
-
-	return processResource(router, res)
+	router.mux.ServeHTTP(w, r)
 }
 
-// Start registers the router as the handler for "/" on the default net/http mux.
-func (router *Router) Start() {
-	http.Handle("/", router)
-}
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// serveResource serves every request but the assets' - see ServeHTTP.
+func (router *Router) serveResource(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	w = newCompressWriter(w, r, router.compression)
+	defer closeCompressWriter(w)
+	rec := &statusRecorder{ResponseWriter: w}
+	w = rec
 
-// ServeHTTP implements http.Handler: resolves the matching resource for the
-// request, runs it, and sends its response.
-func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	requestedRoute := r.URL.Path
 	if requestedRoute != "/" {
 		requestedRoute, _ = strings.CutSuffix(requestedRoute, "/")
 	}
 
-	cResource, code := router.defineResource(requestedRoute, r.Method)
+	route, params, cResource, code := router.defineResource(requestedRoute, r.Method)
+	var ctx kernel.IHandleContext
+	if router.app != nil {
+		defer func() {
+			router.app.Events().Trigger(kernel.EVENT_APP_AFTER_HANDLE_REQUEST, kernel.Dict{
+				"route":    route,
+				"method":   r.Method,
+				"status":   rec.Status(),
+				"duration": time.Since(start),
+				"context":  ctx,
+			})
+		}()
+	}
 	if code != 0 {
 		switch code {
 		case http.StatusNotFound:
@@ -206,44 +310,170 @@ func (router *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	res := cResource()
 	res.Init()
-	if response := router.Handle(res, requestedRoute, w, r); response != nil {
-		ctx := res.Context()
+	ctx = res.Context()
+	if response := router.handle(res, route, params, w, r); response != nil {
 		if router.app != nil {
 			router.app.Events().Trigger(kernel.EVENT_APP_BEFORE_SEND_RESPONSE, kernel.Dict{
 				"context":  ctx,
 				"response": response,
 			})
 		}
+		if resp, ok := response.(*Response); ok && resp.conditional(r) {
+			resp.sendNotModified(ctx.ResponseWriter())
+			return
+		}
 		response.Send(ctx.ResponseWriter())
 	}
 }
 
-/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
- * PRIVATE
- * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+func (router *Router) handle(
+	res kernel.IHttpResource,
+	route string,
+	params map[string]string,
+	w http.ResponseWriter,
+	r *http.Request,
+) kernel.IHttpResponse {
+	ctx := res.Context()
+	ctx.Init(
+		router.app,
+		route,
+		r.Method,
+		w,
+		r,
+	)
+	ctx.SetPathParams(params)
+	w.Header().Set(RequestIDHeader, ctx.RequestID())
+	ctx.Set(serializersKey{}, router.serializers)
 
-func (router *Router) defineResource(requestedRoute, method string) (kernel.CHttpResource, int) {
-	hList, ok := router.resources[requestedRoute]
-	if !ok {
-		return nil, http.StatusNotFound
+	if router.app != nil {
+		router.app.Events().Trigger(kernel.EVENT_APP_BEFORE_HANDLE_REQUEST, kernel.Dict{
+			"context": ctx,
+		})
 	}
 
-	var cHandler kernel.CHttpResource
-	try, exists := hList[method]
-	if exists {
-		cHandler = try
-	} else {
-		try, exists := hList["ALL"]
-		if exists {
-			cHandler = try
+	response := processResource(router, res)
+	if resp, ok := response.(*Response); ok {
+		resp.negotiate(router.serializers, r.Header.Get("Accept"))
+	}
+	return response
+}
+
+// defineResource resolves requestedRoute to its registered route - an exact
+// route first, then the pattern routes if it has no resource for method -
+// returning the route, the values its pattern captured (nil for an exact
+// route), and the resource constructor for method; or an HTTP error code
+// if there's none.
+func (router *Router) defineResource(requestedRoute, method string) (string, map[string]string, kernel.CHttpResource, int) {
+	hList, exact := router.resources[requestedRoute]
+	exact = exact && !isPatternRoute(requestedRoute)
+	if exact {
+		if cHandler := methodResource(hList, method); cHandler != nil {
+			return requestedRoute, nil, cHandler, 0
 		}
 	}
 
+	// Also when the exact route lacks the method - e.g. DELETE /users/me
+	// with /users/me[GET] and /users/{id}[DELETE] registered.
+	route, params := router.matchPattern(requestedRoute)
+	if route == "" {
+		if exact {
+			return "", nil, nil, http.StatusMethodNotAllowed
+		}
+		return "", nil, nil, http.StatusNotFound
+	}
+	cHandler := methodResource(router.resources[route], method)
 	if cHandler == nil {
-		return nil, http.StatusMethodNotAllowed
+		return "", nil, nil, http.StatusMethodNotAllowed
 	}
+	return route, params, cHandler, 0
+}
 
-	return cHandler, 0
+// matchPattern returns the pattern route matching requestedRoute and its
+// params, or an empty route if none does.
+func (router *Router) matchPattern(requestedRoute string) (string, map[string]string) {
+	if router.patterns == nil {
+		return "", nil
+	}
+	return router.patterns.match(requestedRoute)
+}
+
+// methodResource returns hList's resource for method, falling back to the
+// one registered for all methods - nil if there's neither.
+func methodResource(hList kernel.HttpResourcesList, method string) kernel.CHttpResource {
+	if cHandler, exists := hList[method]; exists {
+		return cHandler
+	}
+	return hList["ALL"]
+}
+
+func (router *Router) registerResource(
+	route string,
+	method string,
+	cResource kernel.CHttpResource,
+	group *RouteGroup,
+	mw []kernel.FMiddleware,
+) {
+	method = strings.ToUpper(method)
+
+	_, exists := router.resources[route]
+	if !exists {
+		if isPatternRoute(route) {
+			if router.patterns == nil {
+				router.patterns = newRouteTree()
+			}
+			if err := router.patterns.insert(route); err != nil {
+				router.logError(fmt.Sprintf("can not register route: %s", err))
+				return
+			}
+		}
+		router.resources[route] = make(kernel.HttpResourcesList)
+	}
+
+	if method == "" {
+		method = "ALL"
+	}
+
+	router.resources[route][method] = cResource
+	if group != nil || len(mw) > 0 {
+		if router.bindings == nil {
+			router.bindings = make(map[string]map[string]*routeBinding)
+		}
+		if router.bindings[route] == nil {
+			router.bindings[route] = make(map[string]*routeBinding)
+		}
+		router.bindings[route][method] = &routeBinding{group: group, middleware: mw}
+	} else if router.bindings[route] != nil {
+		delete(router.bindings[route], method)
+	}
+}
+
+// routeMiddleware returns the middleware registered for route/method on top
+// of the router-wide one - its groups' (outermost first), then its own -
+// falling back to the route's any-method ("ALL") registration, same as
+// defineResource does.
+func (router *Router) routeMiddleware(route, method string) []kernel.FWrapMiddleware {
+	methods, ok := router.bindings[route]
+	if !ok {
+		return nil
+	}
+	binding, ok := methods[method]
+	if !ok {
+		if _, registered := router.resources[route][method]; registered {
+			return nil
+		}
+		if binding, ok = methods["ALL"]; !ok {
+			return nil
+		}
+	}
+
+	var chain []kernel.FWrapMiddleware
+	if binding.group != nil {
+		chain = binding.group.chain()
+	}
+	for _, mw := range binding.middleware {
+		chain = append(chain, beforeHook(mw))
+	}
+	return chain
 }
 
 func parseRoute(route string) (string, string) {
@@ -257,33 +487,57 @@ func parseRoute(route string) (string, string) {
 	return route, ""
 }
 
-func processResource(router *Router, resource kernel.IHttpResource) kernel.IHttpResponse {
-	ctx := resource.Context()
-	for _, mw := range router.middleware {
-		err := mw(ctx)
-		if err != nil {
+func (router *Router) logError(msg string) {
+	if router.app == nil {
+		fmt.Println(msg)
+	} else {
+		router.app.LogError(msg, "HttpHandling")
+	}
+}
+
+// beforeHook adapts mw to a kernel.FWrapMiddleware that runs it and then
+// continues down the chain - or, if it fails, writes a generic 500 and
+// stops there with a nil response.
+func beforeHook(mw kernel.FMiddleware) kernel.FWrapMiddleware {
+	return func(ctx kernel.IHandleContext, next kernel.FNextHandler) kernel.IHttpResponse {
+		if err := mw(ctx); err != nil {
 			msg := fmt.Sprintf("can not process middleware: %s", err)
-			if router.app == nil {
+			if ctx.App() == nil {
 				fmt.Println(msg)
 			} else {
-				router.app.LogError(msg, "HttpHandling")
+				ctx.App().LogError(msg, "HttpHandling")
 			}
 			http.Error(ctx.ResponseWriter(), "Internal server error", http.StatusInternalServerError)
 			return nil
 		}
+		return next()
 	}
+}
 
+func processResource(router *Router, resource kernel.IHttpResource) kernel.IHttpResponse {
+	ctx := resource.Context()
+	chain := append(slices.Clip(router.middleware), router.routeMiddleware(ctx.Route(), ctx.Method())...)
+
+	next := func() kernel.IHttpResponse {
+		return runResource(router, resource)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		mw, inner := chain[i], next
+		next = func() kernel.IHttpResponse {
+			return mw(ctx, inner)
+		}
+	}
+	return next()
+}
+
+func runResource(router *Router, resource kernel.IHttpResource) kernel.IHttpResponse {
+	ctx := resource.Context()
 	var resp kernel.IHttpResponse
 	cReq := resource.CRequestForm()
 	if cReq != nil {
 		reqForm := cReq()
 		if err := FormFiller().SetContext(ctx).SetForm(reqForm).Fill(); err != nil {
-			msg := fmt.Sprintf("can not fill request form: %s", err)
-			if router.app == nil {
-				fmt.Println(msg)
-			} else {
-				router.app.LogError(msg, "HttpHandling")
-			}
+			router.logError(fmt.Sprintf("can not fill request form: %s", err))
 			http.Error(ctx.ResponseWriter(), "Internal server error", http.StatusInternalServerError)
 			return nil
 		}
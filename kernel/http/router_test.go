@@ -174,3 +174,75 @@ func TestProcessResource_BeforeRunCallbacks_RunInOrder(t *testing.T) {
 		t.Fatalf("got %v, want %v", order, want)
 	}
 }
+
+func TestProcessResource_WrapMiddleware_ShortCircuits(t *testing.T) {
+	router := &Router{}
+	var hookRan bool
+	router.AddWrapMiddleware(func(ctx kernel.IHandleContext, next kernel.FNextHandler) kernel.IHttpResponse {
+		return ErrorResponse(401, "Unauthorized")
+	})
+	router.AddMiddleware(func(kernel.IHandleContext) error {
+		hookRan = true
+		return nil
+	})
+
+	res := newTestResource()
+	ctx, _ := newGetContext("")
+	res.SetContext(ctx)
+
+	resp := processResource(router, res)
+
+	if resp == nil || resp.Code() != 401 {
+		t.Fatalf("expected the middleware's 401 response, got %#v", resp)
+	}
+	if hookRan {
+		t.Fatal("middleware registered after a short-circuiting one must not run")
+	}
+	if res.ran {
+		t.Fatal("Run() must not be called when a middleware short-circuits")
+	}
+}
+
+func TestProcessResource_WrapMiddleware_PostProcessesInOrder(t *testing.T) {
+	router := &Router{}
+	var order []string
+	router.AddWrapMiddleware(func(ctx kernel.IHandleContext, next kernel.FNextHandler) kernel.IHttpResponse {
+		order = append(order, "outer:before")
+		resp := next()
+		order = append(order, "outer:after")
+		resp.AddHeader("X-Outer", "1")
+		return resp
+	})
+	router.AddMiddleware(func(kernel.IHandleContext) error {
+		order = append(order, "hook")
+		return nil
+	})
+	router.AddWrapMiddleware(func(ctx kernel.IHandleContext, next kernel.FNextHandler) kernel.IHttpResponse {
+		order = append(order, "inner:before")
+		resp := next()
+		order = append(order, "inner:after")
+		return resp
+	})
+
+	res := newTestResource()
+	ctx, _ := newGetContext("")
+	res.SetContext(ctx)
+
+	resp := processResource(router, res)
+
+	if !res.ran {
+		t.Fatal("expected Run() to be called")
+	}
+	want := []string{"outer:before", "hook", "inner:before", "inner:after", "outer:after"}
+	if len(order) != len(want) {
+		t.Fatalf("got %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("got %v, want %v", order, want)
+		}
+	}
+	if resp.Headers()["X-Outer"] != "1" {
+		t.Fatalf("expected the outer middleware's header on the response, got %v", resp.Headers())
+	}
+}
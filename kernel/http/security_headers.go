@@ -0,0 +1,47 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// setSecurityHeaders sets conf's headers on w - HSTS only if r came over
+// HTTPS. A resource can still override them with its response's headers.
+func setSecurityHeaders(w http.ResponseWriter, r *http.Request, conf *kernel.SecurityHeadersConfig) {
+	if conf == nil {
+		return
+	}
+	h := w.Header()
+	if conf.ContentSecurityPolicy != "" {
+		if conf.CSPReportOnly {
+			h.Set("Content-Security-Policy-Report-Only", conf.ContentSecurityPolicy)
+		} else {
+			h.Set("Content-Security-Policy", conf.ContentSecurityPolicy)
+		}
+	}
+	if conf.HSTSMaxAge > 0 && r.TLS != nil {
+		hsts := "max-age=" + strconv.Itoa(conf.HSTSMaxAge)
+		if conf.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if conf.HSTSPreload {
+			hsts += "; preload"
+		}
+		h.Set("Strict-Transport-Security", hsts)
+	}
+	if conf.FrameOptions != "" {
+		h.Set("X-Frame-Options", conf.FrameOptions)
+	}
+	if conf.ReferrerPolicy != "" {
+		h.Set("Referrer-Policy", conf.ReferrerPolicy)
+	}
+	if conf.NoSniff {
+		h.Set("X-Content-Type-Options", "nosniff")
+	}
+}
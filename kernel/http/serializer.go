@@ -0,0 +1,528 @@
+package http
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Content types of the built-in serializers.
+const (
+	ContentTypeJson    = "application/json"
+	ContentTypeXml     = "application/xml"
+	ContentTypeMsgpack = "application/msgpack"
+	ContentTypeCsv     = "text/csv"
+)
+
+// DefaultSerializers are the serializers a Router starts with - JSON, the
+// default, first.
+var DefaultSerializers = []kernel.CSerializer{
+	NewJsonSerializer,
+	NewXmlSerializer,
+	NewMsgpackSerializer,
+	NewCsvSerializer,
+}
+
+// NegotiateSerializers orders serializers by the preference an Accept
+// header value expresses - the most preferred first, those it doesn't
+// accept at all left out. An empty header accepts the first serializer,
+// the default one, and so does a wildcard: the default goes first unless
+// the header names its type and ranks another one above it. A browser's
+// "text/html,...,application/xml;q=0.9,*/*;q=0.8" still gets JSON.
+func NegotiateSerializers(serializers []kernel.ISerializer, accept string) []kernel.ISerializer {
+	if strings.TrimSpace(accept) == "" {
+		return serializers[:min(1, len(serializers))]
+	}
+
+	ranges := parseAccept(accept)
+	type candidate struct {
+		s    kernel.ISerializer
+		q    float64
+		spec int
+		pos  int
+	}
+	var candidates []candidate
+	for pos, s := range serializers {
+		// The most specific matching range decides the serializer's quality.
+		best := candidate{s: s, spec: -1, pos: pos}
+		for _, r := range ranges {
+			if spec := r.match(s.ContentType()); spec > best.spec {
+				best.q, best.spec = r.q, spec
+			}
+		}
+		if best.spec >= 0 && best.q > 0 {
+			candidates = append(candidates, best)
+		}
+	}
+	if len(candidates) > 0 && candidates[0].pos == 0 && candidates[0].spec < 2 {
+		// The default serializer, accepted through a wildcard - first.
+		candidates[0].q = math.Inf(1)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].q != candidates[j].q {
+			return candidates[i].q > candidates[j].q
+		}
+		if candidates[i].spec != candidates[j].spec {
+			return candidates[i].spec > candidates[j].spec
+		}
+		return candidates[i].pos < candidates[j].pos
+	})
+
+	res := make([]kernel.ISerializer, len(candidates))
+	for i, c := range candidates {
+		res[i] = c.s
+	}
+	return res
+}
+
+// SerializerFor returns the serializer of a Content-Type header value's
+// media type, or nil.
+func SerializerFor(serializers []kernel.ISerializer, contentType string) kernel.ISerializer {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+	for _, s := range serializers {
+		if s.ContentType() == mediaType {
+			return s
+		}
+	}
+	return nil
+}
+
+/** @interface kernel.ISerializer */
+
+// JsonSerializer serializes to and from JSON.
+type JsonSerializer struct{}
+
+var _ kernel.ISerializer = (*JsonSerializer)(nil)
+
+/** @constructor */
+
+// NewJsonSerializer constructs a JsonSerializer.
+func NewJsonSerializer() kernel.ISerializer {
+	return &JsonSerializer{}
+}
+
+// ContentType returns "application/json".
+func (s *JsonSerializer) ContentType() string {
+	return ContentTypeJson
+}
+
+// Serialize marshals data to JSON.
+func (s *JsonSerializer) Serialize(data any) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// Deserialize decodes a JSON object.
+func (s *JsonSerializer) Deserialize(body []byte) (kernel.Dict, error) {
+	data := make(kernel.Dict)
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+/** @interface kernel.ISerializer */
+
+// XmlSerializer serializes to and from XML: data is written the way it's
+// written to JSON, under a <response> root - an object's fields become
+// elements, a list's items <item> elements. A request body's root element's
+// children become the Dict's values (strings, Dicts for elements with
+// children, lists for repeated elements).
+type XmlSerializer struct{}
+
+var _ kernel.ISerializer = (*XmlSerializer)(nil)
+
+/** @constructor */
+
+// NewXmlSerializer constructs an XmlSerializer.
+func NewXmlSerializer() kernel.ISerializer {
+	return &XmlSerializer{}
+}
+
+// ContentType returns "application/xml".
+func (s *XmlSerializer) ContentType() string {
+	return ContentTypeXml
+}
+
+// Serialize writes data as XML.
+func (s *XmlSerializer) Serialize(data any) ([]byte, error) {
+	value, err := normalize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := writeXml(enc, "response", value); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Deserialize reads an XML document's root element into a Dict.
+func (s *XmlSerializer) Deserialize(body []byte) (kernel.Dict, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			value, err := readXml(dec, start)
+			if err != nil {
+				return nil, err
+			}
+			if dict, ok := value.(kernel.Dict); ok {
+				return dict, nil
+			}
+			return kernel.Dict{}, nil
+		}
+	}
+}
+
+/** @interface kernel.ISerializer */
+
+// MsgpackSerializer serializes to and from MessagePack - data is written
+// the way it's written to JSON.
+type MsgpackSerializer struct{}
+
+var _ kernel.ISerializer = (*MsgpackSerializer)(nil)
+
+/** @constructor */
+
+// NewMsgpackSerializer constructs a MsgpackSerializer.
+func NewMsgpackSerializer() kernel.ISerializer {
+	return &MsgpackSerializer{}
+}
+
+// ContentType returns "application/msgpack".
+func (s *MsgpackSerializer) ContentType() string {
+	return ContentTypeMsgpack
+}
+
+// Serialize encodes data as MessagePack.
+func (s *MsgpackSerializer) Serialize(data any) ([]byte, error) {
+	value, err := normalize(data)
+	if err != nil {
+		return nil, err
+	}
+	return msgpack.Marshal(value)
+}
+
+// Deserialize decodes a MessagePack map.
+func (s *MsgpackSerializer) Deserialize(body []byte) (kernel.Dict, error) {
+	data := make(map[string]any)
+	if err := msgpack.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+	return kernel.Dict(data), nil
+}
+
+/** @interface kernel.ISerializer */
+
+// CsvSerializer serializes list responses to CSV: a header row of the
+// items' fields (sorted), then a row per item - a list of scalars is
+// written as a single "value" column, and nested values as JSON. Data that
+// isn't a list can't be serialized, so a request accepting it and JSON
+// gets JSON for such responses. Request bodies aren't decoded.
+type CsvSerializer struct{}
+
+var _ kernel.ISerializer = (*CsvSerializer)(nil)
+
+/** @constructor */
+
+// NewCsvSerializer constructs a CsvSerializer.
+func NewCsvSerializer() kernel.ISerializer {
+	return &CsvSerializer{}
+}
+
+// ContentType returns "text/csv".
+func (s *CsvSerializer) ContentType() string {
+	return ContentTypeCsv
+}
+
+// Serialize writes list data as CSV.
+func (s *CsvSerializer) Serialize(data any) ([]byte, error) {
+	value, err := normalize(data)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("csv: only lists can be serialized")
+	}
+
+	var columns []string
+	for _, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			columns = nil
+			break
+		}
+		for key := range obj {
+			if !slices.Contains(columns, key) {
+				columns = append(columns, key)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if columns == nil {
+		w.Write([]string{"value"})
+		for _, item := range list {
+			w.Write([]string{csvCell(item)})
+		}
+	} else {
+		w.Write(columns)
+		for _, item := range list {
+			obj := item.(map[string]any)
+			row := make([]string, len(columns))
+			for i, col := range columns {
+				row[i] = csvCell(obj[col])
+			}
+			w.Write(row)
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// Deserialize isn't supported - CSV is a response-only format.
+func (s *CsvSerializer) Deserialize(body []byte) (kernel.Dict, error) {
+	return nil, errors.New("csv: request bodies are not supported")
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// serializersKey is the IHandleContext key the serving router's
+// serializers are kept under, for the form filler to decode bodies with.
+type serializersKey struct{}
+
+// contextSerializers returns the serializers of the router serving ctx, or
+// the default ones.
+func contextSerializers(ctx kernel.IHandleContext) []kernel.ISerializer {
+	if list, ok := ctx.Get(serializersKey{}).([]kernel.ISerializer); ok {
+		return list
+	}
+	list := make([]kernel.ISerializer, len(DefaultSerializers))
+	for i, c := range DefaultSerializers {
+		list[i] = c()
+	}
+	return list
+}
+
+type acceptRange struct {
+	tp, subtype string
+	q           float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, item := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(item))
+		if err != nil {
+			continue
+		}
+		tp, subtype, _ := strings.Cut(mediaType, "/")
+		r := acceptRange{tp: tp, subtype: subtype, q: 1}
+		if q, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil {
+				r.q = v
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+// match returns how specifically the range matches contentType - 2 for
+// "type/subtype", 1 for "type/*", 0 for "*/*" - or -1 if it doesn't.
+func (r acceptRange) match(contentType string) int {
+	tp, subtype, _ := strings.Cut(contentType, "/")
+	switch {
+	case r.tp == "*" && r.subtype == "*":
+		return 0
+	case r.tp == tp && r.subtype == "*":
+		return 1
+	case r.tp == tp && r.subtype == subtype:
+		return 2
+	}
+	return -1
+}
+
+// normalize turns data into what it'd be decoded from its JSON - maps,
+// lists and scalars, numbers as int64 where they're whole - so every
+// format writes the same fields the JSON one does.
+func normalize(data any) (any, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return convertNumbers(value), nil
+}
+
+func convertNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = convertNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = convertNumbers(item)
+		}
+	}
+	return value
+}
+
+// writeXml writes value as an element called name - or, if name isn't a
+// valid XML name, an <item key="name"> one.
+func writeXml(enc *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !isXmlName(name) {
+		start = xml.StartElement{
+			Name: xml.Name{Local: "item"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+		}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeXml(enc, key, v[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := writeXml(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(v))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// isXmlName reports whether name can be an element's name as is - no
+// namespace prefix, nor the reserved "xml" one.
+func isXmlName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// readXml reads the element start opened: its text if it has no child
+// elements, a Dict of them otherwise.
+func readXml(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	var text strings.Builder
+	var children kernel.Dict
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			value, err := readXml(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			if children == nil {
+				children = make(kernel.Dict)
+			}
+			name := t.Name.Local
+			for _, attr := range t.Attr {
+				if name == "item" && attr.Name.Local == "key" {
+					// A key that isn't an XML name - see writeXml.
+					name = attr.Value
+				}
+			}
+			switch prev := children[name].(type) {
+			case nil:
+				children[name] = value
+			case []any:
+				children[name] = append(prev, value)
+			default:
+				children[name] = []any{prev, value}
+			}
+		case xml.EndElement:
+			if children != nil {
+				return children, nil
+			}
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+}
+
+func csvCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]any, []any:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,223 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiateSerializers(t *testing.T) {
+	router := NewRouter(nil)
+	serializers := router.Serializers()
+
+	cases := []struct {
+		accept string
+		want   []string
+	}{
+		{"", []string{ContentTypeJson}},
+		{"application/xml", []string{ContentTypeXml}},
+		{"text/csv, application/json;q=0.5", []string{ContentTypeCsv, ContentTypeJson}},
+		{"application/*;q=0.9, text/csv;q=0.1", []string{ContentTypeJson, ContentTypeXml, ContentTypeMsgpack, ContentTypeCsv}},
+		{"*/*, application/msgpack;q=0", []string{ContentTypeJson, ContentTypeXml, ContentTypeCsv}},
+		{"text/html", nil},
+		// A browser's default - the wildcard keeps JSON first.
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			[]string{ContentTypeJson, ContentTypeXml, ContentTypeMsgpack, ContentTypeCsv}},
+		{"application/xml, application/json;q=0.5", []string{ContentTypeXml, ContentTypeJson}},
+	}
+	for _, tc := range cases {
+		var got []string
+		for _, s := range NegotiateSerializers(serializers, tc.accept) {
+			got = append(got, s.ContentType())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Accept %q: got %v, want %v", tc.accept, got, tc.want)
+		}
+	}
+}
+
+type serializedItem struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+func TestSerializers(t *testing.T) {
+	list := []serializedItem{{ID: 1, Name: "a, b"}, {ID: 2, Name: "c", Tags: []string{"x"}}}
+
+	t.Run("xml", func(t *testing.T) {
+		s := NewXmlSerializer()
+		data, err := s.Serialize(kernel.Dict{"items": list, "total": 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `<response><items><item><id>1</id><name>a, b</name></item>` +
+			`<item><id>2</id><name>c</name><tags><item>x</item></tags></item></items><total>2</total></response>`
+		if !strings.HasSuffix(string(data), want) {
+			t.Fatalf("got %s", data)
+		}
+
+		dict, err := s.Deserialize([]byte(`<request><name>Alice</name><tag>a</tag><tag>b</tag><address><city>Oslo</city></address></request>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want2 := kernel.Dict{"name": "Alice", "tag": []any{"a", "b"}, "address": kernel.Dict{"city": "Oslo"}}
+		if !reflect.DeepEqual(dict, want2) {
+			t.Fatalf("got %#v", dict)
+		}
+
+		odd := kernel.Dict{"1st": "a", "two words": "b", "xmlns": "c", "ok": "d"}
+		data, err = s.Serialize(odd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = `<response><item key="1st">a</item><ok>d</ok><item key="two words">b</item><item key="xmlns">c</item></response>`
+		if !strings.HasSuffix(string(data), want) {
+			t.Fatalf("expected invalid names as item keys, got %s", data)
+		}
+		if dict, err = s.Deserialize(data); err != nil || !reflect.DeepEqual(dict, odd) {
+			t.Fatalf("expected the keys read back, got %#v, %v", dict, err)
+		}
+	})
+
+	t.Run("msgpack", func(t *testing.T) {
+		s := NewMsgpackSerializer()
+		data, err := s.Serialize(list[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded map[string]any
+		if err := msgpack.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(decoded["id"]) != "1" || decoded["name"] != "a, b" {
+			t.Fatalf("got %#v", decoded)
+		}
+
+		dict, err := s.Deserialize(data)
+		if err != nil || dict["name"] != "a, b" {
+			t.Fatalf("got %#v, %v", dict, err)
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		s := NewCsvSerializer()
+		data, err := s.Serialize(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "id,name,tags\n1,\"a, b\",\n2,c,\"[\"\"x\"\"]\"\n"
+		if string(data) != want {
+			t.Fatalf("got %q, want %q", data, want)
+		}
+
+		if data, _ := s.Serialize([]int{1, 2}); string(data) != "value\n1\n2\n" {
+			t.Errorf("got %q for a list of scalars", data)
+		}
+		if _, err := s.Serialize(kernel.Dict{"id": 1}); err == nil {
+			t.Error("expected an error for a non-list")
+		}
+	})
+}
+
+type serializerTestForm struct {
+	*Form
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type echoResource struct {
+	*Resource
+}
+
+func (r *echoResource) Run() kernel.IHttpResponse {
+	req := r.RequestForm().(*serializerTestForm)
+	if req.HasErrors() {
+		return r.ErrorResponse(400, req.GetFirstError().Error())
+	}
+	if req.Name == "list" {
+		return r.JsonResponse(kernel.JsonResponseConfig{Data: []kernel.Dict{{"name": req.Name, "age": req.Age}}})
+	}
+	return r.JsonResponse(kernel.JsonResponseConfig{Data: kernel.Dict{"name": req.Name, "age": req.Age}})
+}
+
+// textSerializer is a custom serializer of "name=age" lines.
+type textSerializer struct{}
+
+func (s *textSerializer) ContentType() string { return "text/plain" }
+
+func (s *textSerializer) Serialize(data any) ([]byte, error) {
+	d := data.(kernel.Dict)
+	return []byte(d["name"].(string) + "=" + strings.Repeat("I", d["age"].(int))), nil
+}
+
+func (s *textSerializer) Deserialize(body []byte) (kernel.Dict, error) {
+	name, age, _ := strings.Cut(string(body), "=")
+	return kernel.Dict{"name": name, "age": len(age)}, nil
+}
+
+func TestRouter_ContentNegotiation(t *testing.T) {
+	router := NewRouter(nil)
+	router.RegisterSerializer(func() kernel.ISerializer { return &textSerializer{} })
+	router.RegisterResource("/echo", "POST", func() kernel.IHttpResource {
+		return &echoResource{Resource: NewResource(kernel.HttpResourceConfig{
+			CRequestForm: func() kernel.IForm { return &serializerTestForm{Form: NewForm()} },
+		})}
+	})
+
+	do := func(contentType string, body []byte, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/echo", bytes.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		router.(*Router).ServeHTTP(rec, req)
+		return rec
+	}
+
+	cases := []struct {
+		name, contentType, body, accept string
+		wantType, wantBody              string
+	}{
+		{"json", "application/json", `{"name":"Bob","age":3}`, "", "application/json", `{"age":3,"name":"Bob"}`},
+		{"xml", "application/xml; charset=utf-8", `<r><name>Bob</name><age>3</age></r>`, "application/xml",
+			"application/xml", xmlHeader + `<response><age>3</age><name>Bob</name></response>`},
+		{"custom", "text/plain", "Bob=III", "text/plain", "text/plain", "Bob=III"},
+		{"csv list", "application/json", `{"name":"list","age":3}`, "text/csv", "text/csv", "age,name\n3,list\n"},
+		{"csv fallback", "application/json", `{"name":"Bob","age":3}`, "text/csv, application/json;q=0.1", "application/json", `{"age":3,"name":"Bob"}`},
+		{"not acceptable", "application/json", `{"name":"Bob","age":3}`, "text/html", "application/json", `{"age":3,"name":"Bob"}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := do(tc.contentType, []byte(tc.body), tc.accept)
+			if rec.Code != 200 {
+				t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != tc.wantType {
+				t.Errorf("got content type %q, want %q", got, tc.wantType)
+			}
+			if got := rec.Body.String(); got != tc.wantBody {
+				t.Errorf("got body %q, want %q", got, tc.wantBody)
+			}
+			if rec.Header().Get("Vary") != "Accept" {
+				t.Error("expected a Vary: Accept header")
+			}
+		})
+	}
+
+	t.Run("msgpack body", func(t *testing.T) {
+		body, _ := msgpack.Marshal(map[string]any{"name": "Bob", "age": 3})
+		rec := do(ContentTypeMsgpack, body, ContentTypeMsgpack)
+		var decoded map[string]any
+		if err := msgpack.Unmarshal(rec.Body.Bytes(), &decoded); err != nil || decoded["name"] != "Bob" {
+			t.Fatalf("got %#v, %v", decoded, err)
+		}
+	})
+}
+
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
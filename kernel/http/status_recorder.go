@@ -0,0 +1,67 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+/** @interface http.ResponseWriter */
+
+// statusRecorder wraps the http.ResponseWriter a request is served with,
+// remembering the status code written to it - see Router.ServeHTTP and
+// kernel.EVENT_APP_AFTER_HANDLE_REQUEST. It passes Flush/Hijack through,
+// and Unwrap lets http.ResponseController reach the original writer.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+var _ http.ResponseWriter = (*statusRecorder)(nil)
+
+// Status returns the status code written, 200 OK if the handler wrote a
+// body without setting one (and, same as net/http, if it wrote nothing at all).
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// WriteHeader records code and passes it on.
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write passes b on, recording an implicit 200 OK if no status was set yet.
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush flushes the underlying writer, if it supports it.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hijacks the underlying connection, if the underlying writer supports it.
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying writer - see http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
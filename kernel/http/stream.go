@@ -0,0 +1,321 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+// DefaultSSEHeartbeat is the interval of an SSE response's heartbeat
+// comments unless kernel.SSEResponseConfig.Heartbeat says otherwise.
+const DefaultSSEHeartbeat = 15 * time.Second
+
+/** @interface kernel.IHttpResponse */
+
+// StreamResponse is a kernel.IHttpResponse sending its body as it's
+// produced rather than from a buffer - see NewStreamResponse,
+// NewFileResponse and NewSSEResponse. Setting it HTML or JSON data via the
+// embedded Response replaces the stream. Long-lived streams aren't cut by
+// the server's WriteTimeout.
+type StreamResponse struct {
+	*Response
+	ctx  kernel.IHandleContext
+	send func(w http.ResponseWriter) error
+}
+
+var _ kernel.IHttpResponse = (*StreamResponse)(nil)
+
+/** @constructor */
+
+// NewStreamResponse builds a StreamResponse for ctx's request, copying
+// conf.Body or running conf.Write.
+func NewStreamResponse(ctx kernel.IHandleContext, conf kernel.StreamResponseConfig) *StreamResponse {
+	s := newStreamResponse(ctx, conf.Code, conf.Headers)
+	contentType := conf.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	s.send = func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(s.Code())
+		sw := newStreamWriter(w, ctx.Request())
+		if conf.Body != nil {
+			if c, ok := conf.Body.(io.Closer); ok {
+				defer c.Close()
+			}
+			_, err := io.Copy(sw, conf.Body)
+			return err
+		}
+		if conf.Write != nil {
+			return conf.Write(sw)
+		}
+		return nil
+	}
+	return s
+}
+
+/** @constructor */
+
+// NewFileResponse builds a StreamResponse downloading conf.Path's file (or
+// conf.Content) for ctx's request - Range, HEAD and conditional requests
+// included, see http.ServeContent. It fails if the file can't be opened.
+func NewFileResponse(ctx kernel.IHandleContext, conf kernel.FileResponseConfig) (*StreamResponse, error) {
+	content, name, modTime := conf.Content, conf.Name, conf.ModTime
+	if conf.Path != "" {
+		path := conf.Path
+		if ctx.App() != nil {
+			path = ctx.App().Pathfinder().GetAbsPath(path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("can not open file '%s': %w", conf.Path, err)
+		}
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			f.Close()
+			return nil, fmt.Errorf("can not open file '%s': not a regular file", conf.Path)
+		}
+		content, modTime = f, info.ModTime()
+		if name == "" {
+			name = filepath.Base(path)
+		}
+	}
+	if content == nil {
+		return nil, errors.New("can not send file: no Path or Content")
+	}
+
+	s := newStreamResponse(ctx, 0, conf.Headers)
+	disposition := "attachment"
+	if conf.Inline {
+		disposition = "inline"
+	}
+	if name != "" {
+		disposition = mime.FormatMediaType(disposition, map[string]string{"filename": name})
+	}
+	s.send = func(w http.ResponseWriter) error {
+		if c, ok := content.(io.Closer); ok {
+			defer c.Close()
+		}
+		if w.Header().Get("Content-Disposition") == "" {
+			w.Header().Set("Content-Disposition", disposition)
+		}
+		http.ServeContent(w, ctx.Request(), name, modTime, content)
+		return nil
+	}
+	return s, nil
+}
+
+/** @constructor */
+
+// NewSSEResponse builds a StreamResponse sending Server-Sent Events for
+// ctx's request - conf.Run sends them while heartbeat comments keep the
+// connection alive, until it returns or the client disconnects.
+func NewSSEResponse(ctx kernel.IHandleContext, conf kernel.SSEResponseConfig) *StreamResponse {
+	s := newStreamResponse(ctx, http.StatusOK, conf.Headers)
+	s.send = func(w http.ResponseWriter) error {
+		h := w.Header()
+		h.Set("Content-Type", "text/event-stream")
+		h.Set("Cache-Control", "no-cache")
+		h.Set("X-Accel-Buffering", "no")
+		w.WriteHeader(s.Code())
+
+		stream := &sseStream{
+			w:           newStreamWriter(w, ctx.Request()),
+			lastEventID: ctx.Request().Header.Get("Last-Event-ID"),
+		}
+		if conf.Retry > 0 {
+			if err := stream.write("retry: " + strconv.FormatInt(conf.Retry.Milliseconds(), 10) + "\n\n"); err != nil {
+				return err
+			}
+		} else if err := stream.w.Flush(); err != nil {
+			return err
+		}
+		if conf.Run == nil {
+			return nil
+		}
+
+		heartbeat := conf.Heartbeat
+		if heartbeat == 0 {
+			heartbeat = DefaultSSEHeartbeat
+		}
+		if heartbeat > 0 {
+			done := make(chan struct{})
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stream.heartbeat(heartbeat, done)
+			}()
+			// The writer mustn't outlive the handler.
+			defer wg.Wait()
+			defer close(done)
+		}
+		return conf.Run(stream)
+	}
+	return s
+}
+
+// Send writes the response to w - see kernel.IHttpResponse. A stream's
+// error is logged, unless the client disconnected.
+func (s *StreamResponse) Send(w http.ResponseWriter) {
+	if s.dataType != "" {
+		s.Response.Send(w)
+		return
+	}
+	s.writeHeaders(w)
+	if err := s.send(w); err != nil && s.ctx.Request().Context().Err() == nil {
+		s.ctx.Logger().Error("can not send streamed response", "error", err.Error())
+	}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+func newStreamResponse(ctx kernel.IHandleContext, code int, headers map[string]string) *StreamResponse {
+	s := &StreamResponse{Response: new(Response), ctx: ctx}
+	if code != 0 {
+		s.SetCode(code)
+	}
+	for key, val := range headers {
+		s.AddHeader(key, val)
+	}
+	return s
+}
+
+/** @interface kernel.IStreamWriter */
+
+// streamWriter is the kernel.IStreamWriter a StreamResponse's body is
+// written through. It lifts the write deadline, so the server's
+// WriteTimeout doesn't cut long streams.
+type streamWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	ctx context.Context
+}
+
+var _ kernel.IStreamWriter = (*streamWriter)(nil)
+
+func newStreamWriter(w http.ResponseWriter, r *http.Request) *streamWriter {
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	return &streamWriter{w: w, rc: rc, ctx: r.Context()}
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	if err := sw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return sw.w.Write(p)
+}
+
+func (sw *streamWriter) Flush() error {
+	if err := sw.ctx.Err(); err != nil {
+		return err
+	}
+	if err := sw.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+func (sw *streamWriter) Context() context.Context {
+	return sw.ctx
+}
+
+/** @interface kernel.ISSEStream */
+
+// sseStream is the kernel.ISSEStream an SSE response's events are sent
+// through - mu serializes the events and the heartbeat comments.
+type sseStream struct {
+	mu          sync.Mutex
+	w           *streamWriter
+	lastEventID string
+}
+
+var _ kernel.ISSEStream = (*sseStream)(nil)
+
+func (s *sseStream) Send(event kernel.SSEEvent) error {
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: " + singleLine(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + singleLine(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	var data string
+	switch d := event.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		encoded, err := json.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("can not encode event data: %w", err)
+		}
+		data = string(encoded)
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+func (s *sseStream) LastEventID() string {
+	return s.lastEventID
+}
+
+func (s *sseStream) Context() context.Context {
+	return s.w.ctx
+}
+
+func (s *sseStream) write(msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := io.WriteString(s.w, msg); err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// heartbeat sends a comment every interval until done is closed or the
+// client disconnects.
+func (s *sseStream) heartbeat(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-s.w.ctx.Done():
+			return
+		case <-ticker.C:
+			if s.write(": heartbeat\n\n") != nil {
+				return
+			}
+		}
+	}
+}
+
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
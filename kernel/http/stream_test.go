@@ -0,0 +1,218 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+type funcResource struct {
+	*Resource
+	run func(r *Resource) kernel.IHttpResponse
+}
+
+func (r *funcResource) Run() kernel.IHttpResponse {
+	return r.run(r.Resource)
+}
+
+func newStreamServer(t *testing.T, routes map[string]func(r *Resource) kernel.IHttpResponse) *httptest.Server {
+	t.Helper()
+	router := NewRouter(nil).(*Router)
+	router.SetCompression(kernel.CompressionConfig{MinSize: 10})
+	for route, run := range routes {
+		router.RegisterResource(route, "GET", func() kernel.IHttpResource {
+			return &funcResource{Resource: NewResource(), run: run}
+		})
+	}
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestStreamResponse(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader("id,name\n1,alpha\n")}
+	srv := newStreamServer(t, map[string]func(r *Resource) kernel.IHttpResponse{
+		"/chunks": func(r *Resource) kernel.IHttpResponse {
+			return r.StreamResponse(kernel.StreamResponseConfig{
+				ContentType: "text/plain",
+				Write: func(w kernel.IStreamWriter) error {
+					for i := 0; i < 3; i++ {
+						if _, err := io.WriteString(w, "chunk\n"); err != nil {
+							return err
+						}
+						if err := w.Flush(); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			})
+		},
+		"/export": func(r *Resource) kernel.IHttpResponse {
+			return r.StreamResponse(kernel.StreamResponseConfig{
+				Code:        http.StatusCreated,
+				Headers:     map[string]string{"X-Export": "users"},
+				ContentType: "text/csv",
+				Body:        body,
+			})
+		},
+	})
+
+	resp, err := http.Get(srv.URL + "/chunks")
+	if err != nil {
+		t.Fatalf("GET /chunks: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "chunk\nchunk\nchunk\n" || resp.Header.Get("Content-Type") != "text/plain" {
+		t.Fatalf("unexpected stream: %q %v", data, resp.Header)
+	}
+	if resp.TransferEncoding == nil || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("expected a chunked response, got %v", resp.TransferEncoding)
+	}
+
+	resp, err = http.Get(srv.URL + "/export")
+	if err != nil {
+		t.Fatalf("GET /export: %v", err)
+	}
+	data, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("X-Export") != "users" || string(data) != "id,name\n1,alpha\n" {
+		t.Fatalf("unexpected response: %d %v %q", resp.StatusCode, resp.Header, data)
+	}
+	if !body.closed {
+		t.Error("expected the body to be closed")
+	}
+}
+
+func TestFileResponse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := newStreamServer(t, map[string]func(r *Resource) kernel.IHttpResponse{
+		"/report": func(r *Resource) kernel.IHttpResponse {
+			return r.FileResponse(kernel.FileResponseConfig{Path: path, Name: "monthly report.txt"})
+		},
+		"/missing": func(r *Resource) kernel.IHttpResponse {
+			return r.FileResponse(kernel.FileResponseConfig{Path: path + ".none"})
+		},
+	})
+
+	req, _ := http.NewRequest("GET", srv.URL+"/report", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /report: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(data) != "2345" {
+		t.Fatalf("expected the range, got %d %q", resp.StatusCode, data)
+	}
+	if resp.Header.Get("Content-Disposition") != `attachment; filename="monthly report.txt"` {
+		t.Errorf("unexpected Content-Disposition: %q", resp.Header.Get("Content-Disposition"))
+	}
+	if resp.Header.Get("Accept-Ranges") != "bytes" {
+		t.Errorf("expected Accept-Ranges")
+	}
+
+	resp, err = http.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatalf("GET /missing: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing file, got %d", resp.StatusCode)
+	}
+}
+
+func TestSSEResponse(t *testing.T) {
+	finished := make(chan error, 1)
+	srv := newStreamServer(t, map[string]func(r *Resource) kernel.IHttpResponse{
+		"/events": func(r *Resource) kernel.IHttpResponse {
+			return r.SSEResponse(kernel.SSEResponseConfig{
+				Retry:     3 * time.Second,
+				Heartbeat: 20 * time.Millisecond,
+				Run: func(stream kernel.ISSEStream) error {
+					stream.Send(kernel.SSEEvent{ID: "8", Event: "resumed", Data: stream.LastEventID()})
+					stream.Send(kernel.SSEEvent{ID: "9", Data: kernel.Dict{"n": 9}})
+					stream.Send(kernel.SSEEvent{Data: "two\nlines"})
+					<-stream.Context().Done()
+					finished <- stream.Send(kernel.SSEEvent{Data: "late"})
+					return nil
+				},
+			})
+		},
+	})
+
+	req, _ := http.NewRequest("GET", srv.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "7")
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" || resp.Header.Get("Cache-Control") != "no-cache" {
+		t.Fatalf("unexpected headers: %v", resp.Header)
+	}
+
+	expected := []string{
+		"retry: 3000", "",
+		"id: 8", "event: resumed", "data: 7", "",
+		"id: 9", `data: {"n":9}`, "",
+		"data: two", "data: lines", "",
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	heartbeats := 0
+	for i := 0; i < len(expected); {
+		if !scanner.Scan() {
+			t.Fatalf("line %d: stream ended: %v", i, scanner.Err())
+		}
+		if scanner.Text() == ": heartbeat" {
+			heartbeats++
+			continue
+		}
+		if scanner.Text() != expected[i] {
+			t.Fatalf("line %d: expected %q, got %q", i, expected[i], scanner.Text())
+		}
+		i++
+	}
+	for heartbeats == 0 && scanner.Scan() {
+		if scanner.Text() == ": heartbeat" {
+			heartbeats++
+		}
+	}
+	if heartbeats == 0 {
+		t.Fatal("expected a heartbeat")
+	}
+	resp.Body.Close()
+
+	select {
+	case err := <-finished:
+		if err == nil {
+			t.Fatal("expected sending to a disconnected client to fail")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Run to see the client disconnect")
+	}
+}
@@ -0,0 +1,176 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+// PForm is satisfied by a pointer to a form struct - T embeds *Form, or
+// otherwise implements kernel.IForm on its pointer. See Handle, which
+// builds the forms itself: their embedded *Form and nested form fields
+// don't need a constructor.
+type PForm[T any] interface {
+	*T
+	kernel.IForm
+}
+
+// Handle adapts fn into a resource constructor, to register like any
+// other (see kernel.IRouter.RegisterResource). The request is decoded into
+// a new Req form and checked by its rules and Validate before fn runs -
+// an invalid one gets a 400 (413 for a too large body). fn's Resp is sent
+// as JSON, or an empty 204 if it's nil. fn's error is sent with
+// ErrorForm's fields: a kernel.IError's code is its HTTP status if it's
+// one (400-599), other IErrors get a 400 with their own code, and any
+// other error is logged and sent as a 500. Req and Resp are documented as
+// the resource's request and response forms - see the openapi package:
+//
+//	router.RegisterResource("/users", "POST", lxHttp.Handle(
+//		func(ctx kernel.IHandleContext, req *CreateUserRequest) (*UserResponse, error) {
+//			// ...
+//		},
+//	))
+func Handle[Req, Resp any, PReq PForm[Req], PResp PForm[Resp]](
+	fn func(ctx kernel.IHandleContext, req PReq) (PResp, error),
+) kernel.CHttpResource {
+	return func() kernel.IHttpResource {
+		return &typedResource[Req, Resp, PReq, PResp]{
+			Resource: NewResource(kernel.HttpResourceConfig{
+				CRequestForm:  func() kernel.IForm { return newTypedForm[Req, PReq]() },
+				CResponseForm: func() kernel.IForm { return newTypedForm[Resp, PResp]() },
+				CFailForm:     NewErrorForm,
+			}),
+			fn: fn,
+		}
+	}
+}
+
+/** @interface kernel.IForm */
+
+// ErrorForm is the failed response of a resource built with Handle.
+type ErrorForm struct {
+	*Form
+	Success      bool   `json:"success"`
+	ErrorCode    uint   `json:"error_code"`
+	ErrorMessage string `json:"error_message"`
+}
+
+var _ kernel.IForm = (*ErrorForm)(nil)
+
+/** @constructor kernel.CForm */
+
+// NewErrorForm constructs an empty ErrorForm.
+func NewErrorForm() kernel.IForm {
+	return PrepareForm(&ErrorForm{Form: NewForm()})
+}
+
+// Config describes ErrorForm's fields.
+func (f *ErrorForm) Config() kernel.FormConfig {
+	return kernel.FormConfig{
+		"success":       kernel.FormFieldConfig{Description: "Always false"},
+		"error_code":    kernel.FormFieldConfig{Description: "Application error code, 0 if none"},
+		"error_message": kernel.FormFieldConfig{Description: "Error description"},
+	}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IHttpResource */
+type typedResource[Req, Resp any, PReq PForm[Req], PResp PForm[Resp]] struct {
+	*Resource
+	fn func(ctx kernel.IHandleContext, req PReq) (PResp, error)
+}
+
+var formPtrType = reflect.TypeOf((*Form)(nil))
+
+// newTypedForm constructs a T form, its embedded *Form and nested forms
+// included.
+func newTypedForm[T any, PT PForm[T]]() PT {
+	f := PT(new(T))
+	prepareTypedForm(f)
+	return f
+}
+
+func prepareTypedForm(f kernel.IForm) {
+	initForm(f)
+	v := reflect.ValueOf(f).Elem()
+	if v.Kind() == reflect.Struct {
+		for i := 0; i < v.NumField(); i++ {
+			field, value := v.Type().Field(i), v.Field(i)
+			if field.Anonymous || !field.IsExported() || value.Kind() != reflect.Pointer || !value.IsNil() {
+				continue
+			}
+			// A form nesting its own type is left nil, not built forever.
+			if field.Type != v.Addr().Type() && field.Type.Implements(formInterfaceType) &&
+				field.Type.Elem().Kind() == reflect.Struct {
+				nested := reflect.New(field.Type.Elem())
+				prepareTypedForm(nested.Interface().(kernel.IForm))
+				value.Set(nested)
+			}
+		}
+	}
+	PrepareForm(f)
+}
+
+// initForm sets f's embedded *Form, if it's nil - so a form built as a
+// plain struct literal can collect errors.
+func initForm(f kernel.IForm) {
+	v := reflect.ValueOf(f).Elem()
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous && field.Type == formPtrType && v.Field(i).IsNil() {
+			v.Field(i).Set(reflect.ValueOf(NewForm()))
+		}
+	}
+}
+
+func (r *typedResource[Req, Resp, PReq, PResp]) Run() kernel.IHttpResponse {
+	resp, err := r.fn(r.Context(), r.RequestForm().(PReq))
+	if err != nil {
+		return r.errorResponse(err)
+	}
+	if resp == nil {
+		response := new(Response)
+		response.SetCode(http.StatusNoContent)
+		return response
+	}
+	initForm(resp)
+	return r.JsonResponse(kernel.JsonResponseConfig{Form: resp})
+}
+
+func (r *typedResource[Req, Resp, PReq, PResp]) ProcessRequestErrors() kernel.IHttpResponse {
+	err := r.RequestForm().GetFirstError()
+	status := http.StatusBadRequest
+	if code := err.Code(); code == ERR_BODY_TOO_LARGE || code == ERR_FILE_TOO_LARGE {
+		status = http.StatusRequestEntityTooLarge
+	}
+	return r.fail(status, err.Code(), err.Error())
+}
+
+func (r *typedResource[Req, Resp, PReq, PResp]) errorResponse(err error) kernel.IHttpResponse {
+	var appErr kernel.IError
+	if !errors.As(err, &appErr) {
+		r.LogError(err.Error(), "HttpHandling")
+		return r.fail(http.StatusInternalServerError, 0, "Internal server error")
+	}
+
+	status := http.StatusBadRequest
+	if code := appErr.Code(); code >= 400 && code < 600 {
+		status = int(code)
+	}
+	return r.fail(status, appErr.Code(), appErr.Error())
+}
+
+func (r *typedResource[Req, Resp, PReq, PResp]) fail(status int, code uint, msg string) kernel.IHttpResponse {
+	f := NewErrorForm().(*ErrorForm)
+	f.ErrorCode = code
+	f.ErrorMessage = msg
+	return r.FailResponse(kernel.JsonResponseConfig{Code: status, Form: f})
+}
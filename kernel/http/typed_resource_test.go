@@ -0,0 +1,123 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxErrors "github.com/epicoon/lxgo/kernel/errors"
+)
+
+type createItemRequest struct {
+	*Form
+	ID   int    `json:"id"`
+	Name string `json:"name" validate:"min=3"`
+}
+
+func (f *createItemRequest) Config() kernel.FormConfig {
+	return kernel.FormConfig{
+		"name": kernel.FormFieldConfig{Description: "Item name", Required: true},
+	}
+}
+
+type itemResponse struct {
+	*Form
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newTypedServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	router := NewRouter(nil).(*Router)
+	router.RegisterResource("/items/{id:int}", "POST", Handle(
+		func(ctx kernel.IHandleContext, req *createItemRequest) (*itemResponse, error) {
+			switch req.Name {
+			case "taken":
+				return nil, lxErrors.NewCodifiedError(http.StatusConflict, "name is taken")
+			case "banned":
+				return nil, fmt.Errorf("check name: %w", lxErrors.NewCodifiedError(1005, "name is banned"))
+			case "broken":
+				return nil, errors.New("database is down")
+			case "empty":
+				return nil, nil
+			}
+			return &itemResponse{ID: req.ID, Name: req.Name}, nil
+		},
+	))
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHandle(t *testing.T) {
+	srv := newTypedServer(t)
+
+	tests := []struct {
+		name string
+		body string
+		code int
+		want map[string]any
+	}{
+		{"success", `{"name":"apple"}`, http.StatusOK, map[string]any{"id": 7.0, "name": "apple"}},
+		{"missing field", `{}`, http.StatusBadRequest, map[string]any{"success": false}},
+		{"rule", `{"name":"ab"}`, http.StatusBadRequest, map[string]any{"success": false, "error_code": 901.0}},
+		{"http code", `{"name":"taken"}`, http.StatusConflict, map[string]any{"error_code": 409.0, "error_message": "name is taken"}},
+		{"app code", `{"name":"banned"}`, http.StatusBadRequest, map[string]any{"error_code": 1005.0, "error_message": "name is banned"}},
+		{"plain error", `{"name":"broken"}`, http.StatusInternalServerError, map[string]any{"error_code": 0.0, "error_message": "Internal server error"}},
+		{"no content", `{"name":"empty"}`, http.StatusNoContent, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/items/7", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("POST: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.code {
+				t.Fatalf("expected %d, got %d", tt.code, resp.StatusCode)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			var got map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			for key, val := range tt.want {
+				if got[key] != val {
+					t.Errorf("%s: expected %v, got %v (body %v)", key, val, got[key], got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandle_Forms(t *testing.T) {
+	router := NewRouter(nil).(*Router)
+	router.RegisterResource("/items", "POST", Handle(
+		func(ctx kernel.IHandleContext, req *createItemRequest) (*itemResponse, error) {
+			return nil, nil
+		},
+	))
+
+	res := router.Resources()["/items"]["POST"]()
+	req, ok := res.CRequestForm()().(*createItemRequest)
+	if !ok || req.Form == nil {
+		t.Fatalf("expected a prepared *createItemRequest, got %#v", res.CRequestForm()())
+	}
+	if len(req.Required()) != 1 || req.Required()[0] != "name" {
+		t.Errorf("expected the required fields from Config, got %v", req.Required())
+	}
+	if _, ok := res.CResponseForm()().(*itemResponse); !ok {
+		t.Errorf("expected an *itemResponse response form")
+	}
+	if _, ok := res.CFailForm()().(*ErrorForm); !ok {
+		t.Errorf("expected an *ErrorForm fail form")
+	}
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/cast"
+)
+
+// Upload limits' defaults, in bytes - see UploadLimits.
+const (
+	DefaultMaxBodySize int64 = 32 << 20
+	DefaultMaxFileSize int64 = 10 << 20
+	DefaultMaxMemory   int64 = 8 << 20
+)
+
+// Error codes of rejected uploads - next to the validation package's
+// rule error codes.
+const (
+	ERR_BODY_TOO_LARGE uint = 921 + iota
+	ERR_FILE_TOO_LARGE
+)
+
+// UploadLimits bounds the multipart/form-data requests the form filler
+// accepts - set them in the app's config, in bytes:
+//
+//	Uploads:
+//	  MaxBodySize: 33554432
+//	  MaxFileSize: 10485760
+//	  MaxMemory: 8388608
+//
+// A single upload field can have its own MaxFileSize, see
+// kernel.FormFieldConfig.MaxSize.
+type UploadLimits struct {
+	// MaxBodySize is the max size of the whole request body.
+	MaxBodySize int64
+	// MaxFileSize is the max size of each uploaded file.
+	MaxFileSize int64
+	// MaxMemory is how much of the files is kept in memory - the rest is
+	// stored in temporary files, removed once the request is served.
+	MaxMemory int64
+}
+
+// AppUploadLimits returns app's upload limits, defaults for those not
+// configured (or for a nil app).
+func AppUploadLimits(app kernel.IApp) UploadLimits {
+	limits := UploadLimits{
+		MaxBodySize: DefaultMaxBodySize,
+		MaxFileSize: DefaultMaxFileSize,
+		MaxMemory:   DefaultMaxMemory,
+	}
+	if app == nil {
+		return limits
+	}
+
+	for key, dst := range map[string]*int64{
+		"MaxBodySize": &limits.MaxBodySize,
+		"MaxFileSize": &limits.MaxFileSize,
+		"MaxMemory":   &limits.MaxMemory,
+	} {
+		if val := app.ConfigParam("Uploads." + key); val != nil {
+			if n, err := cast.To[int64](val); err == nil && n > 0 {
+				*dst = n
+			}
+		}
+	}
+	return limits
+}
+
+// UploadedFile is the form field type of an uploaded file - declare a field
+// as *UploadedFile for a single file, or []*UploadedFile for several sent
+// under the same name:
+//
+//	type AvatarRequest struct {
+//		*lxHttp.Form
+//		Avatar *lxHttp.UploadedFile `json:"avatar"`
+//	}
+type UploadedFile struct {
+	// Name is the file's name, as sent by the client.
+	Name string
+	// Size is the file's size in bytes.
+	Size int64
+	// ContentType is the file's content type, as sent by the client.
+	ContentType string
+	// Header is the file's part header.
+	Header textproto.MIMEHeader
+
+	fileHeader *multipart.FileHeader
+}
+
+/** @constructor */
+
+// NewUploadedFile wraps a parsed multipart file.
+func NewUploadedFile(fh *multipart.FileHeader) *UploadedFile {
+	return &UploadedFile{
+		Name:        fh.Filename,
+		Size:        fh.Size,
+		ContentType: fh.Header.Get("Content-Type"),
+		Header:      fh.Header,
+		fileHeader:  fh,
+	}
+}
+
+// Open opens the file's content for reading - close it when done.
+func (f *UploadedFile) Open() (multipart.File, error) {
+	return f.fileHeader.Open()
+}
+
+// SaveTo copies the file's content to path, creating or truncating it.
+func (f *UploadedFile) SaveTo(path string) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
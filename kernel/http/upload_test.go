@@ -0,0 +1,140 @@
+package http_test
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+type uploadForm struct {
+	*lxHttp.Form
+	Title  string                 `json:"title"`
+	Avatar *lxHttp.UploadedFile   `json:"avatar"`
+	Docs   []*lxHttp.UploadedFile `json:"docs"`
+}
+
+func (f *uploadForm) Config() kernel.FormConfig {
+	return kernel.FormConfig{
+		"avatar": {Required: true, MaxSize: 10},
+	}
+}
+
+func newUploadForm() *uploadForm {
+	return lxHttp.PrepareForm(&uploadForm{Form: lxHttp.NewForm()}).(*uploadForm)
+}
+
+type part struct {
+	field, filename, content string
+}
+
+func fillUpload(t *testing.T, app kernel.IApp, parts ...part) *uploadForm {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, p := range parts {
+		if p.filename == "" {
+			w.WriteField(p.field, p.content)
+			continue
+		}
+		fw, err := w.CreateFormFile(p.field, p.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(p.content))
+	}
+	w.Close()
+
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	ctx := lxHttp.NewHandleContext(app, "/upload", nil)
+	ctx.Init(app, "/upload", "POST", httptest.NewRecorder(), req)
+
+	f := newUploadForm()
+	if err := lxHttp.FormFiller().SetContext(ctx).SetForm(f).Fill(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return f
+}
+
+func TestFormFiller_Fill_Multipart(t *testing.T) {
+	f := fillUpload(t, nil,
+		part{field: "title", content: "My files"},
+		part{field: "avatar", filename: "me.png", content: "png data"},
+		part{field: "docs", filename: "a.csv", content: "a,b"},
+		part{field: "docs", filename: "b.csv", content: "c,d"},
+	)
+	if f.HasErrors() {
+		t.Fatalf("unexpected form error: %v", f.GetFirstError())
+	}
+	if f.Title != "My files" {
+		t.Errorf("got title %q", f.Title)
+	}
+	if f.Avatar == nil || f.Avatar.Name != "me.png" || f.Avatar.Size != 8 || f.Avatar.ContentType != "application/octet-stream" {
+		t.Fatalf("unexpected avatar %+v", f.Avatar)
+	}
+	if len(f.Docs) != 2 || f.Docs[1].Name != "b.csv" {
+		t.Fatalf("unexpected docs %+v", f.Docs)
+	}
+
+	file, err := f.Docs[0].Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "a,b" {
+		t.Errorf("got content %q", data)
+	}
+
+	path := filepath.Join(t.TempDir(), "avatar.png")
+	if err := f.Avatar.SaveTo(path); err != nil {
+		t.Fatalf("SaveTo: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "png data" {
+		t.Errorf("got saved content %q", data)
+	}
+}
+
+func TestFormFiller_Fill_MultipartLimits(t *testing.T) {
+	t.Run("field max size", func(t *testing.T) {
+		f := fillUpload(t, nil, part{field: "avatar", filename: "big.png", content: "more than ten bytes"})
+		err := f.GetFirstError()
+		if err == nil || err.Code() != lxHttp.ERR_FILE_TOO_LARGE || !strings.HasPrefix(err.Error(), "avatar: ") {
+			t.Fatalf("expected a file size error, got %v", err)
+		}
+	})
+
+	t.Run("app max file size", func(t *testing.T) {
+		app, err := apptest.New(kernel.Dict{"Uploads": kernel.Dict{"MaxFileSize": 4}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := fillUpload(t, app,
+			part{field: "avatar", filename: "me.png", content: "png"},
+			part{field: "docs", filename: "a.csv", content: "a,b,c,d"},
+		)
+		if err := f.GetFirstError(); err == nil || err.Code() != lxHttp.ERR_FILE_TOO_LARGE {
+			t.Fatalf("expected a file size error, got %v", err)
+		}
+	})
+
+	t.Run("app max body size", func(t *testing.T) {
+		app, err := apptest.New(kernel.Dict{"Uploads": kernel.Dict{"MaxBodySize": 64}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := fillUpload(t, app, part{field: "avatar", filename: "me.png", content: strings.Repeat("x", 100)})
+		if err := f.GetFirstError(); err == nil || err.Code() != lxHttp.ERR_BODY_TOO_LARGE {
+			t.Fatalf("expected a body size error, got %v", err)
+		}
+	})
+}
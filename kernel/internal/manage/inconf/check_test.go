@@ -18,31 +18,34 @@ type fakeApp struct {
 
 var _ kernel.IApp = (*fakeApp)(nil)
 
-func (a *fakeApp) BaseApp() kernel.IApp                       { return a }
-func (a *fakeApp) SetPort(int)                                {}
-func (a *fakeApp) ConfigPath() string                         { return "" }
-func (a *fakeApp) SetConfig(c kernel.IDict)                   { a.config = c }
-func (a *fakeApp) SetConfigParam(string, any)                 {}
-func (a *fakeApp) ConfigParam(string) any                     { return nil }
-func (a *fakeApp) Config() kernel.IDict                       { return a.config }
-func (a *fakeApp) SetComponent(any, kernel.IAppComponent)     {}
-func (a *fakeApp) HasComponent(any) bool                      { return false }
-func (a *fakeApp) Component(any) kernel.IAppComponent         { return nil }
-func (a *fakeApp) SetConnection(kernel.IConnection)           {}
-func (a *fakeApp) Pathfinder() kernel.IPathfinder             { return nil }
-func (a *fakeApp) DIContainer() kernel.IDIContainer           { return nil }
-func (a *fakeApp) Connection() kernel.IConnection             { return nil }
-func (a *fakeApp) Router() kernel.IRouter                     { return nil }
-func (a *fakeApp) TemplateHolder() kernel.ITemplateHolder     { return nil }
-func (a *fakeApp) TemplateRenderer() kernel.ITemplateRenderer { return nil }
-func (a *fakeApp) Events() kernel.IEventManager               { return nil }
-func (a *fakeApp) Log(string, string)                         {}
-func (a *fakeApp) LogWarning(string, string)                  {}
-func (a *fakeApp) LogError(string, string)                    {}
-func (a *fakeApp) Logger() kernel.ILogger                     { return nil }
-func (a *fakeApp) SetLogger(kernel.ILogger)                   {}
-func (a *fakeApp) Run()                                       {}
-func (a *fakeApp) Final()                                     {}
+func (a *fakeApp) BaseApp() kernel.IApp                          { return a }
+func (a *fakeApp) SetPort(int)                                   {}
+func (a *fakeApp) ConfigPath() string                            { return "" }
+func (a *fakeApp) SetConfig(c kernel.IDict)                      { a.config = c }
+func (a *fakeApp) SetConfigParam(string, any)                    {}
+func (a *fakeApp) ConfigParam(string) any                        { return nil }
+func (a *fakeApp) Config() kernel.IDict                          { return a.config }
+func (a *fakeApp) SetComponent(any, kernel.IAppComponent)        {}
+func (a *fakeApp) HasComponent(any) bool                         { return false }
+func (a *fakeApp) Component(any) kernel.IAppComponent            { return nil }
+func (a *fakeApp) SetConnection(kernel.IConnection)              {}
+func (a *fakeApp) SetNamedConnection(string, kernel.IConnection) {}
+func (a *fakeApp) Pathfinder() kernel.IPathfinder                { return nil }
+func (a *fakeApp) DIContainer() kernel.IDIContainer              { return nil }
+func (a *fakeApp) Connection() kernel.IConnection                { return nil }
+func (a *fakeApp) NamedConnection(string) kernel.IConnection     { return nil }
+func (a *fakeApp) ConnectionNames() []string                     { return nil }
+func (a *fakeApp) Router() kernel.IRouter                        { return nil }
+func (a *fakeApp) TemplateHolder() kernel.ITemplateHolder        { return nil }
+func (a *fakeApp) TemplateRenderer() kernel.ITemplateRenderer    { return nil }
+func (a *fakeApp) Events() kernel.IEventManager                  { return nil }
+func (a *fakeApp) Log(string, string)                            {}
+func (a *fakeApp) LogWarning(string, string)                     {}
+func (a *fakeApp) LogError(string, string)                       {}
+func (a *fakeApp) Logger() kernel.ILogger                        { return nil }
+func (a *fakeApp) SetLogger(kernel.ILogger)                      {}
+func (a *fakeApp) Run()                                          {}
+func (a *fakeApp) Final()                                        {}
 
 func TestParseArrayAccess(t *testing.T) {
 	cases := []struct {
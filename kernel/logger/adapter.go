@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+// For returns a structured logger for app: app's own logger if it's a
+// kernel.IStructuredLogger, otherwise one writing through app's
+// Log/LogWarning/LogError (see Adapt) - so it works whatever logger, if
+// any, app was given. A nil app gets one writing to the standard log package.
+func For(app kernel.IApp) kernel.IStructuredLogger {
+	if app == nil {
+		return Adapt(nil)
+	}
+	if l, ok := app.Logger().(kernel.IStructuredLogger); ok {
+		return l
+	}
+	return Adapt(app)
+}
+
+/** @interface kernel.IStructuredLogger */
+
+// adapter is a kernel.IStructuredLogger over a plain kernel.ILogger, which
+// has neither fields nor a debug level: fields are appended to the message
+// as "key=value" pairs, and Debug entries are dropped.
+type adapter struct {
+	target   kernel.ILogger
+	category string
+	fields   []Field
+}
+
+var _ kernel.IStructuredLogger = (*adapter)(nil)
+
+/** @constructor */
+
+// Adapt wraps l (the standard log package if nil) into a
+// kernel.IStructuredLogger - see For.
+func Adapt(l kernel.ILogger) kernel.IStructuredLogger {
+	return &adapter{target: l, category: DefaultCategory}
+}
+
+// Log writes an informational message under category.
+func (a *adapter) Log(msg string, category string) {
+	a.write(LevelInfo, category, msg, nil)
+}
+
+// LogWarning writes a warning message under category.
+func (a *adapter) LogWarning(msg string, category string) {
+	a.write(LevelWarning, category, msg, nil)
+}
+
+// LogError writes an error message under category.
+func (a *adapter) LogError(msg string, category string) {
+	a.write(LevelError, category, msg, nil)
+}
+
+// Debug drops the entry - a plain ILogger has no debug level.
+func (a *adapter) Debug(msg string, kv ...any) {
+	// Pass
+}
+
+// Info writes an informational entry.
+func (a *adapter) Info(msg string, kv ...any) {
+	a.write(LevelInfo, a.category, msg, kv)
+}
+
+// Warning writes a warning entry.
+func (a *adapter) Warning(msg string, kv ...any) {
+	a.write(LevelWarning, a.category, msg, kv)
+}
+
+// Error writes an error entry.
+func (a *adapter) Error(msg string, kv ...any) {
+	a.write(LevelError, a.category, msg, kv)
+}
+
+// With returns a logger adding kv to every entry it writes.
+func (a *adapter) With(kv ...any) kernel.IStructuredLogger {
+	return &adapter{
+		target:   a.target,
+		category: a.category,
+		fields:   appendFields(a.fields, toFields(kv)),
+	}
+}
+
+// WithCategory returns a logger writing its entries under category.
+func (a *adapter) WithCategory(category string) kernel.IStructuredLogger {
+	return &adapter{
+		target:   a.target,
+		category: category,
+		fields:   a.fields,
+	}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+func (a *adapter) write(level Level, category, msg string, kv []any) {
+	fields := appendFields(a.fields, toFields(kv))
+	if len(fields) > 0 {
+		var b bytes.Buffer
+		b.WriteString(msg)
+		appendTextFields(&b, fields)
+		msg = b.String()
+	}
+
+	if a.target == nil {
+		// Same format as App's own fallback, see App.Log.
+		switch level {
+		case LevelWarning:
+			log.Println("[" + category + ": warning]" + " " + msg)
+		case LevelError:
+			log.Println("[" + category + ": error]" + " " + msg)
+		default:
+			log.Println("[" + category + "]" + " " + msg)
+		}
+		return
+	}
+
+	switch level {
+	case LevelWarning:
+		a.target.LogWarning(msg, category)
+	case LevelError:
+		a.target.LogError(msg, category)
+	default:
+		a.target.Log(msg, category)
+	}
+}
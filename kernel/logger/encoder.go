@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field is a single key/value pair attached to an Entry.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Entry is a single log entry, as handed to an IEncoder.
+type Entry struct {
+	Time     time.Time
+	Level    Level
+	Category string
+	Message  string
+	Fields   []Field
+}
+
+// IEncoder formats an Entry into the line a Logger writes to its output.
+type IEncoder interface {
+	// Encode returns e's line, including the trailing newline.
+	Encode(e *Entry) []byte
+}
+
+/** @interface IEncoder */
+
+// TextEncoder encodes an entry as a human-readable line:
+//
+//	2024-05-01T10:00:00.000Z INFO [HttpHandling] request served status=200 took=1.2ms
+type TextEncoder struct{}
+
+var _ IEncoder = (*TextEncoder)(nil)
+
+// Encode returns e's text line.
+func (TextEncoder) Encode(e *Entry) []byte {
+	var b bytes.Buffer
+	b.WriteString(e.Time.UTC().Format(timeFormat))
+	b.WriteByte(' ')
+	b.WriteString(strings.ToUpper(e.Level.String()))
+	if e.Category != "" {
+		b.WriteString(" [")
+		b.WriteString(e.Category)
+		b.WriteByte(']')
+	}
+	b.WriteByte(' ')
+	b.WriteString(e.Message)
+	appendTextFields(&b, e.Fields)
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+/** @interface IEncoder */
+
+// JSONEncoder encodes an entry as a single-line JSON object - "time",
+// "level", "category" and "msg" first, then the entry's fields:
+//
+//	{"time":"2024-05-01T10:00:00.000Z","level":"info","category":"HttpHandling","msg":"request served","status":200}
+type JSONEncoder struct{}
+
+var _ IEncoder = (*JSONEncoder)(nil)
+
+// Encode returns e's JSON line.
+func (JSONEncoder) Encode(e *Entry) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	appendJSONPair(&b, "time", e.Time.UTC().Format(timeFormat))
+	b.WriteByte(',')
+	appendJSONPair(&b, "level", e.Level.String())
+	if e.Category != "" {
+		b.WriteByte(',')
+		appendJSONPair(&b, "category", e.Category)
+	}
+	b.WriteByte(',')
+	appendJSONPair(&b, "msg", e.Message)
+	for _, f := range e.Fields {
+		b.WriteByte(',')
+		appendJSONPair(&b, f.Key, f.Value)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// badKey is the key a value with no key of its own (an odd trailing kv
+// element) is logged under.
+const badKey = "!BADKEY"
+
+// toFields turns a flat alternating key/value list into fields - a
+// non-string key is stringified.
+func toFields(kv []any) []Field {
+	if len(kv) == 0 {
+		return nil
+	}
+	fields := make([]Field, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			fields = append(fields, Field{Key: badKey, Value: kv[i]})
+			break
+		}
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		fields = append(fields, Field{Key: key, Value: kv[i+1]})
+	}
+	return fields
+}
+
+func appendTextFields(b *bytes.Buffer, fields []Field) {
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		b.WriteString(textValue(f.Value))
+	}
+}
+
+func textValue(v any) string {
+	var s string
+	switch val := v.(type) {
+	case string:
+		s = val
+	case error:
+		s = val.Error()
+	case fmt.Stringer:
+		s = val.String()
+	default:
+		s = fmt.Sprint(val)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\t\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func appendJSONPair(b *bytes.Buffer, key string, v any) {
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteByte(':')
+	b.Write(jsonValue(v))
+}
+
+func jsonValue(v any) []byte {
+	switch val := v.(type) {
+	case error:
+		v = val.Error()
+	case time.Duration:
+		v = val.String()
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprint(v))
+	}
+	return data
+}
@@ -0,0 +1,54 @@
+// Package logger provides the default kernel.IStructuredLogger
+// implementation (Logger) - leveled entries with key/value fields, encoded
+// as text or JSON, written to stdout/stderr or a rotating file, with
+// per-category level overrides. InitApp builds one from the app config's
+// "Logger" section (see Config); For returns a structured logger for any
+// app, adapting a plain kernel.ILogger if that's all the app has.
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is a log entry's severity - a Logger drops entries below its level
+// (or below its category's level, see Config.Categories).
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+)
+
+// String returns the level's lowercase name, as used in config and output.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarning:
+		return "warning"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel parses a level name - "debug", "info", "warning" (or "warn")
+// or "error", case-insensitively.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warning", "warn":
+		return LevelWarning, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level '%s'", s)
+}
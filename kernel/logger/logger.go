@@ -0,0 +1,258 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+)
+
+// DefaultCategory is the category a Logger's Debug/Info/Warning/Error
+// entries are written under until WithCategory sets another one.
+const DefaultCategory = "App"
+
+// Config configures a Logger - its fields map onto the app config's
+// "Logger" section, see NewFromConfig:
+//
+//	Logger:
+//	  Level: info          # debug, info (the default), warning or error
+//	  Format: json         # json or text (the default)
+//	  Output: logs/app.log # stdout (the default), stderr or a file path
+//	  MaxSize: 10          # rotate the file after this many megabytes, 0 - never
+//	  MaxBackups: 5        # rotated files to keep, 0 - all of them
+//	  Categories:          # per-category levels, overriding Level
+//	    HttpHandling: debug
+//	    Session: warning
+type Config struct {
+	Level      string
+	Format     string
+	Output     string
+	MaxSize    int
+	MaxBackups int
+	Categories map[string]string
+}
+
+/** @interface kernel.IStructuredLogger */
+
+// Logger is the default kernel.IStructuredLogger implementation. Loggers
+// derived from one via With/WithCategory share its output, encoder and
+// levels - so e.g. SetCategoryLevel affects all of them.
+type Logger struct {
+	core     *core
+	category string
+	fields   []Field
+}
+
+var _ kernel.IStructuredLogger = (*Logger)(nil)
+
+/** @constructor */
+
+// New constructs a Logger writing entries at level and above to out,
+// formatted by encoder (a TextEncoder if nil).
+func New(out io.Writer, encoder IEncoder, level Level) *Logger {
+	if encoder == nil {
+		encoder = TextEncoder{}
+	}
+	return &Logger{
+		core: &core{
+			out:        out,
+			encoder:    encoder,
+			level:      level,
+			categories: make(map[string]Level),
+		},
+		category: DefaultCategory,
+	}
+}
+
+/** @constructor */
+
+// NewFromConfig constructs a Logger from conf, opening its output file if
+// conf names one - a relative path is taken as is, so resolve it against
+// the app's root first (InitApp does).
+func NewFromConfig(conf Config) (*Logger, error) {
+	level := LevelInfo
+	if conf.Level != "" {
+		l, err := ParseLevel(conf.Level)
+		if err != nil {
+			return nil, err
+		}
+		level = l
+	}
+
+	var encoder IEncoder
+	switch strings.ToLower(conf.Format) {
+	case "", "text":
+		encoder = TextEncoder{}
+	case "json":
+		encoder = JSONEncoder{}
+	default:
+		return nil, fmt.Errorf("unknown log format '%s'", conf.Format)
+	}
+
+	var out io.Writer
+	switch strings.ToLower(conf.Output) {
+	case "", "stdout":
+		out = os.Stdout
+	case "stderr":
+		out = os.Stderr
+	default:
+		f, err := NewRotatingFile(conf.Output, int64(conf.MaxSize)*1024*1024, conf.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		out = f
+	}
+
+	l := New(out, encoder, level)
+	for category, name := range conf.Categories {
+		catLevel, err := ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("category '%s': %v", category, err)
+		}
+		l.SetCategoryLevel(category, catLevel)
+	}
+	return l, nil
+}
+
+// SetLevel sets the level entries are written at, for every category
+// without a level of its own.
+func (l *Logger) SetLevel(level Level) {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	l.core.level = level
+}
+
+// SetCategoryLevel sets the level entries under category are written at,
+// overriding the logger's level.
+func (l *Logger) SetCategoryLevel(category string, level Level) {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	l.core.categories[category] = level
+}
+
+// Enabled reports whether an entry at level under category would be written.
+func (l *Logger) Enabled(level Level, category string) bool {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	return l.core.enabled(level, category)
+}
+
+// Log writes an informational message under category.
+func (l *Logger) Log(msg string, category string) {
+	l.write(LevelInfo, category, msg, nil)
+}
+
+// LogWarning writes a warning message under category.
+func (l *Logger) LogWarning(msg string, category string) {
+	l.write(LevelWarning, category, msg, nil)
+}
+
+// LogError writes an error message under category.
+func (l *Logger) LogError(msg string, category string) {
+	l.write(LevelError, category, msg, nil)
+}
+
+// Debug writes a debug entry.
+func (l *Logger) Debug(msg string, kv ...any) {
+	l.write(LevelDebug, l.category, msg, kv)
+}
+
+// Info writes an informational entry.
+func (l *Logger) Info(msg string, kv ...any) {
+	l.write(LevelInfo, l.category, msg, kv)
+}
+
+// Warning writes a warning entry.
+func (l *Logger) Warning(msg string, kv ...any) {
+	l.write(LevelWarning, l.category, msg, kv)
+}
+
+// Error writes an error entry.
+func (l *Logger) Error(msg string, kv ...any) {
+	l.write(LevelError, l.category, msg, kv)
+}
+
+// With returns a logger adding kv to every entry it writes.
+func (l *Logger) With(kv ...any) kernel.IStructuredLogger {
+	return &Logger{
+		core:     l.core,
+		category: l.category,
+		fields:   appendFields(l.fields, toFields(kv)),
+	}
+}
+
+// WithCategory returns a logger writing its entries under category.
+func (l *Logger) WithCategory(category string) kernel.IStructuredLogger {
+	return &Logger{
+		core:     l.core,
+		category: category,
+		fields:   l.fields,
+	}
+}
+
+// Close closes the logger's output if it's a file - stdout/stderr stay open.
+func (l *Logger) Close() error {
+	if l.core.out == os.Stdout || l.core.out == os.Stderr {
+		return nil
+	}
+	if c, ok := l.core.out.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// core is the state shared by a Logger and every logger derived from it.
+type core struct {
+	mu         sync.Mutex
+	out        io.Writer
+	encoder    IEncoder
+	level      Level
+	categories map[string]Level
+}
+
+func (c *core) enabled(level Level, category string) bool {
+	if catLevel, ok := c.categories[category]; ok {
+		return level >= catLevel
+	}
+	return level >= c.level
+}
+
+func (l *Logger) write(level Level, category, msg string, kv []any) {
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+
+	if !l.core.enabled(level, category) {
+		return
+	}
+
+	e := &Entry{
+		Time:     time.Now(),
+		Level:    level,
+		Category: category,
+		Message:  msg,
+		Fields:   appendFields(l.fields, toFields(kv)),
+	}
+	if _, err := l.core.out.Write(l.core.encoder.Encode(e)); err != nil {
+		fmt.Fprintf(os.Stderr, "Can not write log entry: %v\n", err)
+	}
+}
+
+// appendFields returns base followed by extra, never sharing extra's
+// backing array with base - loggers derived from the same parent mustn't
+// overwrite each other's fields.
+func appendFields(base, extra []Field) []Field {
+	if len(extra) == 0 {
+		return base
+	}
+	res := make([]Field, 0, len(base)+len(extra))
+	res = append(res, base...)
+	return append(res, extra...)
+}
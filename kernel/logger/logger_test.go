@@ -0,0 +1,209 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel/logger"
+)
+
+func TestLogger_TextEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.New(&buf, logger.TextEncoder{}, logger.LevelInfo)
+
+	l.WithCategory("HttpHandling").With("route", "/users").Info("request served", "status", 200, "note", "a b", "err", errors.New("boom"))
+
+	line := buf.String()
+	for _, want := range []string{
+		" INFO [HttpHandling] request served ",
+		"route=/users",
+		"status=200",
+		`note="a b"`,
+		"err=boom",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected %q in %q", want, line)
+		}
+	}
+	if !strings.HasSuffix(line, "\n") {
+		t.Errorf("expected a trailing newline in %q", line)
+	}
+}
+
+func TestLogger_JSONEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.New(&buf, logger.JSONEncoder{}, logger.LevelDebug)
+
+	l.With("request_id", "abc").Debug("took a while", "took", 1500*time.Millisecond, "odd")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("not a JSON line: %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"level":      "debug",
+		"category":   logger.DefaultCategory,
+		"msg":        "took a while",
+		"request_id": "abc",
+		"took":       "1.5s",
+		"!BADKEY":    "odd",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s: got %v, want %v", k, entry[k], v)
+		}
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Error("expected a time field")
+	}
+}
+
+func TestLogger_Levels(t *testing.T) {
+	var buf bytes.Buffer
+	l := logger.New(&buf, nil, logger.LevelWarning)
+	l.SetCategoryLevel("Verbose", logger.LevelDebug)
+	l.SetCategoryLevel("Quiet", logger.LevelError)
+
+	l.Info("dropped")
+	l.Log("dropped", "App")
+	l.LogWarning("kept-1", "App")
+	l.WithCategory("Verbose").Debug("kept-2")
+	l.LogWarning("dropped", "Quiet")
+	l.LogError("kept-3", "Quiet")
+
+	out := buf.String()
+	if strings.Contains(out, "dropped") {
+		t.Errorf("expected entries below their category's level to be dropped, got %q", out)
+	}
+	for _, want := range []string{"kept-1", "kept-2", "kept-3"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+}
+
+func TestLogger_With_DoesNotShareFields(t *testing.T) {
+	var buf bytes.Buffer
+	base := logger.New(&buf, nil, logger.LevelInfo).With("a", 1)
+	first := base.With("b", 2)
+	second := base.With("c", 3)
+
+	first.Info("first")
+	second.Info("second")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	if !strings.Contains(lines[0], "a=1 b=2") || strings.Contains(lines[0], "c=3") {
+		t.Errorf("unexpected first line %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "a=1 c=3") || strings.Contains(lines[1], "b=2") {
+		t.Errorf("unexpected second line %q", lines[1])
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	l, err := logger.NewFromConfig(logger.Config{
+		Level:      "warning",
+		Format:     "json",
+		Output:     path,
+		Categories: map[string]string{"Db": "debug"},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	l.Info("dropped")
+	l.WithCategory("Db").Debug("query", "sql", "SELECT 1")
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	if strings.Contains(string(data), "dropped") || !strings.Contains(string(data), `"sql":"SELECT 1"`) {
+		t.Fatalf("unexpected log file contents %q", data)
+	}
+
+	for _, conf := range []logger.Config{
+		{Level: "verbose"},
+		{Format: "xml"},
+		{Categories: map[string]string{"Db": "loud"}},
+	} {
+		if _, err := logger.NewFromConfig(conf); err == nil {
+			t.Errorf("expected an error for %+v", conf)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := logger.NewRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingFile: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "dddddd\n",
+		path + ".1": "cccccc\n",
+		path + ".2": "bbbbbb\n",
+	}
+	for p, content := range want {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("read %s: %v", p, err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: got %q, want %q", p, data, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("expected at most 2 backups to be kept")
+	}
+}
+
+// capturingLogger is a plain kernel.ILogger recording what it's given.
+type capturingLogger struct {
+	lines []string
+}
+
+func (c *capturingLogger) Log(msg, category string) {
+	c.lines = append(c.lines, "info ["+category+"] "+msg)
+}
+func (c *capturingLogger) LogWarning(msg, category string) {
+	c.lines = append(c.lines, "warning ["+category+"] "+msg)
+}
+func (c *capturingLogger) LogError(msg, category string) {
+	c.lines = append(c.lines, "error ["+category+"] "+msg)
+}
+
+func TestAdapt(t *testing.T) {
+	target := &capturingLogger{}
+	l := logger.Adapt(target).WithCategory("Auth").With("user", 7)
+
+	l.Debug("dropped")
+	l.Warning("bad password", "attempt", 3)
+
+	if len(target.lines) != 1 {
+		t.Fatalf("expected 1 line, got %v", target.lines)
+	}
+	if target.lines[0] != "warning [Auth] bad password user=7 attempt=3" {
+		t.Fatalf("unexpected line %q", target.lines[0])
+	}
+}
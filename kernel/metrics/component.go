@@ -0,0 +1,267 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+// APP_COMPONENT_KEY is the key Component registers itself under - see
+// SetAppComponent/AppComponent.
+const APP_COMPONENT_KEY = "lxgo_metrics"
+
+// DefaultRoute is the route metrics are served on unless Config.Route says otherwise.
+const DefaultRoute = "/metrics"
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Config
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponentConfig */
+
+// Config is Component's app-component configuration.
+type Config struct {
+	*lxApp.ComponentConfig
+	// Route is the route the metrics are served on, DefaultRoute if unset.
+	Route string
+	// Buckets are the request duration histogram's buckets, in seconds -
+	// DefaultBuckets if unset.
+	Buckets []float64
+}
+
+/** @constructor kernel.CAppComponentConfig */
+
+// NewConfig constructs a Config.
+func NewConfig() kernel.IAppComponentConfig {
+	return &Config{ComponentConfig: lxApp.NewComponentConfigStruct()}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Component
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponent */
+
+// Component records request counts and latencies for every request the
+// app's router serves, plus the pool stats of the app's DB connections,
+// and serves them (along with any metric app code registers on
+// Registry) on Config.Route - see SetAppComponent to register it on an app.
+type Component struct {
+	*lxApp.AppComponent
+	registry  *Registry
+	requests  *Counter
+	durations *Histogram
+}
+
+var _ kernel.IAppComponent = (*Component)(nil)
+
+// SetAppComponent registers a new Component on app under
+// APP_COMPONENT_KEY, configured from the config section named by configKey.
+func SetAppComponent(app kernel.IApp, configKey string) error {
+	if app.HasComponent(APP_COMPONENT_KEY) {
+		return fmt.Errorf("the application already has component: %s", APP_COMPONENT_KEY)
+	}
+
+	c := NewComponent()
+	if err := lxApp.InitComponent(c, app, configKey); err != nil {
+		return fmt.Errorf("can not init metrics component: %s", err)
+	}
+
+	app.SetComponent(APP_COMPONENT_KEY, c)
+	return nil
+}
+
+// AppComponent returns the Component registered on app under APP_COMPONENT_KEY.
+func AppComponent(app kernel.IApp) (*Component, error) {
+	c := app.Component(APP_COMPONENT_KEY)
+	if c == nil {
+		return nil, fmt.Errorf("application component '%s' not found", APP_COMPONENT_KEY)
+	}
+
+	m, ok := c.(*Component)
+	if !ok {
+		return nil, fmt.Errorf("application component '%s' is not '*metrics.Component'", APP_COMPONENT_KEY)
+	}
+
+	return m, nil
+}
+
+/** @constructor */
+
+// NewComponent constructs a Component with an empty Registry.
+func NewComponent() *Component {
+	return &Component{
+		AppComponent: lxApp.NewAppComponent(),
+		registry:     NewRegistry(),
+	}
+}
+
+// Name returns the component's name - see kernel.IAppComponent.
+func (c *Component) Name() string {
+	return "Metrics"
+}
+
+// LogCategory returns the category the component's log methods write under.
+func (c *Component) LogCategory() string {
+	return "Metrics"
+}
+
+// CConfig returns Config's constructor - see kernel.IAppComponent.
+func (c *Component) CConfig() kernel.CAppComponentConfig {
+	return NewConfig
+}
+
+// Config returns the component's bound Config.
+func (c *Component) Config() *Config {
+	return (c.GetConfig()).(*Config)
+}
+
+// Registry returns the component's registry - register custom metrics on it.
+func (c *Component) Registry() *Registry {
+	return c.registry
+}
+
+// AfterInit registers the HTTP (and, if the app has DB connections, DB)
+// metrics and the route serving them - see kernel.IAppComponent.
+func (c *Component) AfterInit() {
+	var err error
+	c.requests, err = c.registry.NewCounter(
+		"lxgo_http_requests_total",
+		"Total HTTP requests handled, by route, method and status code.",
+		"route", "method", "status",
+	)
+	if err != nil {
+		c.LogError("Can not register HTTP metrics: %v", err)
+		return
+	}
+	c.durations, err = c.registry.NewHistogram(
+		"lxgo_http_request_duration_seconds",
+		"HTTP request handling latency in seconds, by route and method.",
+		c.Config().Buckets,
+		"route", "method",
+	)
+	if err != nil {
+		c.LogError("Can not register HTTP metrics: %v", err)
+		return
+	}
+	c.App().Events().Subscribe(kernel.EVENT_APP_AFTER_HANDLE_REQUEST, c.observeRequest)
+
+	if c.App().Connection() != nil || len(c.App().ConnectionNames()) > 0 {
+		if err := c.registerDBMetrics(); err != nil {
+			c.LogError("Can not register DB metrics: %v", err)
+		}
+	}
+
+	route := c.Config().Route
+	if route == "" {
+		route = DefaultRoute
+	}
+	c.App().Router().RegisterResource(route, http.MethodGet, func() kernel.IHttpResource {
+		return &metricsResource{Resource: lxHttp.NewResource(), registry: c.registry}
+	})
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+func (c *Component) observeRequest(e kernel.IEvent) {
+	p := e.Payload()
+	route, _ := p.Get("route").(string)
+	method, _ := p.Get("method").(string)
+	status, _ := p.Get("status").(int)
+	duration, _ := p.Get("duration").(time.Duration)
+
+	c.requests.Inc(route, method, strconv.Itoa(status))
+	c.durations.Observe(duration.Seconds(), route, method)
+}
+
+// registerDBMetrics registers gauges/counters reading the app connections'
+// sql.DBStats at collection time, labeled by connection name (empty for the
+// main one) - all zero until a connection is connected.
+func (c *Component) registerDBMetrics() error {
+	collect := func(f func(s sql.DBStats) float64) func(observe ObserveFunc) {
+		return func(observe ObserveFunc) {
+			c.eachDBStats(func(name string, s sql.DBStats) { observe(f(s), name) })
+		}
+	}
+
+	gauges := []struct {
+		name, help string
+		f          func(s sql.DBStats) float64
+	}{
+		{"lxgo_db_max_open_connections", "Maximum number of open DB connections.",
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"lxgo_db_open_connections", "Number of established DB connections, in use and idle.",
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"lxgo_db_in_use_connections", "Number of DB connections currently in use.",
+			func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"lxgo_db_idle_connections", "Number of idle DB connections.",
+			func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	}
+	for _, g := range gauges {
+		if err := c.registry.NewGaugeFuncVec(g.name, g.help, collect(g.f), "connection"); err != nil {
+			return err
+		}
+	}
+
+	counters := []struct {
+		name, help string
+		f          func(s sql.DBStats) float64
+	}{
+		{"lxgo_db_wait_count_total", "Total number of DB connections waited for.",
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"lxgo_db_wait_duration_seconds_total", "Total time blocked waiting for a new DB connection.",
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"lxgo_db_max_idle_closed_total", "Total number of DB connections closed due to SetMaxIdleConns.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"lxgo_db_max_lifetime_closed_total", "Total number of DB connections closed due to SetConnMaxLifetime.",
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+	for _, m := range counters {
+		if err := c.registry.NewCounterFuncVec(m.name, m.help, collect(m.f), "connection"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachDBStats calls f with the name and pool stats of each of the app's DB
+// connections - the main one first, under an empty name.
+func (c *Component) eachDBStats(f func(name string, s sql.DBStats)) {
+	stats := func(conn kernel.IConnection) sql.DBStats {
+		if conn == nil || conn.DB() == nil {
+			return sql.DBStats{}
+		}
+		return conn.DB().Stats()
+	}
+	if conn := c.App().Connection(); conn != nil {
+		f("", stats(conn))
+	}
+	for _, name := range c.App().ConnectionNames() {
+		f(name, stats(c.App().NamedConnection(name)))
+	}
+}
+
+/** @interface kernel.IHttpResource */
+
+// metricsResource serves a registry in the Prometheus text format.
+type metricsResource struct {
+	*lxHttp.Resource
+	registry *Registry
+}
+
+func (r *metricsResource) Run() kernel.IHttpResponse {
+	w := r.ResponseWriter()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := r.registry.WriteTo(w); err != nil {
+		r.LogError(fmt.Sprintf("Can not write metrics: %v", err), "Metrics")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+	"github.com/epicoon/lxgo/kernel/metrics"
+)
+
+func TestComponent_ServesRequestAndDBMetrics(t *testing.T) {
+	a, err := apptest.New(kernel.Dict{
+		"Database": kernel.Dict{"Connections": kernel.Dict{
+			"replica": kernel.Dict{"Driver": "sqlite", "DBName": ":memory:", "MaxOpenConns": 3},
+		}},
+		"Components": kernel.Dict{"Metrics": kernel.Dict{"Route": "/internal/metrics"}},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	m := apptest.SetComponent(t, a, "Components.Metrics", metrics.SetAppComponent, metrics.AppComponent)
+	a.Router().RegisterResource("/users/{id:int}", "GET", func() kernel.IHttpResource {
+		return lxHttp.NewResource()
+	})
+
+	custom, err := m.Registry().NewCounter("app_signups_total", "Signups.")
+	if err != nil {
+		t.Fatalf("NewCounter: %v", err)
+	}
+	custom.Inc()
+
+	if err := a.NamedConnection("replica").Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer a.Final()
+
+	srv := apptest.Server(a)
+	defer srv.Close()
+
+	for _, path := range []string{"/users/1", "/users/2", "/nowhere"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := http.Get(srv.URL + "/internal/metrics")
+	if err != nil {
+		t.Fatalf("GET metrics: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	for _, want := range []string{
+		`lxgo_http_requests_total{route="/users/{id:int}",method="GET",status="200"} 2`,
+		`lxgo_http_requests_total{route="",method="GET",status="404"} 1`,
+		`lxgo_http_request_duration_seconds_count{route="/users/{id:int}",method="GET"} 2`,
+		`lxgo_db_open_connections{connection=""} 0`,
+		`lxgo_db_max_open_connections{connection="replica"} 3`,
+		"app_signups_total 1",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,462 @@
+// Package metrics provides Prometheus-compatible application metrics - a
+// Registry of counters, gauges and histograms (optionally labeled) written
+// out in the Prometheus text exposition format, and Component, an app
+// component recording HTTP request metrics and DB pool stats and serving
+// its registry on a "/metrics" route. See "Metrics" in the kernel README.
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram buckets used when none are given - in
+// seconds, suited to request latencies.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds a set of metrics and writes them out in the Prometheus
+// text format - see WriteTo. It's safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+/** @constructor */
+
+// NewRegistry constructs an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+// NewCounter registers a counter - a value that only goes up - with the
+// given label names, failing if name is invalid or already registered.
+func (r *Registry) NewCounter(name, help string, labels ...string) (*Counter, error) {
+	c := &Counter{vec: newVec(name, help, labels)}
+	if err := r.register(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NewGauge registers a gauge - a value that goes up and down - with the
+// given label names, failing if name is invalid or already registered.
+func (r *Registry) NewGauge(name, help string, labels ...string) (*Gauge, error) {
+	g := &Gauge{vec: newVec(name, help, labels)}
+	if err := r.register(g); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// NewHistogram registers a histogram counting observed values into buckets
+// (upper bounds, DefaultBuckets if empty) with the given label names,
+// failing if name is invalid or already registered.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) (*Histogram, error) {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{vec: newVec(name, help, labels), buckets: buckets}
+	if err := r.register(h); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// NewGaugeFunc registers an unlabeled gauge whose value f returns at
+// collection time - e.g. the size of some in-memory registry.
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) error {
+	return r.NewGaugeFuncVec(name, help, func(observe ObserveFunc) { observe(f()) })
+}
+
+// NewCounterFunc registers an unlabeled counter whose value f returns at
+// collection time - e.g. a running total kept elsewhere.
+func (r *Registry) NewCounterFunc(name, help string, f func() float64) error {
+	return r.NewCounterFuncVec(name, help, func(observe ObserveFunc) { observe(f()) })
+}
+
+// ObserveFunc reports one series' value to a func metric - see
+// NewGaugeFuncVec.
+type ObserveFunc func(v float64, labelValues ...string)
+
+// NewGaugeFuncVec registers a gauge with the given label names whose
+// series f reports at collection time, calling observe once per set of
+// label values - e.g. a pool stat per DB connection.
+func (r *Registry) NewGaugeFuncVec(name, help string, f func(observe ObserveFunc), labels ...string) error {
+	return r.register(&funcMetric{vec: newVec(name, help, labels), tp: "gauge", f: f})
+}
+
+// NewCounterFuncVec is NewGaugeFuncVec for a counter.
+func (r *Registry) NewCounterFuncVec(name, help string, f func(observe ObserveFunc), labels ...string) error {
+	return r.register(&funcMetric{vec: newVec(name, help, labels), tp: "counter", f: f})
+}
+
+// Unregister removes the metric registered under name, reporting whether
+// there was one.
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, exists := r.metrics[name]
+	delete(r.metrics, name)
+	return exists
+}
+
+// WriteTo writes every registered metric to w in the Prometheus text
+// exposition format, sorted by name.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]metric, len(names))
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	var b bytes.Buffer
+	for _, m := range metrics {
+		m.write(&b)
+	}
+	return b.WriteTo(w)
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Counter
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// Counter is a value that only goes up, one per distinct set of label
+// values. Passing a number of label values other than the number of label
+// names the counter was registered with is a programming error and panics.
+type Counter struct {
+	*vec
+}
+
+// Inc adds 1 to the counter for labelValues.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (negative values are ignored) to the counter for labelValues.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Value returns the counter's current value for labelValues.
+func (c *Counter) Value(labelValues ...string) float64 {
+	return c.value(labelValues)
+}
+
+func (c *Counter) write(b *bytes.Buffer) {
+	c.writeSimple(b, "counter")
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Gauge
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// Gauge is a value that goes up and down, one per distinct set of label
+// values - see Counter for the label values contract.
+type Gauge struct {
+	*vec
+}
+
+// Set sets the gauge for labelValues to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.update(labelValues, func(s *series) { s.value = v })
+}
+
+// Add adds v (which may be negative) to the gauge for labelValues.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Inc adds 1 to the gauge for labelValues.
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec subtracts 1 from the gauge for labelValues.
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Value returns the gauge's current value for labelValues.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	return g.value(labelValues)
+}
+
+func (g *Gauge) write(b *bytes.Buffer) {
+	g.writeSimple(b, "gauge")
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Histogram
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// Histogram counts observed values into buckets, one set of buckets per
+// distinct set of label values - see Counter for the label values contract.
+type Histogram struct {
+	*vec
+	buckets []float64
+}
+
+// Observe records v for labelValues.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.update(labelValues, func(s *series) {
+		if s.counts == nil {
+			s.counts = make([]uint64, len(h.buckets))
+		}
+		for i, upper := range h.buckets {
+			if v <= upper {
+				s.counts[i]++
+			}
+		}
+		s.count++
+		s.value += v
+	})
+}
+
+// Count returns the number of values observed for labelValues.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[h.key(labelValues)]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(b *bytes.Buffer) {
+	h.writeHeader(b, "histogram")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.sortedSeries() {
+		for i, upper := range h.buckets {
+			var n uint64
+			if s.counts != nil {
+				n = s.counts[i]
+			}
+			h.writeSample(b, "_bucket", s.labelValues, "le", formatFloat(upper), float64(n))
+		}
+		h.writeSample(b, "_bucket", s.labelValues, "le", "+Inf", float64(s.count))
+		h.writeSample(b, "_sum", s.labelValues, "", "", s.value)
+		h.writeSample(b, "_count", s.labelValues, "", "", float64(s.count))
+	}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// metric is anything a Registry can hold.
+type metric interface {
+	metricName() string
+	validate() error
+	write(b *bytes.Buffer)
+}
+
+func (r *Registry) register(m metric) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.metrics[m.metricName()]; exists {
+		return fmt.Errorf("metric '%s' is already registered", m.metricName())
+	}
+	r.metrics[m.metricName()] = m
+	return nil
+}
+
+// series is a metric's state for one distinct set of label values - value
+// doubles as a histogram's sum.
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	count       uint64
+}
+
+// vec is the part common to every metric: name, help, label names and a
+// series per distinct set of label values.
+type vec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	labels []string
+	series map[string]*series
+}
+
+func newVec(name, help string, labels []string) *vec {
+	return &vec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		series: make(map[string]*series),
+	}
+}
+
+func (v *vec) metricName() string {
+	return v.name
+}
+
+func (v *vec) validate() error {
+	if !metricNameRe.MatchString(v.name) {
+		return fmt.Errorf("invalid metric name '%s'", v.name)
+	}
+	for _, l := range v.labels {
+		if !labelNameRe.MatchString(l) || strings.HasPrefix(l, "__") || l == "le" {
+			return fmt.Errorf("metric '%s': invalid label name '%s'", v.name, l)
+		}
+	}
+	return nil
+}
+
+func (v *vec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric '%s': got %d label values, want %d (%s)",
+			v.name, len(labelValues), len(v.labels), strings.Join(v.labels, ", ")))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (v *vec) update(labelValues []string, f func(s *series)) {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	f(s)
+}
+
+func (v *vec) value(labelValues []string) float64 {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// sortedSeries returns the series ordered by label values - callers hold mu.
+func (v *vec) sortedSeries() []*series {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	res := make([]*series, len(keys))
+	for i, k := range keys {
+		res[i] = v.series[k]
+	}
+	return res
+}
+
+func (v *vec) writeSimple(b *bytes.Buffer, tp string) {
+	v.writeHeader(b, tp)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, s := range v.sortedSeries() {
+		v.writeSample(b, "", s.labelValues, "", "", s.value)
+	}
+}
+
+func (v *vec) writeHeader(b *bytes.Buffer, tp string) {
+	if v.help != "" {
+		fmt.Fprintf(b, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	}
+	fmt.Fprintf(b, "# TYPE %s %s\n", v.name, tp)
+}
+
+// writeSample writes one sample line - extraLabel/extraValue (if set) is
+// appended after the metric's own labels, e.g. a histogram bucket's "le".
+func (v *vec) writeSample(b *bytes.Buffer, suffix string, labelValues []string, extraLabel, extraValue string, value float64) {
+	b.WriteString(v.name)
+	b.WriteString(suffix)
+	if len(labelValues) > 0 || extraLabel != "" {
+		b.WriteByte('{')
+		for i, l := range v.labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, `%s="%s"`, l, escapeLabelValue(labelValues[i]))
+		}
+		if extraLabel != "" {
+			if len(v.labels) > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, `%s="%s"`, extraLabel, extraValue)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+}
+
+// funcMetric is a gauge or counter whose series f reports at collection
+// time.
+type funcMetric struct {
+	*vec
+	tp string
+	f  func(observe ObserveFunc)
+}
+
+func (m *funcMetric) write(b *bytes.Buffer) {
+	type sample struct {
+		key         string
+		labelValues []string
+		value       float64
+	}
+	var samples []sample
+	m.f(func(v float64, labelValues ...string) {
+		samples = append(samples, sample{m.key(labelValues), labelValues, v})
+	})
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].key < samples[j].key })
+
+	m.writeHeader(b, m.tp)
+	for _, s := range samples {
+		m.writeSample(b, "", s.labelValues, "", "", s.value)
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
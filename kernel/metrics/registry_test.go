@@ -0,0 +1,114 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel/metrics"
+)
+
+func scrape(t *testing.T, r *metrics.Registry) string {
+	t.Helper()
+	var out strings.Builder
+	if _, err := r.WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	return out.String()
+}
+
+func TestRegistry_TextFormat(t *testing.T) {
+	r := metrics.NewRegistry()
+	c, err := r.NewCounter("jobs_total", "Jobs processed.", "queue", "result")
+	if err != nil {
+		t.Fatalf("NewCounter: %v", err)
+	}
+	g, err := r.NewGauge("queue_depth", "Jobs waiting.\nPer queue.", "queue")
+	if err != nil {
+		t.Fatalf("NewGauge: %v", err)
+	}
+	h, err := r.NewHistogram("job_seconds", "", []float64{1, 0.1}, "queue")
+	if err != nil {
+		t.Fatalf("NewHistogram: %v", err)
+	}
+	if err := r.NewGaugeFunc("workers", "Busy workers.", func() float64 { return 3 }); err != nil {
+		t.Fatalf("NewGaugeFunc: %v", err)
+	}
+	if err := r.NewCounterFuncVec("pool_waits_total", "Pool waits.", func(observe metrics.ObserveFunc) {
+		observe(7, "replica")
+		observe(2, "")
+	}, "pool"); err != nil {
+		t.Fatalf("NewCounterFuncVec: %v", err)
+	}
+
+	c.Inc("mail", "ok")
+	c.Add(2, "mail", "ok")
+	c.Add(-5, "mail", "ok")
+	c.Inc(`say "hi"`, "failed")
+	g.Set(10, "mail")
+	g.Dec("mail")
+	h.Observe(0.05, "mail")
+	h.Observe(0.5, "mail")
+	h.Observe(5, "mail")
+
+	// No HELP line for job_seconds - its help is empty.
+	want := `# TYPE job_seconds histogram
+job_seconds_bucket{queue="mail",le="0.1"} 1
+job_seconds_bucket{queue="mail",le="1"} 2
+job_seconds_bucket{queue="mail",le="+Inf"} 3
+job_seconds_sum{queue="mail"} 5.55
+job_seconds_count{queue="mail"} 3
+# HELP jobs_total Jobs processed.
+# TYPE jobs_total counter
+jobs_total{queue="mail",result="ok"} 3
+jobs_total{queue="say \"hi\"",result="failed"} 1
+# HELP pool_waits_total Pool waits.
+# TYPE pool_waits_total counter
+pool_waits_total{pool=""} 2
+pool_waits_total{pool="replica"} 7
+# HELP queue_depth Jobs waiting.\nPer queue.
+# TYPE queue_depth gauge
+queue_depth{queue="mail"} 9
+# HELP workers Busy workers.
+# TYPE workers gauge
+workers 3
+`
+	if got := scrape(t, r); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if c.Value("mail", "ok") != 3 || g.Value("mail") != 9 || h.Count("mail") != 3 {
+		t.Fatal("unexpected values read back")
+	}
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	r := metrics.NewRegistry()
+	if _, err := r.NewCounter("requests_total", ""); err != nil {
+		t.Fatalf("NewCounter: %v", err)
+	}
+	if _, err := r.NewGauge("requests_total", ""); err == nil {
+		t.Error("expected an error for a duplicate name")
+	}
+	if _, err := r.NewCounter("bad-name", ""); err == nil {
+		t.Error("expected an error for an invalid metric name")
+	}
+	if _, err := r.NewHistogram("latency", "", nil, "le"); err == nil {
+		t.Error("expected an error for the reserved 'le' label")
+	}
+
+	if !r.Unregister("requests_total") {
+		t.Error("expected Unregister to report the metric was there")
+	}
+	if _, err := r.NewGauge("requests_total", ""); err != nil {
+		t.Errorf("expected the name to be free again after Unregister: %v", err)
+	}
+}
+
+func TestCounter_WrongLabelCountPanics(t *testing.T) {
+	c, _ := metrics.NewRegistry().NewCounter("hits_total", "", "route")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a wrong number of label values")
+		}
+	}()
+	c.Inc()
+}
@@ -0,0 +1,276 @@
+// Package openapi generates an OpenAPI 3.1 document from an application's
+// router: every registered resource becomes an operation, its request form
+// becomes query parameters (GET/HEAD/DELETE) or a JSON request body
+// (multipart, if it has file uploads), its response and fail forms become
+// the success and "default" responses, and a pattern route's params
+// ("/users/{id:int}") become path parameters.
+// Forms are reflected into JSON Schema including nested forms, structs,
+// slices and maps, with descriptions and required fields taken from
+// IForm.Config. See Generate, and Register to serve the document along
+// with a bundled viewer.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/epicoon/lxgo/kernel"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the OpenAPI version of the generated documents.
+const Version = "3.1.0"
+
+// Info is the document's "info" object.
+type Info struct {
+	Title       string `json:"title" yaml:"title"`
+	Version     string `json:"version" yaml:"version"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Document is an OpenAPI document - only the parts Generate fills in.
+type Document struct {
+	OpenAPI string               `json:"openapi" yaml:"openapi"`
+	Info    Info                 `json:"info" yaml:"info"`
+	Paths   map[string]*PathItem `json:"paths" yaml:"paths"`
+}
+
+// PathItem holds the operations of a single path, one per HTTP method.
+type PathItem struct {
+	Get    *Operation `json:"get,omitempty" yaml:"get,omitempty"`
+	Put    *Operation `json:"put,omitempty" yaml:"put,omitempty"`
+	Post   *Operation `json:"post,omitempty" yaml:"post,omitempty"`
+	Delete *Operation `json:"delete,omitempty" yaml:"delete,omitempty"`
+	Head   *Operation `json:"head,omitempty" yaml:"head,omitempty"`
+	Patch  *Operation `json:"patch,omitempty" yaml:"patch,omitempty"`
+}
+
+// Operation is a single path + method.
+type Operation struct {
+	Parameters  []*Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+}
+
+// Parameter is a path or query parameter.
+type Parameter struct {
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *Schema `json:"schema" yaml:"schema"`
+}
+
+// RequestBody is an operation's request body.
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty" yaml:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content" yaml:"content"`
+}
+
+// Response is one of an operation's responses.
+type Response struct {
+	Description string                `json:"description" yaml:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// MediaType is the schema of a request/response body of one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema" yaml:"schema"`
+}
+
+/** @constructor */
+
+// Generate builds a document describing every resource registered on
+// router - a resource registered for any method ("ALL") is documented under
+// each of GET/POST/PUT/PATCH/DELETE not registered explicitly.
+func Generate(router kernel.IRouter, info Info) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+
+	for route, list := range router.Resources() {
+		path, pathParams := convertRoute(route)
+		item := &PathItem{}
+
+		for method, cResource := range list {
+			if method == "ALL" {
+				continue
+			}
+			setOperation(item, method, buildOperation(cResource(), method, pathParams))
+		}
+		if cResource, ok := list["ALL"]; ok {
+			for _, method := range anyMethods {
+				if operation(item, method) == nil {
+					setOperation(item, method, buildOperation(cResource(), method, pathParams))
+				}
+			}
+		}
+
+		doc.Paths[path] = item
+	}
+
+	return doc
+}
+
+// JSON returns the document as indented JSON.
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML returns the document as YAML.
+func (d *Document) YAML() ([]byte, error) {
+	return yaml.Marshal(d)
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// anyMethods are the methods an "ALL" resource is documented under.
+var anyMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// routeParamRe matches a pattern route's param segment - see the kernel
+// README's "Route patterns": "{name}", "{name:type}" or "{name...}".
+var routeParamRe = regexp.MustCompile(`^\{([^{}:.]+)(?::([a-z]+)|(\.\.\.))?\}$`)
+
+// routeParamSchemas maps route param types to the schema of their values.
+var routeParamSchemas = map[string]func() *Schema{
+	"int":   func() *Schema { return &Schema{Type: "integer"} },
+	"uint":  func() *Schema { return &Schema{Type: "integer", Minimum: ptr(0.0)} },
+	"alpha": func() *Schema { return &Schema{Type: "string", Pattern: "^[a-zA-Z]+$"} },
+	"uuid":  func() *Schema { return &Schema{Type: "string", Format: "uuid"} },
+}
+
+// convertRoute turns a route into an OpenAPI path ("/users/{id:int}" ->
+// "/users/{id}") and its path parameters, in order.
+func convertRoute(route string) (string, []*Parameter) {
+	var params []*Parameter
+	segments := strings.Split(route, "/")
+	for i, seg := range segments {
+		m := routeParamRe.FindStringSubmatch(seg)
+		if m == nil {
+			continue
+		}
+		name, tp := m[1], m[2]
+		schema := &Schema{Type: "string"}
+		if f, ok := routeParamSchemas[tp]; ok {
+			schema = f()
+		}
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func buildOperation(res kernel.IHttpResource, method string, pathParams []*Parameter) *Operation {
+	op := &Operation{Responses: make(map[string]*Response)}
+
+	// Copied, as each operation may fill in descriptions from its own form.
+	for _, p := range pathParams {
+		pc := *p
+		op.Parameters = append(op.Parameters, &pc)
+	}
+
+	if cForm := res.CRequestForm(); cForm != nil {
+		schema := FormSchema(cForm())
+		for _, p := range op.Parameters {
+			if prop, ok := schema.Properties[p.Name]; ok {
+				p.Description = prop.Description
+				schema.removeProperty(p.Name)
+			}
+		}
+
+		if len(schema.Properties) > 0 {
+			switch method {
+			case http.MethodGet, http.MethodHead, http.MethodDelete:
+				names := make([]string, 0, len(schema.Properties))
+				for name := range schema.Properties {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					prop := schema.Properties[name]
+					op.Parameters = append(op.Parameters, &Parameter{
+						Name:        name,
+						In:          "query",
+						Description: prop.Description,
+						Required:    schema.isRequired(name),
+						Schema:      prop,
+					})
+				}
+			default:
+				content := jsonContent(schema)
+				if schema.hasFiles() {
+					content = map[string]*MediaType{"multipart/form-data": {Schema: schema}}
+				}
+				op.RequestBody = &RequestBody{
+					Required: len(schema.Required) > 0,
+					Content:  content,
+				}
+			}
+		}
+	}
+
+	ok := &Response{Description: "Successful response"}
+	if cForm := res.CResponseForm(); cForm != nil {
+		ok.Content = jsonContent(FormSchema(cForm()))
+	}
+	op.Responses["200"] = ok
+
+	if cForm := res.CFailForm(); cForm != nil {
+		op.Responses["default"] = &Response{
+			Description: "Failed response",
+			Content:     jsonContent(FormSchema(cForm())),
+		}
+	}
+
+	return op
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+func operation(item *PathItem, method string) *Operation {
+	switch method {
+	case http.MethodGet:
+		return item.Get
+	case http.MethodPut:
+		return item.Put
+	case http.MethodPost:
+		return item.Post
+	case http.MethodDelete:
+		return item.Delete
+	case http.MethodHead:
+		return item.Head
+	case http.MethodPatch:
+		return item.Patch
+	}
+	return nil
+}
+
+func setOperation(item *PathItem, method string, op *Operation) {
+	switch method {
+	case http.MethodGet:
+		item.Get = op
+	case http.MethodPut:
+		item.Put = op
+	case http.MethodPost:
+		item.Post = op
+	case http.MethodDelete:
+		item.Delete = op
+	case http.MethodHead:
+		item.Head = op
+	case http.MethodPatch:
+		item.Patch = op
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
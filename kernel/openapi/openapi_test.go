@@ -0,0 +1,326 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+	"github.com/epicoon/lxgo/kernel/openapi"
+)
+
+type addressForm struct {
+	*lxHttp.Form
+	City string `json:"city"`
+	Zip  string `json:"zip" validate:"regex=^[0-9]{5}$"`
+}
+
+func (f *addressForm) Config() kernel.FormConfig {
+	return kernel.FormConfig{
+		"city": {Description: "City name", Required: true},
+	}
+}
+
+type tag struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+	Next  *tag    `json:"next"`
+}
+
+type userForm struct {
+	*lxHttp.Form
+	ID       int               `json:"id"`
+	Name     string            `json:"name" validate:"min=2,max=40"`
+	Age      uint              `json:"age"`
+	Born     time.Time         `json:"born"`
+	Tags     []tag             `json:"tags"`
+	Meta     map[string]string `json:"meta"`
+	Secret   string            `json:"-"`
+	Address  *addressForm      `json:"address"`
+	internal string
+}
+
+func (f *userForm) Config() kernel.FormConfig {
+	return kernel.FormConfig{
+		"id":      {Description: "User ID", Required: true},
+		"name":    {Description: "Display name", Required: true},
+		"age":     {Rules: "max=150"},
+		"address": {Description: "Postal address"},
+	}
+}
+
+func newUserForm() kernel.IForm {
+	return lxHttp.PrepareForm(&userForm{
+		Form:    lxHttp.NewForm(),
+		Address: lxHttp.PrepareForm(&addressForm{Form: lxHttp.NewForm()}).(*addressForm),
+	})
+}
+
+type errorForm struct {
+	*lxHttp.Form
+	Error string `json:"error"`
+}
+
+func (f *errorForm) Config() kernel.FormConfig {
+	return kernel.FormConfig{"error": {Description: "What went wrong"}}
+}
+
+func newRouter() kernel.IRouter {
+	router := lxHttp.NewRouter(nil)
+	router.RegisterResource("/users/{id:int}", "PUT", func() kernel.IHttpResource {
+		return lxHttp.NewResource(kernel.HttpResourceConfig{
+			CRequestForm:  newUserForm,
+			CResponseForm: newUserForm,
+			CFailForm:     func() kernel.IForm { return &errorForm{Form: lxHttp.NewForm()} },
+		})
+	})
+	router.RegisterResource("/users", "GET", func() kernel.IHttpResource {
+		return lxHttp.NewResource(kernel.HttpResourceConfig{CRequestForm: newUserForm})
+	})
+	router.RegisterResource("/ping", "", func() kernel.IHttpResource {
+		return lxHttp.NewResource()
+	})
+	router.RegisterResource("/ping", "POST", func() kernel.IHttpResource {
+		return lxHttp.NewResource(kernel.HttpResourceConfig{CResponseForm: newUserForm})
+	})
+	return router
+}
+
+func TestGenerate(t *testing.T) {
+	doc := openapi.Generate(newRouter(), openapi.Info{Title: "Test", Version: "1.0"})
+
+	if doc.OpenAPI != "3.1.0" {
+		t.Fatalf("unexpected version %q", doc.OpenAPI)
+	}
+
+	t.Run("path params and request body", func(t *testing.T) {
+		item, ok := doc.Paths["/users/{id}"]
+		if !ok || item.Put == nil {
+			t.Fatalf("expected PUT /users/{id}, got paths %v", keys(doc.Paths))
+		}
+		op := item.Put
+		if len(op.Parameters) != 1 {
+			t.Fatalf("expected only the path param, got %+v", op.Parameters)
+		}
+		p := op.Parameters[0]
+		if p.Name != "id" || p.In != "path" || !p.Required || p.Schema.Type != "integer" || p.Description != "User ID" {
+			t.Fatalf("unexpected path param %+v", p)
+		}
+
+		body := op.RequestBody.Content["application/json"].Schema
+		if _, ok := body.Properties["id"]; ok {
+			t.Error("a path param must not also be in the request body")
+		}
+		if !reflect.DeepEqual(body.Required, []string{"name"}) {
+			t.Errorf("got required %v, want [name]", body.Required)
+		}
+		for _, skipped := range []string{"Secret", "-", "internal"} {
+			if _, ok := body.Properties[skipped]; ok {
+				t.Errorf("unexpected property %q", skipped)
+			}
+		}
+
+		checkSchema(t, body.Properties["age"], "integer", "")
+		if body.Properties["age"].Minimum == nil {
+			t.Error("expected a minimum for an unsigned field")
+		}
+		if age := body.Properties["age"]; *age.Minimum != 0 || age.Maximum == nil || *age.Maximum != 150 {
+			t.Errorf("expected the rules' bounds to keep the unsigned minimum, got %+v", age)
+		}
+		if name := body.Properties["name"]; name.MinLength == nil || *name.MinLength != 2 || *name.MaxLength != 40 {
+			t.Errorf("expected length bounds from the rules, got %+v", name)
+		}
+		checkSchema(t, body.Properties["born"], "string", "date-time")
+		checkSchema(t, body.Properties["meta"], "object", "")
+		checkSchema(t, body.Properties["meta"].AdditionalProperties, "string", "")
+
+		tags := body.Properties["tags"]
+		checkSchema(t, tags, "array", "")
+		checkSchema(t, tags.Items.Properties["score"], "number", "double")
+		// The recursive field stops at a plain object.
+		next := tags.Items.Properties["next"]
+		if next.Type != "object" || next.Properties != nil {
+			t.Errorf("unexpected recursive schema %+v", next)
+		}
+
+		addr := body.Properties["address"]
+		if addr.Description != "Postal address" || addr.Properties["city"].Description != "City name" {
+			t.Errorf("nested form not reflected: %+v", addr)
+		}
+		if addr.Properties["zip"].Pattern != "^[0-9]{5}$" {
+			t.Errorf("expected the nested form's rules in its schema, got %+v", addr.Properties["zip"])
+		}
+		if !reflect.DeepEqual(addr.Required, []string{"city"}) {
+			t.Errorf("got nested required %v, want [city]", addr.Required)
+		}
+
+		if op.Responses["200"].Content == nil {
+			t.Error("expected the response form in the 200 response")
+		}
+		fail := op.Responses["default"]
+		if fail == nil || fail.Content["application/json"].Schema.Properties["error"].Description != "What went wrong" {
+			t.Errorf("expected the fail form in the default response, got %+v", fail)
+		}
+	})
+
+	t.Run("query params", func(t *testing.T) {
+		op := doc.Paths["/users"].Get
+		if op == nil || op.RequestBody != nil {
+			t.Fatalf("expected a GET without a body, got %+v", op)
+		}
+		var names []string
+		for _, p := range op.Parameters {
+			if p.In != "query" {
+				t.Errorf("unexpected param location %q", p.In)
+			}
+			names = append(names, p.Name)
+			if p.Name == "id" && !p.Required {
+				t.Error("expected 'id' to be required")
+			}
+		}
+		want := []string{"address", "age", "born", "id", "meta", "name", "tags"}
+		if !reflect.DeepEqual(names, want) {
+			t.Errorf("got query params %v, want %v", names, want)
+		}
+	})
+
+	t.Run("any method", func(t *testing.T) {
+		item := doc.Paths["/ping"]
+		if item.Get == nil || item.Put == nil || item.Patch == nil || item.Delete == nil {
+			t.Fatalf("expected the any-method resource under every method, got %+v", item)
+		}
+		if item.Post.Responses["200"].Content == nil {
+			t.Error("an explicit POST must win over the any-method resource")
+		}
+		if item.Head != nil {
+			t.Error("HEAD isn't documented for an any-method resource")
+		}
+	})
+
+	t.Run("encoding", func(t *testing.T) {
+		data, err := doc.JSON()
+		if err != nil {
+			t.Fatalf("JSON: %v", err)
+		}
+		var decoded map[string]any
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded["openapi"] != "3.1.0" {
+			t.Fatalf("unexpected JSON document: %v", err)
+		}
+
+		data, err = doc.YAML()
+		if err != nil {
+			t.Fatalf("YAML: %v", err)
+		}
+		if !strings.HasPrefix(string(data), "openapi: 3.1.0\n") || !strings.Contains(string(data), "/users/{id}:") {
+			t.Fatalf("unexpected YAML document:\n%s", data)
+		}
+	})
+}
+
+func TestRegister(t *testing.T) {
+	router := newRouter()
+	openapi.Register(router, "/docs", openapi.Info{Title: "Test API", Version: "2.0"})
+
+	get := func(path string) (*httptest.ResponseRecorder, string) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		body, _ := io.ReadAll(rec.Body)
+		return rec, string(body)
+	}
+
+	rec, body := get("/docs/openapi.json")
+	if rec.Code != 200 || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var doc openapi.Document
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		t.Fatalf("not a JSON document: %v", err)
+	}
+	if doc.Info.Title != "Test API" || doc.Paths["/users/{id}"] == nil {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+
+	rec, body = get("/docs/openapi.yaml")
+	if rec.Code != 200 || !strings.HasPrefix(body, "openapi: 3.1.0") {
+		t.Fatalf("unexpected YAML response %d: %s", rec.Code, body)
+	}
+
+	rec, body = get("/docs")
+	if rec.Code != 200 || !strings.Contains(body, "<title>Test API</title>") || !strings.Contains(body, "/docs/openapi.json") {
+		t.Fatalf("unexpected viewer response %d: %s", rec.Code, body)
+	}
+}
+
+func checkSchema(t *testing.T, s *openapi.Schema, tp, format string) {
+	t.Helper()
+	if s == nil {
+		t.Fatalf("missing schema, want %s", tp)
+	}
+	if s.Type != tp || s.Format != format {
+		t.Errorf("got %s/%s, want %s/%s", s.Type, s.Format, tp, format)
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	var res []string
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
+
+type uploadForm struct {
+	*lxHttp.Form
+	Title  string                 `json:"title"`
+	Avatar *lxHttp.UploadedFile   `json:"avatar"`
+	Docs   []*lxHttp.UploadedFile `json:"docs"`
+}
+
+func TestGenerate_Uploads(t *testing.T) {
+	router := lxHttp.NewRouter(nil)
+	router.RegisterResource("/upload", "POST", func() kernel.IHttpResource {
+		return lxHttp.NewResource(kernel.HttpResourceConfig{
+			CRequestForm: func() kernel.IForm { return &uploadForm{Form: lxHttp.NewForm()} },
+		})
+	})
+
+	body := openapi.Generate(router, openapi.Info{}).Paths["/upload"].Post.RequestBody
+	media, ok := body.Content["multipart/form-data"]
+	if !ok {
+		t.Fatalf("expected a multipart body, got %v", keys(body.Content))
+	}
+	checkSchema(t, media.Schema.Properties["avatar"], "string", "binary")
+	checkSchema(t, media.Schema.Properties["docs"].Items, "string", "binary")
+}
+
+func TestGenerate_Handle(t *testing.T) {
+	router := lxHttp.NewRouter(nil)
+	router.RegisterResource("/users/{id:int}", "PUT", lxHttp.Handle(
+		func(ctx kernel.IHandleContext, req *userForm) (*userForm, error) {
+			return req, nil
+		},
+	))
+
+	op := openapi.Generate(router, openapi.Info{}).Paths["/users/{id}"].Put
+	body := op.RequestBody.Content["application/json"].Schema
+	if !reflect.DeepEqual(body.Required, []string{"name"}) {
+		t.Errorf("unexpected required fields %v", body.Required)
+	}
+	if body.Properties["address"] == nil || body.Properties["address"].Properties["city"] == nil {
+		t.Errorf("expected the nested address form, got %v", body.Properties["address"])
+	}
+	if op.Responses["200"].Content["application/json"].Schema.Properties["id"] == nil {
+		t.Error("expected the response form")
+	}
+	fail := op.Responses["default"].Content["application/json"].Schema
+	for _, name := range []string{"success", "error_code", "error_message"} {
+		if fail.Properties[name] == nil {
+			t.Errorf("expected %s in the failed response", name)
+		}
+	}
+}
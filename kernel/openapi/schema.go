@@ -0,0 +1,314 @@
+package openapi
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/cast"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+	"github.com/epicoon/lxgo/kernel/validation"
+)
+
+// Schema is a JSON Schema object - only the keywords FormSchema produces.
+type Schema struct {
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Pattern              string             `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty" yaml:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty" yaml:"maxItems,omitempty"`
+	Enum                 []any              `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// FormSchema reflects form f into an object schema: a property per field
+// (named the way the form is filled - see cast.FieldName - embedded structs
+// flattened), described and marked required per f.Config() and
+// f.Required(), constrained per the field's validation rules (see the
+// validation package). A field holding a nested form is reflected the same way,
+// from the instance the form holds.
+func FormSchema(f kernel.IForm) *Schema {
+	v := reflect.ValueOf(f)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if v.Kind() != reflect.Struct {
+		return schema
+	}
+
+	conf := f.Config()
+	addFormFields(schema, v, conf, make(map[reflect.Type]bool))
+
+	for name := range schema.Properties {
+		if conf[name].Required || slices.Contains(f.Required(), name) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	slices.Sort(schema.Required)
+	return schema
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+var formType = reflect.TypeOf((*kernel.IForm)(nil)).Elem()
+var timeType = reflect.TypeOf(time.Time{})
+var uploadedFileType = reflect.TypeOf(lxHttp.UploadedFile{})
+
+func addFormFields(schema *Schema, v reflect.Value, conf kernel.FormConfig, seen map[reflect.Type]bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		if field.Anonymous {
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				addFormFields(schema, value, conf, seen)
+			}
+			continue
+		}
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		name := cast.FieldName(field)
+		var prop *Schema
+		if nested, ok := nestedForm(value); ok {
+			prop = FormSchema(nested)
+		} else {
+			prop = typeSchema(field.Type, seen)
+		}
+		if c, ok := conf[name]; ok && c.Description != "" {
+			prop.Description = c.Description
+		}
+		applyRules(prop, field, conf[name])
+		schema.Properties[name] = prop
+	}
+}
+
+// nestedForm returns the form a field holds, if it's a (non-nil) kernel.IForm.
+func nestedForm(value reflect.Value) (kernel.IForm, bool) {
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() || !value.Type().Implements(formType) {
+			return nil, false
+		}
+		f, ok := value.Interface().(kernel.IForm)
+		return f, ok
+	}
+	if !value.CanAddr() || !reflect.PointerTo(value.Type()).Implements(formType) {
+		return nil, false
+	}
+	f, ok := value.Addr().Interface().(kernel.IForm)
+	return f, ok
+}
+
+// typeSchema reflects a Go type into a schema - seen guards against
+// recursive struct types, which become a plain object past the first level.
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if t == uploadedFileType {
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16:
+		return &Schema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: ptr(0.0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: typeSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: typeSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		return structSchema(t, seen)
+	}
+	// interface{} and anything else - any value.
+	return &Schema{}
+}
+
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	schema := &Schema{Type: "object"}
+	if seen[t] {
+		return schema
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	schema.Properties = make(map[string]*Schema)
+	// A form type (e.g. the element of a []*ItemForm) gets its descriptions
+	// and required fields from a fresh instance's Config.
+	var conf kernel.FormConfig
+	if reflect.PointerTo(t).Implements(formType) {
+		conf = formConfig(reflect.New(t).Interface().(kernel.IForm))
+	}
+	addStructFields(schema, t, conf, seen)
+
+	for name := range schema.Properties {
+		if conf[name].Required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	slices.Sort(schema.Required)
+	if len(schema.Properties) == 0 {
+		schema.Properties = nil
+	}
+	return schema
+}
+
+func addStructFields(schema *Schema, t reflect.Type, conf kernel.FormConfig, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && !seen[ft] {
+				seen[ft] = true
+				addStructFields(schema, ft, conf, seen)
+				delete(seen, ft)
+			}
+			continue
+		}
+		if !field.IsExported() || field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		name := cast.FieldName(field)
+		prop := typeSchema(field.Type, seen)
+		if c, ok := conf[name]; ok && c.Description != "" {
+			prop.Description = c.Description
+		}
+		applyRules(prop, field, conf[name])
+		schema.Properties[name] = prop
+	}
+}
+
+// applyRules adds the keywords matching a field's validation rules to its
+// schema - an invalid rule set is left undocumented.
+func applyRules(prop *Schema, field reflect.StructField, conf kernel.FormFieldConfig) {
+	rules, err := validation.FieldRules(field, conf)
+	if err != nil || rules == nil {
+		return
+	}
+
+	switch prop.Type {
+	case "string":
+		applyStringRules(prop, rules)
+		prop.MinLength, prop.MaxLength = lengthBounds(rules)
+	case "integer", "number":
+		if rules.Min != nil {
+			prop.Minimum = rules.Min
+		}
+		prop.Maximum = rules.Max
+		for _, val := range rules.Enum {
+			if n, err := strconv.ParseFloat(val, 64); err == nil {
+				prop.Enum = append(prop.Enum, n)
+			}
+		}
+	case "array":
+		prop.MinItems, prop.MaxItems = lengthBounds(rules)
+		if prop.Items != nil && prop.Items.Type == "string" {
+			applyStringRules(prop.Items, rules)
+		}
+	}
+}
+
+func applyStringRules(s *Schema, rules *validation.Rules) {
+	if rules.Email {
+		s.Format = "email"
+	}
+	if rules.Regex != "" {
+		s.Pattern = rules.Regex
+	}
+	for _, val := range rules.Enum {
+		s.Enum = append(s.Enum, val)
+	}
+}
+
+// lengthBounds converts min/max/len rules into length bounds.
+func lengthBounds(rules *validation.Rules) (min, max *int) {
+	if rules.Len != nil {
+		return rules.Len, rules.Len
+	}
+	if rules.Min != nil {
+		min = ptr(int(math.Ceil(*rules.Min)))
+	}
+	if rules.Max != nil {
+		max = ptr(int(math.Floor(*rules.Max)))
+	}
+	return min, max
+}
+
+// formConfig calls f.Config() on a zero-valued form, which a Config
+// reading the form's state could panic on - it's then treated as empty.
+func formConfig(f kernel.IForm) (conf kernel.FormConfig) {
+	defer func() {
+		if recover() != nil {
+			conf = nil
+		}
+	}()
+	return f.Config()
+}
+
+// removeProperty drops a property along with its required mark.
+func (s *Schema) removeProperty(name string) {
+	delete(s.Properties, name)
+	s.Required = slices.DeleteFunc(s.Required, func(r string) bool { return r == name })
+}
+
+func (s *Schema) isRequired(name string) bool {
+	return slices.Contains(s.Required, name)
+}
+
+// hasFiles reports whether any property is a file upload (or a list of them).
+func (s *Schema) hasFiles() bool {
+	for _, prop := range s.Properties {
+		if prop.Items != nil {
+			prop = prop.Items
+		}
+		if prop.Format == "binary" {
+			return true
+		}
+	}
+	return false
+}
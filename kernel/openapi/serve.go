@@ -0,0 +1,103 @@
+package openapi
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+//go:embed viewer.html
+var viewerSource string
+
+var viewerTpl = template.Must(template.New("viewer").Parse(viewerSource))
+
+// Register serves the document generated from router on route: the viewer
+// at route itself, the document at route+"/openapi.json" and
+// route+"/openapi.yaml". The document is generated per request, so
+// resources registered after Register are documented too.
+func Register(router kernel.IRouter, route string, info Info) {
+	route = strings.TrimSuffix(route, "/")
+	if route == "" {
+		route = "/"
+	}
+	jsonRoute := strings.TrimSuffix(route, "/") + "/openapi.json"
+	yamlRoute := strings.TrimSuffix(route, "/") + "/openapi.yaml"
+
+	router.RegisterResources(kernel.HttpResourcesList{
+		route + "[GET]": func() kernel.IHttpResource {
+			return &viewerResource{Resource: lxHttp.NewResource(), title: info.Title, specURL: jsonRoute}
+		},
+		jsonRoute + "[GET]": func() kernel.IHttpResource {
+			return &documentResource{Resource: lxHttp.NewResource(), router: router, info: info, format: "json"}
+		},
+		yamlRoute + "[GET]": func() kernel.IHttpResource {
+			return &documentResource{Resource: lxHttp.NewResource(), router: router, info: info, format: "yaml"}
+		},
+	})
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IHttpResource */
+
+// documentResource serves the document, generated per request.
+type documentResource struct {
+	*lxHttp.Resource
+	router kernel.IRouter
+	info   Info
+	format string
+}
+
+func (r *documentResource) Run() kernel.IHttpResponse {
+	doc := Generate(r.router, r.info)
+
+	var data []byte
+	var err error
+	contentType := "application/json"
+	if r.format == "yaml" {
+		data, err = doc.YAML()
+		contentType = "application/yaml"
+	} else {
+		data, err = doc.JSON()
+	}
+	if err != nil {
+		r.LogError(fmt.Sprintf("Can not encode OpenAPI document: %v", err), "HttpHandling")
+		return r.ErrorResponse(http.StatusInternalServerError, "Internal server error")
+	}
+
+	w := r.ResponseWriter()
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+	return nil
+}
+
+/** @interface kernel.IHttpResource */
+
+// viewerResource serves the bundled viewer page, pointed at the JSON document.
+type viewerResource struct {
+	*lxHttp.Resource
+	title   string
+	specURL string
+}
+
+func (r *viewerResource) Run() kernel.IHttpResponse {
+	var sb strings.Builder
+	err := viewerTpl.Execute(&sb, map[string]string{
+		"Title":   r.title,
+		"SpecURL": r.specURL,
+	})
+	if err != nil {
+		r.LogError(fmt.Sprintf("Can not render OpenAPI viewer: %v", err), "HttpHandling")
+		return r.ErrorResponse(http.StatusInternalServerError, "Internal server error")
+	}
+	response := new(lxHttp.Response)
+	response.SetHtmlData(sb.String())
+	return response
+}
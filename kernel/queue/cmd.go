@@ -0,0 +1,316 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/epicoon/lxgo/cmd"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+)
+
+// CommandOptions is QueueCommand's cmd.ICommandOptions - Store is the store
+// the app keeps its jobs in, e.g. NewSQLStore on its DB connection.
+type CommandOptions struct {
+	Store IStore
+}
+
+/** @interface cmd.ICommand */
+
+// QueueCommand is the queue:<action> console command - list/show/retry/
+// purge the stored jobs, and write the migration creating their table -
+// see NewCommand.
+type QueueCommand struct {
+	*cmd.Command
+	Store IStore
+}
+
+var _ cmd.ICommand = (*QueueCommand)(nil)
+
+/** @constructor cmd.CCommand */
+
+// NewCommand constructs a QueueCommand - pass the store via CommandOptions.
+func NewCommand(opt ...cmd.ICommandOptions) cmd.ICommand {
+	options := cmd.GetOptions[CommandOptions](opt)
+	return cmd.Prepare(&QueueCommand{
+		Command: cmd.NewCommand(),
+		Store:   options.Store,
+	})
+}
+
+// Config declares the "list", "show", "retry", "purge" and "migration"
+// actions - see cmd.ICommand.
+func (c *QueueCommand) Config() *cmd.Config {
+	return &cmd.Config{
+		Description: "Command to inspect and manage the background job queue",
+		Actions: cmd.ActionsConfig{
+			"list": cmd.ActionConfig{
+				Description: "Show the latest jobs",
+				Executor:    list,
+				Params: cmd.ParamsConfig{
+					"status": cmd.ParamConfig{
+						Description: "Only show jobs with the status",
+						Type:        cmd.ParamTypeEnum,
+						TypeDetails: []string{STATUS_PENDING, STATUS_RUNNING, STATUS_DONE, STATUS_DEAD},
+						Required:    false,
+					},
+					"queue": cmd.ParamConfig{
+						Description: "Only show jobs of the queue",
+						Type:        cmd.ParamTypeString,
+						Required:    false,
+					},
+					"count": cmd.ParamConfig{
+						Description: "Jobs count to show",
+						Type:        cmd.ParamTypeInt,
+						Required:    false,
+						Default:     20,
+					},
+				},
+			},
+			"show": cmd.ActionConfig{
+				Description: "Show a job with its payload and last error",
+				Executor:    show,
+				Params: cmd.ParamsConfig{
+					"id": cmd.ParamConfig{
+						Description: "Job ID",
+						Type:        cmd.ParamTypeInt,
+						Required:    true,
+					},
+				},
+			},
+			"retry": cmd.ActionConfig{
+				Description: "Queue dead jobs again",
+				Executor:    retry,
+				Params: cmd.ParamsConfig{
+					"id": cmd.ParamConfig{
+						Description: "Job ID. If not defined all dead jobs (of --queue, if defined) will be retried",
+						Type:        cmd.ParamTypeInt,
+						Required:    false,
+					},
+					"queue": cmd.ParamConfig{
+						Description: "Only retry dead jobs of the queue",
+						Type:        cmd.ParamTypeString,
+						Required:    false,
+					},
+				},
+			},
+			"purge": cmd.ActionConfig{
+				Description: "Delete done or dead jobs",
+				Executor:    purge,
+				Params: cmd.ParamsConfig{
+					"status": cmd.ParamConfig{
+						Description: "Status of the jobs to delete",
+						Type:        cmd.ParamTypeEnum,
+						TypeDetails: []string{STATUS_DONE, STATUS_DEAD},
+						Required:    false,
+						Default:     STATUS_DONE,
+					},
+					"older": cmd.ParamConfig{
+						Description: "Only delete jobs finished more than this many hours ago",
+						Type:        cmd.ParamTypeInt,
+						Required:    false,
+						Default:     0,
+						HideDefault: true,
+					},
+				},
+			},
+			"migration": cmd.ActionConfig{
+				Description: "Create the migration for the jobs table, to apply with lxgo/migrator",
+				Executor:    migration,
+				Params: cmd.ParamsConfig{
+					"path": cmd.ParamConfig{
+						Description: "Path to directory with migrations",
+						Type:        cmd.ParamTypeString,
+						Required:    true,
+					},
+					"driver": cmd.ParamConfig{
+						Description: "DB driver",
+						Type:        cmd.ParamTypeEnum,
+						TypeDetails: []string{lxApp.DriverPostgres, lxApp.DriverMysql, lxApp.DriverSqlite},
+						Required:    false,
+						Default:     lxApp.DriverPostgres,
+					},
+					"table": cmd.ParamConfig{
+						Description: "Jobs table name",
+						Type:        cmd.ParamTypeString,
+						Required:    false,
+						Default:     DEFAULT_TABLE,
+					},
+				},
+			},
+		},
+	}
+}
+
+/** @handler cmd.FAction */
+func list(c cmd.ICommand) error {
+	store, ok := commandStore(c)
+	if !ok {
+		return nil
+	}
+	count, ok := intParam(c, "count", 20)
+	if !ok {
+		return nil
+	}
+
+	jobs, err := store.List(context.Background(), Filter{
+		Queue:  stringParam(c, "queue"),
+		Status: stringParam(c, "status"),
+		Limit:  count,
+	})
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return nil
+	}
+	if len(jobs) == 0 {
+		fmt.Println("There are no jobs")
+		return nil
+	}
+
+	fmt.Println("Jobs:")
+	for _, j := range jobs {
+		fmt.Printf("%d %s/%s [%s] attempts: %d/%d, run at: %s\n",
+			j.ID, j.Queue, j.Type, j.Status, j.Attempts, j.MaxAttempts, j.RunAt.Format(time.DateTime))
+	}
+	return nil
+}
+
+/** @handler cmd.FAction */
+func show(c cmd.ICommand) error {
+	store, ok := commandStore(c)
+	if !ok {
+		return nil
+	}
+	id, ok := intParam(c, "id", 0)
+	if !ok {
+		return nil
+	}
+
+	j, err := store.Get(context.Background(), int64(id))
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return nil
+	}
+	fmt.Printf("ID:         %d\n", j.ID)
+	fmt.Printf("Queue:      %s\n", j.Queue)
+	fmt.Printf("Type:       %s\n", j.Type)
+	fmt.Printf("Status:     %s\n", j.Status)
+	fmt.Printf("Attempts:   %d/%d\n", j.Attempts, j.MaxAttempts)
+	fmt.Printf("Run at:     %s\n", j.RunAt.Format(time.DateTime))
+	fmt.Printf("Created at: %s\n", j.CreatedAt.Format(time.DateTime))
+	fmt.Printf("Updated at: %s\n", j.UpdatedAt.Format(time.DateTime))
+	if j.UniqueKey != "" {
+		fmt.Printf("Unique key: %s\n", j.UniqueKey)
+	}
+	fmt.Printf("Payload:    %s\n", j.Payload)
+	if j.LastError != "" {
+		fmt.Printf("Last error: %s\n", j.LastError)
+	}
+	return nil
+}
+
+/** @handler cmd.FAction */
+func retry(c cmd.ICommand) error {
+	store, ok := commandStore(c)
+	if !ok {
+		return nil
+	}
+	ctx := context.Background()
+
+	if c.HasParam("id") {
+		id, ok := intParam(c, "id", 0)
+		if !ok {
+			return nil
+		}
+		if err := store.Retry(ctx, int64(id)); err != nil {
+			fmt.Printf("Can not retry job %d: %s\n", id, err)
+			return nil
+		}
+		fmt.Printf("Job %d queued again\n", id)
+		return nil
+	}
+
+	jobs, err := store.List(ctx, Filter{Queue: stringParam(c, "queue"), Status: STATUS_DEAD})
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return nil
+	}
+	count := 0
+	for _, j := range jobs {
+		if err := store.Retry(ctx, j.ID); err != nil {
+			fmt.Printf("Can not retry job %d: %s\n", j.ID, err)
+			continue
+		}
+		count++
+	}
+	fmt.Printf("%d jobs queued again\n", count)
+	return nil
+}
+
+/** @handler cmd.FAction */
+func purge(c cmd.ICommand) error {
+	store, ok := commandStore(c)
+	if !ok {
+		return nil
+	}
+	older, ok := intParam(c, "older", 0)
+	if !ok {
+		return nil
+	}
+	status := stringParam(c, "status")
+	if status == "" {
+		status = STATUS_DONE
+	}
+
+	count, err := store.Purge(context.Background(), status, time.Now().Add(-time.Duration(older)*time.Hour))
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return nil
+	}
+	fmt.Printf("%d %s jobs deleted\n", count, status)
+	return nil
+}
+
+/** @handler cmd.FAction */
+func migration(c cmd.ICommand) error {
+	driver := stringParam(c, "driver")
+	if driver == "" {
+		driver = lxApp.DriverPostgres
+	}
+	path, err := WriteMigration(stringParam(c, "path"), driver, stringParam(c, "table"))
+	if err != nil {
+		fmt.Printf("Can not create migration file: %s\n", err)
+		return nil
+	}
+	fmt.Printf("Migration '%s' has been created\n", path)
+	return nil
+}
+
+func commandStore(c cmd.ICommand) (IStore, bool) {
+	store := c.(*QueueCommand).Store
+	if store == nil {
+		fmt.Println("The command has no job store - pass it via queue.CommandOptions")
+		return nil, false
+	}
+	return store, true
+}
+
+func stringParam(c cmd.ICommand, key string) string {
+	if !c.HasParam(key) {
+		return ""
+	}
+	return fmt.Sprintf("%v", c.Param(key))
+}
+
+func intParam(c cmd.ICommand, key string, def int) (int, bool) {
+	if !c.HasParam(key) {
+		return def, true
+	}
+	val, err := strconv.Atoi(fmt.Sprintf("%v", c.Param(key)))
+	if err != nil {
+		fmt.Printf("Invalid %s parameter: %v. Integer required\n", key, c.Param(key))
+		return 0, false
+	}
+	return val, true
+}
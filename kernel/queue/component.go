@@ -0,0 +1,450 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+)
+
+// APP_COMPONENT_KEY is the key Component registers itself under - see
+// SetAppComponent/AppComponent.
+const APP_COMPONENT_KEY = "lxgo_queue"
+
+// Config.Store values.
+const (
+	STORE_DB     = "db"
+	STORE_MEMORY = "memory"
+)
+
+// Defaults for the Config fields left unset.
+const (
+	DefaultWorkers      = 4
+	DefaultMaxAttempts  = 5
+	DefaultBackoff      = 10 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultTimeout      = 5 * time.Minute
+	DefaultPollInterval = time.Second
+	DefaultStopTimeout  = 30 * time.Second
+)
+
+// FHandler runs a job - see Component.Handle. ctx is canceled once the
+// job's time is up or the app stops; an error (or a panic) counts as a
+// failed attempt.
+type FHandler func(ctx context.Context, job *Job) error
+
+// EnqueueConfig configures a job being enqueued - see Component.Enqueue.
+type EnqueueConfig struct {
+	// Queue is the queue the job goes to - DEFAULT_QUEUE if empty.
+	Queue string
+	// Delay postpones the job.
+	Delay time.Duration
+	// RunAt postpones the job until the given time - it wins over Delay.
+	RunAt time.Time
+	// UniqueKey keeps the job from being queued while another one with the
+	// same key is pending or running - Enqueue returns ErrDuplicate then.
+	UniqueKey string
+	// MaxAttempts overrides Config.MaxAttempts for the job.
+	MaxAttempts int
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Config
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponentConfig */
+
+// Config is Component's app-component configuration. Durations are in
+// seconds.
+type Config struct {
+	*lxApp.ComponentConfig
+	// Store is STORE_DB (default) or STORE_MEMORY.
+	Store string
+	// Connection is the app's named DB connection the jobs are stored in -
+	// the main one if empty.
+	Connection string
+	// Table is the jobs table - DEFAULT_TABLE if empty.
+	Table string
+	// Queues maps the queues this app works to their number of workers -
+	// DEFAULT_QUEUE with Workers workers if empty.
+	Queues map[string]int
+	// Workers is DEFAULT_QUEUE's number of workers if Queues is empty -
+	// DefaultWorkers if unset.
+	Workers int
+	// MaxAttempts is how many times a job is tried before it's dead.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for every next
+	// one up to MaxBackoff.
+	Backoff    int
+	MaxBackoff int
+	// Timeout is how long a job may run - it's considered lost (and run
+	// again) if its worker doesn't report back by then.
+	Timeout int
+	// PollInterval is how often idle workers look for due jobs.
+	PollInterval int
+	// StopTimeout is how long Final waits for running jobs.
+	StopTimeout int
+}
+
+/** @constructor kernel.CAppComponentConfig */
+
+// NewConfig constructs a Config.
+func NewConfig() kernel.IAppComponentConfig {
+	return &Config{ComponentConfig: lxApp.NewComponentConfigStruct()}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Component
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponent */
+
+// Component runs the jobs of its queues with the handlers registered for
+// their types, once the app runs - see SetAppComponent to register it on
+// an app, Enqueue to defer work to it.
+type Component struct {
+	*lxApp.AppComponent
+
+	store    IStore
+	mu       sync.RWMutex
+	handlers map[string]FHandler
+	wake     map[string]chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+var _ kernel.IAppComponent = (*Component)(nil)
+
+// SetAppComponent registers a new Component on app under
+// APP_COMPONENT_KEY, configured from the config section named by configKey.
+func SetAppComponent(app kernel.IApp, configKey string) error {
+	if app.HasComponent(APP_COMPONENT_KEY) {
+		return fmt.Errorf("the application already has component: %s", APP_COMPONENT_KEY)
+	}
+
+	c := NewComponent()
+	if err := lxApp.InitComponent(c, app, configKey); err != nil {
+		return fmt.Errorf("can not init queue component: %s", err)
+	}
+	if err := c.init(); err != nil {
+		return fmt.Errorf("can not init queue component: %s", err)
+	}
+
+	app.SetComponent(APP_COMPONENT_KEY, c)
+	return nil
+}
+
+// AppComponent returns the Component registered on app under APP_COMPONENT_KEY.
+func AppComponent(app kernel.IApp) (*Component, error) {
+	c := app.Component(APP_COMPONENT_KEY)
+	if c == nil {
+		return nil, fmt.Errorf("application component '%s' not found", APP_COMPONENT_KEY)
+	}
+
+	q, ok := c.(*Component)
+	if !ok {
+		return nil, fmt.Errorf("application component '%s' is not '*queue.Component'", APP_COMPONENT_KEY)
+	}
+
+	return q, nil
+}
+
+/** @constructor */
+
+// NewComponent constructs a Component with no handlers.
+func NewComponent() *Component {
+	return &Component{
+		AppComponent: lxApp.NewAppComponent(),
+		handlers:     make(map[string]FHandler),
+		wake:         make(map[string]chan struct{}),
+	}
+}
+
+// Name returns the component's name - see kernel.IAppComponent.
+func (c *Component) Name() string {
+	return "Queue"
+}
+
+// LogCategory returns the category the component's log methods write under.
+func (c *Component) LogCategory() string {
+	return "Queue"
+}
+
+// CConfig returns Config's constructor - see kernel.IAppComponent.
+func (c *Component) CConfig() kernel.CAppComponentConfig {
+	return NewConfig
+}
+
+// Config returns the component's bound Config.
+func (c *Component) Config() *Config {
+	return (c.GetConfig()).(*Config)
+}
+
+// Store returns the store the jobs are kept in.
+func (c *Component) Store() IStore {
+	return c.store
+}
+
+// SetStore replaces the store the jobs are kept in - call it before the
+// app runs.
+func (c *Component) SetStore(s IStore) {
+	c.store = s
+}
+
+// Handle registers h to run the jobs of jobType.
+func (c *Component) Handle(jobType string, h FHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[jobType] = h
+}
+
+// Enqueue stores a job of jobType with payload encoded as JSON, for the
+// workers of its queue to run - ErrDuplicate if its unique key is taken.
+func (c *Component) Enqueue(ctx context.Context, jobType string, payload any, conf ...EnqueueConfig) (*Job, error) {
+	var ec EnqueueConfig
+	if len(conf) > 0 {
+		ec = conf[0]
+	}
+
+	job := &Job{
+		Queue:       ec.Queue,
+		Type:        jobType,
+		UniqueKey:   ec.UniqueKey,
+		MaxAttempts: ec.MaxAttempts,
+		RunAt:       ec.RunAt,
+	}
+	if job.Queue == "" {
+		job.Queue = DEFAULT_QUEUE
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = c.maxAttempts()
+	}
+	if job.RunAt.IsZero() && ec.Delay > 0 {
+		job.RunAt = time.Now().Add(ec.Delay)
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("can not encode '%s' job payload: %w", jobType, err)
+		}
+		job.Payload = data
+	}
+
+	if err := c.store.Push(ctx, job); err != nil {
+		return nil, err
+	}
+	if !job.RunAt.After(time.Now()) {
+		c.notify(job.Queue)
+	}
+	return job, nil
+}
+
+// Run starts the workers - see kernel.IAppComponent.
+func (c *Component) Run() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ctx != nil {
+		return nil
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	for queue, workers := range c.queues() {
+		wake := make(chan struct{}, workers)
+		c.wake[queue] = wake
+		for i := 0; i < workers; i++ {
+			c.wg.Add(1)
+			go c.work(queue, wake)
+		}
+	}
+	return nil
+}
+
+// Final stops the workers, waiting up to Config.StopTimeout for the jobs
+// they run - the jobs are canceled and go back to their queues undone.
+// See kernel.IAppComponent.
+func (c *Component) Final() error {
+	c.mu.Lock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.mu.Unlock()
+
+	timeout := seconds(c.Config().StopTimeout, DefaultStopTimeout)
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("jobs still running after %s", timeout)
+	}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// init sets up the configured store.
+func (c *Component) init() error {
+	switch c.Config().Store {
+	case STORE_MEMORY:
+		c.store = NewMemoryStore()
+	case "", STORE_DB:
+		conn := c.App().NamedConnection(c.Config().Connection)
+		if conn == nil {
+			return fmt.Errorf("DB connection '%s' not found", c.Config().Connection)
+		}
+		c.store = NewSQLStore(conn, c.Config().Table)
+	default:
+		return fmt.Errorf("unknown store '%s'", c.Config().Store)
+	}
+	return nil
+}
+
+func (c *Component) queues() map[string]int {
+	if len(c.Config().Queues) > 0 {
+		return c.Config().Queues
+	}
+	workers := c.Config().Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return map[string]int{DEFAULT_QUEUE: workers}
+}
+
+func (c *Component) maxAttempts() int {
+	if c.Config().MaxAttempts > 0 {
+		return c.Config().MaxAttempts
+	}
+	return DefaultMaxAttempts
+}
+
+// backoff returns the delay before retrying a job failed attempts times.
+func (c *Component) backoff(attempts int) time.Duration {
+	d := seconds(c.Config().Backoff, DefaultBackoff)
+	limit := seconds(c.Config().MaxBackoff, DefaultMaxBackoff)
+	for i := 1; i < attempts && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+// notify wakes an idle worker of queue, if this app works it.
+func (c *Component) notify(queue string) {
+	c.mu.RLock()
+	wake := c.wake[queue]
+	c.mu.RUnlock()
+	if wake == nil {
+		return
+	}
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// work runs the jobs of queue until the app stops, polling for them when idle.
+func (c *Component) work(queue string, wake chan struct{}) {
+	defer c.wg.Done()
+	poll := seconds(c.Config().PollInterval, DefaultPollInterval)
+	timeout := seconds(c.Config().Timeout, DefaultTimeout)
+	for {
+		if c.ctx.Err() != nil {
+			return
+		}
+
+		now := time.Now()
+		job, err := c.store.Reserve(context.Background(), queue, now, now.Add(timeout))
+		if err != nil {
+			c.Logger().Error("can not reserve job", "queue", queue, "error", err.Error())
+		}
+		if job != nil {
+			c.process(job, timeout)
+			continue
+		}
+
+		timer := time.NewTimer(poll)
+		select {
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		case <-wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// process runs a reserved job and records the outcome.
+func (c *Component) process(job *Job, timeout time.Duration) {
+	log := c.Logger().With("job", job.ID, "type", job.Type, "queue", job.Queue, "attempt", job.Attempts)
+
+	c.mu.RLock()
+	h, ok := c.handlers[job.Type]
+	c.mu.RUnlock()
+
+	start := time.Now()
+	var err error
+	if ok {
+		ctx, cancel := context.WithTimeout(c.ctx, timeout)
+		err = runHandler(ctx, h, job)
+		cancel()
+	} else {
+		err = fmt.Errorf("no handler for job type '%s'", job.Type)
+	}
+	duration := time.Since(start).String()
+
+	// The app's stopping - leave the job for the next run.
+	if err != nil && c.ctx.Err() != nil {
+		if rErr := c.store.Release(context.Background(), job); rErr != nil {
+			log.Error("can not release job", "error", rErr.Error())
+		}
+		log.Info("job released on shutdown", "duration", duration)
+		return
+	}
+
+	var sErr error
+	switch {
+	case err == nil:
+		sErr = c.store.Complete(context.Background(), job)
+		log.Info("job done", "duration", duration)
+	case job.Attempts >= job.MaxAttempts:
+		sErr = c.store.Fail(context.Background(), job, err.Error(), time.Time{})
+		log.Error("job dead", "duration", duration, "error", err.Error())
+	default:
+		retryAt := time.Now().Add(c.backoff(job.Attempts))
+		sErr = c.store.Fail(context.Background(), job, err.Error(), retryAt)
+		log.Warning("job failed, will retry", "duration", duration, "error", err.Error(), "retry_at", retryAt.Format(time.RFC3339))
+	}
+	if errors.Is(sErr, ErrLeaseLost) {
+		log.Warning("job lease lost, outcome dropped", "duration", duration)
+	} else if sErr != nil {
+		log.Error("can not update job", "error", sErr.Error())
+	}
+}
+
+func runHandler(ctx context.Context, h FHandler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return h(ctx, job)
+}
+
+func seconds(s int, def time.Duration) time.Duration {
+	if s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return def
+}
@@ -0,0 +1,148 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	"github.com/epicoon/lxgo/kernel/queue"
+)
+
+func newQueue(t *testing.T, conf kernel.Dict) *queue.Component {
+	t.Helper()
+	conf["Store"] = queue.STORE_MEMORY
+	_, q := apptest.NewComponent(t, "Queue", conf, queue.SetAppComponent, queue.AppComponent)
+	return q
+}
+
+func waitForJob(t *testing.T, q *queue.Component, id int64, status string) *queue.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := q.Store().Get(context.Background(), id)
+		if err == nil && job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d not %s in time: %+v", id, status, job)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestComponent_RunsJobs(t *testing.T) {
+	q := newQueue(t, kernel.Dict{"Queues": kernel.Dict{"default": 2, "mail": 1}})
+
+	type mail struct{ To string }
+	sent := make(chan string, 1)
+	q.Handle("send", func(ctx context.Context, job *queue.Job) error {
+		var m mail
+		if err := job.Decode(&m); err != nil {
+			return err
+		}
+		sent <- m.To
+		return nil
+	})
+	if err := q.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	defer q.Final()
+
+	ctx := context.Background()
+	job, err := q.Enqueue(ctx, "send", mail{To: "a@b.c"}, queue.EnqueueConfig{Queue: "mail", UniqueKey: "welcome:1"})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case to := <-sent:
+		if to != "a@b.c" {
+			t.Fatalf("unexpected payload %q", to)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the job to run right away")
+	}
+	waitForJob(t, q, job.ID, queue.STATUS_DONE)
+
+	delayed, _ := q.Enqueue(ctx, "send", mail{To: "later"}, queue.EnqueueConfig{Queue: "mail", Delay: time.Hour})
+	if _, err := q.Enqueue(ctx, "send", nil, queue.EnqueueConfig{Queue: "mail", RunAt: time.Now().Add(time.Hour), UniqueKey: "digest"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if _, err := q.Enqueue(ctx, "send", nil, queue.EnqueueConfig{Queue: "mail", UniqueKey: "digest"}); !errors.Is(err, queue.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if job, _ := q.Store().Get(ctx, delayed.ID); job.Status != queue.STATUS_PENDING {
+		t.Fatalf("expected the delayed job to wait, got %+v", job)
+	}
+}
+
+func TestComponent_RetriesAndDeadLetter(t *testing.T) {
+	q := newQueue(t, kernel.Dict{"MaxAttempts": 2, "Backoff": 1, "PollInterval": 1})
+
+	var attempts atomic.Int32
+	q.Handle("flaky", func(ctx context.Context, job *queue.Job) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("timeout")
+		}
+		return nil
+	})
+	q.Handle("broken", func(ctx context.Context, job *queue.Job) error {
+		panic("boom")
+	})
+	q.Run()
+	defer q.Final()
+
+	ctx := context.Background()
+	flaky, _ := q.Enqueue(ctx, "flaky", nil)
+	broken, _ := q.Enqueue(ctx, "broken", nil)
+	unknown, _ := q.Enqueue(ctx, "unknown", nil, queue.EnqueueConfig{MaxAttempts: 1})
+
+	job := waitForJob(t, q, flaky.ID, queue.STATUS_DONE)
+	if job.Attempts != 2 || job.LastError != "timeout" {
+		t.Fatalf("expected the job to succeed on retry, got %+v", job)
+	}
+	job = waitForJob(t, q, broken.ID, queue.STATUS_DEAD)
+	if job.Attempts != 2 || job.LastError[:11] != "panic: boom" {
+		t.Fatalf("expected the panicking job to be dead, got %+v", job)
+	}
+	job = waitForJob(t, q, unknown.ID, queue.STATUS_DEAD)
+	if job.LastError != "no handler for job type 'unknown'" {
+		t.Fatalf("unexpected error %q", job.LastError)
+	}
+}
+
+func TestComponent_FinalReleasesJobs(t *testing.T) {
+	q := newQueue(t, kernel.Dict{"StopTimeout": 1})
+
+	started := make(chan struct{})
+	q.Handle("export", func(ctx context.Context, job *queue.Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	q.Run()
+	job, _ := q.Enqueue(context.Background(), "export", nil)
+	<-started
+
+	if err := q.Final(); err != nil {
+		t.Fatalf("Final: %v", err)
+	}
+	job, _ = q.Store().Get(context.Background(), job.ID)
+	if job.Status != queue.STATUS_PENDING || job.Attempts != 0 {
+		t.Fatalf("expected the interrupted job back in its queue, got %+v", job)
+	}
+}
+
+func TestComponent_DBStore(t *testing.T) {
+	a, err := apptest.New(kernel.Dict{"Components": kernel.Dict{"Queue": kernel.Dict{}}})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if err := queue.SetAppComponent(a, "Components.Queue"); err == nil {
+		t.Fatal("expected the component to need a DB connection")
+	}
+}
@@ -0,0 +1,236 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+/** @interface IStore */
+
+// MemoryStore is an IStore keeping the jobs in memory - for tests, and for
+// jobs that may be lost on restart.
+type MemoryStore struct {
+	mu     sync.Mutex
+	jobs   map[int64]*memoryJob
+	locks  map[string]int64
+	lastID int64
+}
+
+var _ IStore = (*MemoryStore)(nil)
+
+/** @constructor */
+
+// NewMemoryStore constructs an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:  make(map[int64]*memoryJob),
+		locks: make(map[string]int64),
+	}
+}
+
+// Push stores job as pending - see IStore.
+func (s *MemoryStore) Push(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job.UniqueKey != "" {
+		if _, held := s.locks[job.UniqueKey]; held {
+			return ErrDuplicate
+		}
+	}
+
+	s.lastID++
+	now := time.Now()
+	job.ID = s.lastID
+	job.Status = STATUS_PENDING
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	stored := *job
+	s.jobs[job.ID] = &memoryJob{Job: stored}
+	if job.UniqueKey != "" {
+		s.locks[job.UniqueKey] = job.ID
+	}
+	return nil
+}
+
+// Reserve takes the first due job of queue - see IStore.
+func (s *MemoryStore) Reserve(ctx context.Context, queue string, now, leaseUntil time.Time) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found *memoryJob
+	for _, j := range s.jobs {
+		if j.Queue != queue || !j.due(now) {
+			continue
+		}
+		if found == nil || j.RunAt.Before(found.RunAt) || (j.RunAt.Equal(found.RunAt) && j.ID < found.ID) {
+			found = j
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+
+	found.Status = STATUS_RUNNING
+	found.Attempts++
+	found.ReservedUntil = leaseUntil
+	found.UpdatedAt = now
+	job := found.Job
+	return &job, nil
+}
+
+// Complete marks a reserved job done - see IStore.
+func (s *MemoryStore) Complete(ctx context.Context, job *Job) error {
+	return s.updateLeased(job, func(j *memoryJob) error {
+		j.Status = STATUS_DONE
+		s.unlock(j)
+		return nil
+	})
+}
+
+// Fail records a reserved job's failed attempt - see IStore.
+func (s *MemoryStore) Fail(ctx context.Context, job *Job, errMsg string, retryAt time.Time) error {
+	return s.updateLeased(job, func(j *memoryJob) error {
+		j.LastError = errMsg
+		if retryAt.IsZero() {
+			j.Status = STATUS_DEAD
+			s.unlock(j)
+		} else {
+			j.Status = STATUS_PENDING
+			j.RunAt = retryAt
+		}
+		return nil
+	})
+}
+
+// Release puts a reserved job back to pending - see IStore.
+func (s *MemoryStore) Release(ctx context.Context, job *Job) error {
+	return s.updateLeased(job, func(j *memoryJob) error {
+		j.Status = STATUS_PENDING
+		if j.Attempts > 0 {
+			j.Attempts--
+		}
+		return nil
+	})
+}
+
+// Get returns the job - see IStore.
+func (s *MemoryStore) Get(ctx context.Context, id int64) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	job := j.Job
+	return &job, nil
+}
+
+// List returns the jobs matching f, newest first - see IStore.
+func (s *MemoryStore) List(ctx context.Context, f Filter) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*Job, 0)
+	for _, j := range s.jobs {
+		if (f.Queue == "" || j.Queue == f.Queue) && (f.Status == "" || j.Status == f.Status) {
+			job := j.Job
+			list = append(list, &job)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	if f.Limit > 0 && len(list) > f.Limit {
+		list = list[:f.Limit]
+	}
+	return list, nil
+}
+
+// Retry makes a dead job pending again - see IStore.
+func (s *MemoryStore) Retry(ctx context.Context, id int64) error {
+	return s.update(id, func(j *memoryJob) error {
+		if j.Status != STATUS_DEAD {
+			return fmt.Errorf("job %d is %s, not %s", id, j.Status, STATUS_DEAD)
+		}
+		if j.UniqueKey != "" {
+			if _, held := s.locks[j.UniqueKey]; held {
+				return ErrDuplicate
+			}
+			s.locks[j.UniqueKey] = j.ID
+		}
+		j.Status = STATUS_PENDING
+		j.Attempts = 0
+		j.RunAt = time.Now()
+		return nil
+	})
+}
+
+// Purge deletes finished jobs - see IStore.
+func (s *MemoryStore) Purge(ctx context.Context, status string, before time.Time) (int, error) {
+	if status != STATUS_DONE && status != STATUS_DEAD {
+		return 0, fmt.Errorf("only %s and %s jobs can be purged", STATUS_DONE, STATUS_DEAD)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	for id, j := range s.jobs {
+		if j.Status == status && j.UpdatedAt.Before(before) {
+			delete(s.jobs, id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+type memoryJob struct {
+	Job
+}
+
+func (j *memoryJob) due(now time.Time) bool {
+	switch j.Status {
+	case STATUS_PENDING:
+		return !j.RunAt.After(now)
+	case STATUS_RUNNING:
+		return !j.ReservedUntil.After(now)
+	}
+	return false
+}
+
+func (s *MemoryStore) update(id int64, f func(j *memoryJob) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := f(j); err != nil {
+		return err
+	}
+	j.UpdatedAt = time.Now()
+	return nil
+}
+
+// updateLeased runs update for a job as Reserve returned it, only while it's
+// still running under that reservation - ErrLeaseLost otherwise.
+func (s *MemoryStore) updateLeased(job *Job, f func(j *memoryJob) error) error {
+	return s.update(job.ID, func(j *memoryJob) error {
+		if j.Status != STATUS_RUNNING || j.Attempts != job.Attempts || !j.ReservedUntil.Equal(job.ReservedUntil) {
+			return ErrLeaseLost
+		}
+		return f(j)
+	})
+}
+
+// unlock frees j's unique key - s.mu must be held.
+func (s *MemoryStore) unlock(j *memoryJob) {
+	if j.UniqueKey != "" && s.locks[j.UniqueKey] == j.ID {
+		delete(s.locks, j.UniqueKey)
+	}
+}
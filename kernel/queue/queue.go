@@ -0,0 +1,116 @@
+// Package queue provides a persistent background job queue component for
+// lxgo/kernel applications - jobs are stored (in the app's DB, see
+// SQLStore, or in memory for tests, see MemoryStore) and run by worker
+// pools, retried with exponential backoff and moved to the dead-letter
+// state once out of attempts. See NewCommand for the console command to
+// inspect, retry and purge them.
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Job statuses.
+const (
+	STATUS_PENDING = "pending"
+	STATUS_RUNNING = "running"
+	STATUS_DONE    = "done"
+	STATUS_DEAD    = "dead"
+)
+
+// DEFAULT_QUEUE is the queue jobs go to unless EnqueueConfig.Queue says otherwise.
+const DEFAULT_QUEUE = "default"
+
+// ErrDuplicate is returned when enqueuing (or retrying) a job whose unique
+// key is held by another pending or running job.
+var ErrDuplicate = errors.New("a job with the same unique key is already queued")
+
+// ErrNotFound is returned for a job ID the store doesn't have.
+var ErrNotFound = errors.New("job not found")
+
+// ErrLeaseLost is returned when finishing a reserved job whose lease ran out
+// meanwhile - the job is due again, or has been taken by another worker, and
+// the outcome isn't recorded.
+var ErrLeaseLost = errors.New("job lease lost")
+
+// Job is a stored job.
+type Job struct {
+	ID    int64
+	Queue string
+	// Type names the handler running the job - see Component.Handle.
+	Type string
+	// Payload is the job's JSON-encoded payload - see Decode.
+	Payload []byte
+	// UniqueKey, if set, keeps another job with the same key from being
+	// queued while this one is pending or running.
+	UniqueKey   string
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	// RunAt is when the job is due.
+	RunAt time.Time
+	// ReservedUntil is when the lease of a running job runs out - see
+	// IStore.Reserve.
+	ReservedUntil time.Time
+	// LastError is the latest failed attempt's error.
+	LastError string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Decode unmarshals the job's payload into v.
+func (j *Job) Decode(v any) error {
+	if len(j.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Filter selects jobs for IStore.List - empty fields match everything.
+type Filter struct {
+	Queue  string
+	Status string
+	// Limit caps the number of jobs returned, 0 means no limit.
+	Limit int
+}
+
+// IStore keeps the jobs - see SQLStore and MemoryStore.
+type IStore interface {
+	// Push stores job as pending, setting its ID, status and timestamps -
+	// ErrDuplicate if its UniqueKey is held.
+	Push(ctx context.Context, job *Job) error
+
+	// Reserve marks the first job of queue due at now as running until
+	// leaseUntil, counting an attempt, and returns it - nil if there's none.
+	// Running jobs whose lease ran out (their worker died) are due again.
+	Reserve(ctx context.Context, queue string, now, leaseUntil time.Time) (*Job, error)
+
+	// Complete marks job, as Reserve returned it, done. Like Fail and
+	// Release, it returns ErrLeaseLost unless the job is still running under
+	// that reservation.
+	Complete(ctx context.Context, job *Job) error
+
+	// Fail records a reserved job's failed attempt - it's pending again from
+	// retryAt, or dead if retryAt is zero.
+	Fail(ctx context.Context, job *Job, errMsg string, retryAt time.Time) error
+
+	// Release puts a reserved job back to pending without counting the attempt.
+	Release(ctx context.Context, job *Job) error
+
+	// Get returns the job - ErrNotFound if there's none.
+	Get(ctx context.Context, id int64) (*Job, error)
+
+	// List returns the jobs matching f, newest first.
+	List(ctx context.Context, f Filter) ([]*Job, error)
+
+	// Retry makes a dead job pending and due right away, with its attempts
+	// reset - ErrDuplicate if its UniqueKey has been taken meanwhile.
+	Retry(ctx context.Context, id int64) error
+
+	// Purge deletes the jobs with status (STATUS_DONE or STATUS_DEAD) last
+	// updated before before, returning how many there were.
+	Purge(ctx context.Context, status string, before time.Time) (int, error)
+}
@@ -0,0 +1,412 @@
+package queue
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+)
+
+// DEFAULT_TABLE is the table SQLStore keeps the jobs in unless told otherwise.
+const DEFAULT_TABLE = "lxgo_jobs"
+
+/** @interface IStore */
+
+// SQLStore is an IStore keeping the jobs in a table of an app's DB -
+// PostgreSQL, MySQL or SQLite. The table is created by a migration, see
+// Migration/WriteMigration. Reserve locks rows with "FOR UPDATE SKIP
+// LOCKED" where the DB has it, so any number of processes can work the
+// same queues.
+type SQLStore struct {
+	conn  kernel.IConnection
+	table string
+}
+
+var _ IStore = (*SQLStore)(nil)
+
+/** @constructor */
+
+// NewSQLStore constructs an SQLStore working on conn's DB, in table -
+// DEFAULT_TABLE if empty. conn may connect later, its DB is only taken
+// once the store is used.
+func NewSQLStore(conn kernel.IConnection, table string) *SQLStore {
+	if table == "" {
+		table = DEFAULT_TABLE
+	}
+	return &SQLStore{conn: conn, table: table}
+}
+
+// Push stores job as pending - see IStore.
+func (s *SQLStore) Push(ctx context.Context, job *Job) error {
+	now := time.Now()
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (queue, type, payload, unique_key, unique_lock, status, attempts, max_attempts, run_at, reserved_until, last_error, created_at, updated_at)"+
+			" VALUES (%s, %s, %s, %s, %s, %s, 0, %s, %s, 0, '', %s, %s)",
+		s.table, s.ph(1), s.ph(2), s.ph(3), s.ph(4), s.ph(5), s.ph(6), s.ph(7), s.ph(8), s.ph(9), s.ph(10),
+	)
+	args := []any{
+		job.Queue, job.Type, string(job.Payload), job.UniqueKey, lockValue(job.UniqueKey),
+		STATUS_PENDING, job.MaxAttempts, job.RunAt.UnixMilli(), now.UnixMilli(), now.UnixMilli(),
+	}
+
+	var err error
+	if s.driver() == lxApp.DriverPostgres {
+		err = s.db().QueryRowContext(ctx, query+" RETURNING id", args...).Scan(&job.ID)
+	} else {
+		var res sql.Result
+		if res, err = s.db().ExecContext(ctx, query, args...); err == nil {
+			job.ID, err = res.LastInsertId()
+		}
+	}
+	if err != nil {
+		if job.UniqueKey != "" && s.isLocked(ctx, job.UniqueKey) {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("can not push job: %w", err)
+	}
+
+	job.Status = STATUS_PENDING
+	job.CreatedAt = time.UnixMilli(now.UnixMilli())
+	job.UpdatedAt = job.CreatedAt
+	return nil
+}
+
+// Reserve takes the first due job of queue - see IStore.
+func (s *SQLStore) Reserve(ctx context.Context, queue string, now, leaseUntil time.Time) (*Job, error) {
+	tx, err := s.db().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can not reserve job: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("SELECT id FROM %s WHERE %s ORDER BY run_at, id LIMIT 1", s.table, s.dueCond(1))
+	if s.driver() != lxApp.DriverSqlite {
+		query += " FOR UPDATE SKIP LOCKED"
+	}
+	ms := now.UnixMilli()
+	var id int64
+	if err := tx.QueryRowContext(ctx, query, queue, ms, ms).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("can not reserve job: %w", err)
+	}
+
+	// The due condition again, for SQLite - it has no row locks.
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(
+		"UPDATE %s SET status = '%s', attempts = attempts + 1, reserved_until = %s, updated_at = %s WHERE id = %s AND %s",
+		s.table, STATUS_RUNNING, s.ph(1), s.ph(2), s.ph(3), s.dueCond(4),
+	), leaseUntil.UnixMilli(), ms, id, queue, ms, ms)
+	if err != nil {
+		return nil, fmt.Errorf("can not reserve job: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Taken by another process in between - try again next time.
+		return nil, nil
+	}
+
+	job, err := scanJob(tx.QueryRowContext(ctx, s.selectQuery()+" WHERE id = "+s.ph(1), id))
+	if err != nil {
+		return nil, fmt.Errorf("can not reserve job: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("can not reserve job: %w", err)
+	}
+	return job, nil
+}
+
+// Complete marks a reserved job done - see IStore.
+func (s *SQLStore) Complete(ctx context.Context, job *Job) error {
+	return s.execLeased(ctx, job, fmt.Sprintf("status = '%s', unique_lock = NULL", STATUS_DONE))
+}
+
+// Fail records a reserved job's failed attempt - see IStore.
+func (s *SQLStore) Fail(ctx context.Context, job *Job, errMsg string, retryAt time.Time) error {
+	if retryAt.IsZero() {
+		return s.execLeased(ctx, job, fmt.Sprintf("status = '%s', unique_lock = NULL, last_error = %s", STATUS_DEAD, s.ph(1)), errMsg)
+	}
+	return s.execLeased(ctx, job, fmt.Sprintf("status = '%s', run_at = %s, last_error = %s", STATUS_PENDING, s.ph(1), s.ph(2)), retryAt.UnixMilli(), errMsg)
+}
+
+// Release puts a reserved job back to pending - see IStore.
+func (s *SQLStore) Release(ctx context.Context, job *Job) error {
+	return s.execLeased(ctx, job, fmt.Sprintf("status = '%s', attempts = attempts - 1", STATUS_PENDING))
+}
+
+// Get returns the job - see IStore.
+func (s *SQLStore) Get(ctx context.Context, id int64) (*Job, error) {
+	job, err := scanJob(s.db().QueryRowContext(ctx, s.selectQuery()+" WHERE id = "+s.ph(1), id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not get job %d: %w", id, err)
+	}
+	return job, nil
+}
+
+// List returns the jobs matching f, newest first - see IStore.
+func (s *SQLStore) List(ctx context.Context, f Filter) ([]*Job, error) {
+	var conds []string
+	var args []any
+	if f.Queue != "" {
+		args = append(args, f.Queue)
+		conds = append(conds, "queue = "+s.ph(len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conds = append(conds, "status = "+s.ph(len(args)))
+	}
+	query := s.selectQuery()
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := s.db().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("can not list jobs: %w", err)
+	}
+	defer rows.Close()
+	list := make([]*Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("can not list jobs: %w", err)
+		}
+		list = append(list, job)
+	}
+	return list, rows.Err()
+}
+
+// Retry makes a dead job pending again - see IStore.
+func (s *SQLStore) Retry(ctx context.Context, id int64) error {
+	job, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if job.Status != STATUS_DEAD {
+		return fmt.Errorf("job %d is %s, not %s", id, job.Status, STATUS_DEAD)
+	}
+	if job.UniqueKey != "" && s.isLocked(ctx, job.UniqueKey) {
+		return ErrDuplicate
+	}
+
+	err = s.exec(ctx, id, fmt.Sprintf("status = '%s', attempts = 0, run_at = %s, unique_lock = %s", STATUS_PENDING, s.ph(1), s.ph(2)),
+		time.Now().UnixMilli(), lockValue(job.UniqueKey))
+	if err != nil && job.UniqueKey != "" && s.isLocked(ctx, job.UniqueKey) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+// Purge deletes finished jobs - see IStore.
+func (s *SQLStore) Purge(ctx context.Context, status string, before time.Time) (int, error) {
+	if status != STATUS_DONE && status != STATUS_DEAD {
+		return 0, fmt.Errorf("only %s and %s jobs can be purged", STATUS_DONE, STATUS_DEAD)
+	}
+	res, err := s.db().ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE status = %s AND updated_at < %s", s.table, s.ph(1), s.ph(2)),
+		status, before.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("can not purge jobs: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Migration returns the "up" and "down" SQL statements creating and
+// dropping the jobs table for driver - see WriteMigration.
+func Migration(driver, table string) (up, down []string) {
+	if table == "" {
+		table = DEFAULT_TABLE
+	}
+	var id, text string
+	switch driver {
+	case lxApp.DriverMysql:
+		id, text = "BIGINT AUTO_INCREMENT PRIMARY KEY", "LONGTEXT"
+	case lxApp.DriverSqlite:
+		id, text = "INTEGER PRIMARY KEY AUTOINCREMENT", "TEXT"
+	default:
+		id, text = "BIGSERIAL PRIMARY KEY", "TEXT"
+	}
+
+	up = []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+  id %s,
+  queue VARCHAR(255) NOT NULL,
+  type VARCHAR(255) NOT NULL,
+  payload %s NOT NULL,
+  unique_key VARCHAR(255) NOT NULL,
+  unique_lock VARCHAR(255) NULL,
+  status VARCHAR(16) NOT NULL,
+  attempts INT NOT NULL,
+  max_attempts INT NOT NULL,
+  run_at BIGINT NOT NULL,
+  reserved_until BIGINT NOT NULL,
+  last_error %s NOT NULL,
+  created_at BIGINT NOT NULL,
+  updated_at BIGINT NOT NULL
+)`, table, id, text, text),
+		fmt.Sprintf("CREATE UNIQUE INDEX %s_unique_lock_idx ON %s (unique_lock)", table, table),
+		fmt.Sprintf("CREATE INDEX %s_due_idx ON %s (queue, status, run_at)", table, table),
+	}
+	down = []string{fmt.Sprintf("DROP TABLE %s", table)}
+	return up, down
+}
+
+// WriteMigration writes the migration creating the jobs table for driver
+// into dir, named the way lxgo/migrator expects - then apply it with
+// "migrator:up". Returns the file's path.
+func WriteMigration(dir, driver, table string) (string, error) {
+	up, down := Migration(driver, table)
+
+	var b strings.Builder
+	b.WriteString("name: create_jobs_table\ntype: query\n\nup:\n")
+	writeYamlList(&b, up)
+	b.WriteString("\ndown:\n")
+	writeYamlList(&b, down)
+
+	timestamp := time.Now().UTC().Format("20060102150405.000")
+	path := filepath.Join(dir, timestamp+"_create_jobs_table.yaml")
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("can not write migration: %w", err)
+	}
+	return path, nil
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+const jobColumns = "id, queue, type, payload, unique_key, status, attempts, max_attempts, run_at, reserved_until, last_error, created_at, updated_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	var (
+		job                  Job
+		payload              string
+		runAt, reservedUntil int64
+		createdAt, updatedAt int64
+	)
+	err := row.Scan(&job.ID, &job.Queue, &job.Type, &payload, &job.UniqueKey, &job.Status,
+		&job.Attempts, &job.MaxAttempts, &runAt, &reservedUntil, &job.LastError, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Payload = []byte(payload)
+	job.RunAt = time.UnixMilli(runAt)
+	if reservedUntil > 0 {
+		job.ReservedUntil = time.UnixMilli(reservedUntil)
+	}
+	job.CreatedAt = time.UnixMilli(createdAt)
+	job.UpdatedAt = time.UnixMilli(updatedAt)
+	return &job, nil
+}
+
+func (s *SQLStore) db() *sql.DB {
+	return s.conn.DB()
+}
+
+func (s *SQLStore) driver() string {
+	return s.conn.Driver()
+}
+
+// ph returns the driver's bind parameter for the i-th (1-based) value.
+func (s *SQLStore) ph(i int) string {
+	if s.driver() == lxApp.DriverMysql {
+		return "?"
+	}
+	return fmt.Sprintf("$%d", i)
+}
+
+func (s *SQLStore) selectQuery() string {
+	return fmt.Sprintf("SELECT %s FROM %s", jobColumns, s.table)
+}
+
+// dueCond returns the condition matching the jobs of a queue due at a
+// time - binding the queue, then the time twice, from the from-th parameter.
+func (s *SQLStore) dueCond(from int) string {
+	return fmt.Sprintf("queue = %s AND ((status = '%s' AND run_at <= %s) OR (status = '%s' AND reserved_until <= %s))",
+		s.ph(from), STATUS_PENDING, s.ph(from+1), STATUS_RUNNING, s.ph(from+2))
+}
+
+// exec runs "UPDATE table SET <set> WHERE id = <id>", args binding set's
+// parameters.
+func (s *SQLStore) exec(ctx context.Context, id int64, set string, args ...any) error {
+	n, err := s.update(ctx, id, set, args, "")
+	if err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+// execLeased runs exec for a job as Reserve returned it, only while it's
+// still running under that reservation (same attempt, same lease) -
+// ErrLeaseLost otherwise.
+func (s *SQLStore) execLeased(ctx context.Context, job *Job, set string, args ...any) error {
+	i := len(args) + 2
+	cond := fmt.Sprintf(" AND status = '%s' AND attempts = %s AND reserved_until = %s", STATUS_RUNNING, s.ph(i+1), s.ph(i+2))
+	n, err := s.update(ctx, job.ID, set, args, cond, job.Attempts, job.ReservedUntil.UnixMilli())
+	if err == nil && n == 0 {
+		return ErrLeaseLost
+	}
+	return err
+}
+
+// update runs "UPDATE table SET <set> WHERE id = <id><cond>", args binding
+// set's parameters and condArgs cond's (from the len(args)+3-th on), and
+// returns how many jobs were updated.
+func (s *SQLStore) update(ctx context.Context, id int64, set string, args []any, cond string, condArgs ...any) (int64, error) {
+	query := fmt.Sprintf("UPDATE %s SET %s, updated_at = %s WHERE id = %s%s", s.table, set, s.ph(len(args)+1), s.ph(len(args)+2), cond)
+	args = append(append(args, time.Now().UnixMilli(), id), condArgs...)
+	res, err := s.db().ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("can not update job %d: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		// The driver can't tell - take it as updated.
+		return 1, nil
+	}
+	return n, nil
+}
+
+func (s *SQLStore) isLocked(ctx context.Context, key string) bool {
+	var count int
+	err := s.db().QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE unique_lock = %s", s.table, s.ph(1)), key).Scan(&count)
+	return err == nil && count > 0
+}
+
+func lockValue(key string) any {
+	if key == "" {
+		return nil
+	}
+	return key
+}
+
+func writeYamlList(b *strings.Builder, list []string) {
+	for _, stmt := range list {
+		b.WriteString("  - |\n")
+		for _, line := range strings.Split(stmt, "\n") {
+			b.WriteString("    " + line + "\n")
+		}
+	}
+}
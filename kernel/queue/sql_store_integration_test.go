@@ -0,0 +1,42 @@
+//go:build integration
+
+package queue_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/queue"
+)
+
+func TestSQLStore(t *testing.T) {
+	dsn := os.Getenv("LXGO_QUEUE_TEST_DSN")
+	if dsn == "" {
+		t.Skip("LXGO_QUEUE_TEST_DSN is not set")
+	}
+	conn := lxApp.NewConnection()
+	conn.SetConfig(kernel.Dict{"DSN": dsn})
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer conn.Close()
+
+	up, down := queue.Migration(conn.Driver(), "lxgo_jobs_test")
+	for _, stmt := range down {
+		conn.DB().Exec(stmt)
+	}
+	for _, stmt := range up {
+		if _, err := conn.DB().Exec(stmt); err != nil {
+			t.Fatalf("migration: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		for _, stmt := range down {
+			conn.DB().Exec(stmt)
+		}
+	})
+
+	testStore(t, queue.NewSQLStore(conn, "lxgo_jobs_test"))
+}
@@ -0,0 +1,120 @@
+package queue_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	"github.com/epicoon/lxgo/kernel/queue"
+	"gopkg.in/yaml.v3"
+)
+
+// newSqliteApp builds an app whose main connection is a migrated SQLite
+// file DB, with the queue component storing its jobs there.
+func newSqliteApp(t *testing.T, path string) (kernel.IApp, *queue.Component) {
+	t.Helper()
+	a, err := apptest.New(kernel.Dict{
+		"Database":   kernel.Dict{"Driver": "sqlite", "DBName": path, "MaxOpenConns": 1},
+		"Components": kernel.Dict{"Queue": kernel.Dict{"Store": queue.STORE_DB, "Workers": 1}},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	conn := a.Connection()
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	up, _ := queue.Migration(conn.Driver(), queue.DEFAULT_TABLE)
+	for _, stmt := range up {
+		if _, err := conn.DB().Exec(stmt); err != nil {
+			t.Fatalf("migration: %v", err)
+		}
+	}
+
+	return a, apptest.SetComponent(t, a, "Components.Queue", queue.SetAppComponent, queue.AppComponent)
+}
+
+func TestSQLStore_Sqlite(t *testing.T) {
+	a, q := newSqliteApp(t, filepath.Join(t.TempDir(), "queue.db"))
+	defer a.Final()
+
+	testStore(t, q.Store())
+}
+
+func TestSQLStore_FinalReleasesRunningJob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+	a, q := newSqliteApp(t, path)
+
+	started := make(chan struct{})
+	q.Handle("slow", func(ctx context.Context, job *queue.Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err := q.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	job, err := q.Enqueue(context.Background(), "slow", nil)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the job to start")
+	}
+
+	a.Final()
+
+	// The app's connection is closed by now - reopen the file to see what
+	// the worker left.
+	conn := lxApp.NewConnection()
+	conn.SetConfig(kernel.Dict{"Driver": "sqlite", "DBName": path})
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer conn.Close()
+	got, err := queue.NewSQLStore(conn, queue.DEFAULT_TABLE).Get(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != queue.STATUS_PENDING {
+		t.Fatalf("expected the running job released to its queue, got %s", got.Status)
+	}
+}
+
+func TestWriteMigration(t *testing.T) {
+	dir := t.TempDir()
+	path, err := queue.WriteMigration(dir, "mysql", "jobs")
+	if err != nil {
+		t.Fatalf("WriteMigration: %v", err)
+	}
+	if !strings.HasSuffix(path, "_create_jobs_table.yaml") {
+		t.Fatalf("expected a migrator-style file name, got %s", path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var mig struct {
+		Up   []string `yaml:"up"`
+		Down []string `yaml:"down"`
+	}
+	if err := yaml.Unmarshal(content, &mig); err != nil {
+		t.Fatalf("expected valid YAML, got %v:\n%s", err, content)
+	}
+	up, down := queue.Migration("mysql", "jobs")
+	if len(mig.Up) != len(up) || strings.TrimSpace(mig.Up[0]) != up[0] || strings.TrimSpace(mig.Down[0]) != down[0] {
+		t.Fatalf("expected the migration's statements, got %+v", mig)
+	}
+	if !strings.Contains(up[0], "AUTO_INCREMENT") {
+		t.Fatalf("expected MySQL's DDL, got %s", up[0])
+	}
+}
@@ -0,0 +1,129 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel/queue"
+)
+
+// testStore runs the IStore contract against s, which must be empty.
+func testStore(t *testing.T, s queue.IStore) {
+	ctx := context.Background()
+	now := time.Now()
+	lease := now.Add(time.Minute)
+
+	later := &queue.Job{Queue: "mail", Type: "send", Payload: []byte(`{"to":"a@b.c"}`), MaxAttempts: 3, RunAt: now.Add(time.Hour)}
+	first := &queue.Job{Queue: "mail", Type: "send", MaxAttempts: 3, UniqueKey: "user:1"}
+	other := &queue.Job{Queue: "reports", Type: "build", MaxAttempts: 1}
+	for _, j := range []*queue.Job{later, first, other} {
+		if err := s.Push(ctx, j); err != nil {
+			t.Fatalf("Push: %v", err)
+		}
+	}
+	if first.ID == 0 || first.ID == later.ID || first.Status != queue.STATUS_PENDING {
+		t.Fatalf("expected Push to set the ID and status, got %+v", first)
+	}
+	if err := s.Push(ctx, &queue.Job{Queue: "mail", Type: "send", UniqueKey: "user:1"}); !errors.Is(err, queue.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+
+	job, err := s.Reserve(ctx, "mail", time.Now(), lease)
+	if err != nil || job == nil || job.ID != first.ID || job.Status != queue.STATUS_RUNNING || job.Attempts != 1 {
+		t.Fatalf("expected the due job to be reserved, got %+v, %v", job, err)
+	}
+	if job, err := s.Reserve(ctx, "mail", time.Now(), lease); job != nil || err != nil {
+		t.Fatalf("expected no more due jobs, got %+v, %v", job, err)
+	}
+
+	// A lost worker's job is due again once its lease runs out, and the
+	// lost worker can't finish it anymore.
+	lost := job
+	job, _ = s.Reserve(ctx, "mail", lease, lease.Add(time.Minute))
+	if job == nil || job.ID != first.ID || job.Attempts != 2 {
+		t.Fatalf("expected the job with an expired lease to be reserved again, got %+v", job)
+	}
+	if err := s.Complete(ctx, lost); !errors.Is(err, queue.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if err := s.Release(ctx, job); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := s.Fail(ctx, job, "late", time.Time{}); !errors.Is(err, queue.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	job, _ = s.Reserve(ctx, "mail", time.Now(), lease)
+	if job == nil || job.Attempts != 2 {
+		t.Fatalf("expected a released job not to count the attempt, got %+v", job)
+	}
+	if err := s.Release(ctx, lost); !errors.Is(err, queue.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+
+	retryAt := time.Now().Add(30 * time.Minute)
+	if err := s.Fail(ctx, job, "smtp down", retryAt); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	if job, _ := s.Reserve(ctx, "mail", time.Now(), lease); job != nil {
+		t.Fatalf("expected the failed job to wait for its retry, got %+v", job)
+	}
+	job, _ = s.Reserve(ctx, "mail", retryAt, lease)
+	if job == nil || job.ID != first.ID || job.LastError != "smtp down" {
+		t.Fatalf("expected the failed job to be retried, got %+v", job)
+	}
+	if err := s.Fail(ctx, job, "smtp still down", time.Time{}); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	if job, _ := s.Get(ctx, first.ID); job.Status != queue.STATUS_DEAD || job.LastError != "smtp still down" {
+		t.Fatalf("expected the job to be dead, got %+v", job)
+	}
+
+	// A dead job frees its unique key, and can't take it back while it's held.
+	dup := &queue.Job{Queue: "mail", Type: "send", UniqueKey: "user:1"}
+	if err := s.Push(ctx, dup); err != nil {
+		t.Fatalf("expected the unique key to be free, got %v", err)
+	}
+	if err := s.Retry(ctx, first.ID); !errors.Is(err, queue.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+	job, _ = s.Reserve(ctx, "mail", time.Now(), lease)
+	if err := s.Complete(ctx, job); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if err := s.Retry(ctx, first.ID); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	if err := s.Retry(ctx, first.ID); err == nil {
+		t.Fatal("expected only dead jobs to be retried")
+	}
+	if job, _ := s.Get(ctx, first.ID); job.Status != queue.STATUS_PENDING || job.Attempts != 0 {
+		t.Fatalf("expected the retried job to be pending, got %+v", job)
+	}
+
+	list, err := s.List(ctx, queue.Filter{Queue: "mail"})
+	if err != nil || len(list) != 3 || list[0].ID != dup.ID || string(list[2].Payload) != `{"to":"a@b.c"}` {
+		t.Fatalf("expected the mail jobs, newest first, got %+v, %v", list, err)
+	}
+	if list, _ := s.List(ctx, queue.Filter{Status: queue.STATUS_DONE, Limit: 1}); len(list) != 1 || list[0].ID != dup.ID {
+		t.Fatalf("expected the done job, got %+v", list)
+	}
+
+	if n, err := s.Purge(ctx, queue.STATUS_DONE, time.Now().Add(-time.Hour)); n != 0 || err != nil {
+		t.Fatalf("expected no job finished an hour ago, got %d, %v", n, err)
+	}
+	if n, err := s.Purge(ctx, queue.STATUS_DONE, time.Now().Add(time.Second)); n != 1 || err != nil {
+		t.Fatalf("expected the done job to be purged, got %d, %v", n, err)
+	}
+	if _, err := s.Purge(ctx, queue.STATUS_PENDING, time.Now()); err == nil {
+		t.Fatal("expected pending jobs not to be purged")
+	}
+	if _, err := s.Get(ctx, dup.ID); !errors.Is(err, queue.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, queue.NewMemoryStore())
+}
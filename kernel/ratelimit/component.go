@@ -0,0 +1,433 @@
+// Package ratelimit provides a rate limiting component for lxgo/kernel
+// applications - limits per route from config, keyed by client IP, route,
+// user or any custom function, counted by a sliding window or a token
+// bucket in an IStore (see MemoryStore).
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+)
+
+// APP_COMPONENT_KEY is the key Component registers itself under - see
+// SetAppComponent/AppComponent.
+const APP_COMPONENT_KEY = "lxgo_ratelimit"
+
+// KEY_IP keys the requests by client IP - see Rule.Key.
+const KEY_IP = "ip"
+
+// KEY_ROUTE keys the requests by method and route - see Rule.Key.
+const KEY_ROUTE = "route"
+
+// KEY_USER keys the requests by the user the app identifies with the key
+// function set via Component.SetKeyFunc - see Rule.Key.
+const KEY_USER = "user"
+
+// DefaultPeriod is a rule's period, in seconds, unless it says otherwise.
+const DefaultPeriod = 60
+
+// FKey returns the part of a request's rate limit key it's named for in
+// Rule.Key - "" falls back to the client IP. See Component.SetKeyFunc.
+type FKey func(ctx kernel.IHandleContext) string
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Config
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// Rule is a rate limit applied to some requests - its unset fields take
+// Config's.
+type Rule struct {
+	// Algorithm is ALGORITHM_SLIDING_WINDOW (the default) or
+	// ALGORITHM_TOKEN_BUCKET.
+	Algorithm string
+	// Limit is how many requests are allowed per Period.
+	Limit int
+	// Period is the time Limit requests are allowed in, in seconds -
+	// DefaultPeriod if unset.
+	Period int
+	// Burst is the token bucket's size - Limit if unset.
+	Burst int
+	// Key lists, comma-separated, what the requests are counted by: KEY_IP
+	// (the default), KEY_ROUTE, KEY_USER or a key function's name (see
+	// Component.SetKeyFunc) - e.g. "ip,route" counts each client's requests
+	// to each route apart.
+	Key string
+	// Disabled exempts the rule's requests from any limit.
+	Disabled bool
+}
+
+/** @interface kernel.IAppComponentConfig */
+
+// Config is Component's app-component configuration - its Rule applies to
+// every request not matching one of Routes, if its Limit is set.
+type Config struct {
+	*lxApp.ComponentConfig
+	Algorithm string
+	Limit     int
+	Period    int
+	Burst     int
+	Key       string
+	// Routes maps routes to their own rules - a registered route (e.g.
+	// "/users/{id:int}") or a path prefix ending with "*" (e.g. "/api/*"),
+	// optionally with a method, e.g. "/login[POST]". The most specific
+	// match applies: the route with the method, the route, then the longest
+	// prefix.
+	Routes map[string]Rule
+	// TrustProxy takes the client IP from X-Forwarded-For/X-Real-IP - only
+	// set it behind a proxy setting them.
+	TrustProxy bool
+	// ProxyHops is how many trusted proxies append to X-Forwarded-For - the
+	// client IP is the entry the outermost one appended, ProxyHops from the
+	// right, as the entries before it are the client's own. 1 if unset.
+	ProxyHops int
+}
+
+/** @constructor kernel.CAppComponentConfig */
+
+// NewConfig constructs a Config.
+func NewConfig() kernel.IAppComponentConfig {
+	return &Config{ComponentConfig: lxApp.NewComponentConfigStruct()}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Component
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponent */
+
+// Component limits the rate of the app's requests per its Config - see
+// SetAppComponent to register it on an app. A request over its limit gets
+// a 429 with Retry-After, and every limited one the X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset (seconds) headers.
+type Component struct {
+	*lxApp.AppComponent
+
+	store    IStore
+	keyFuncs map[string]FKey
+	exact    map[string]*namedRule
+	prefixes []*namedRule
+	fallback *namedRule
+}
+
+var _ kernel.IAppComponent = (*Component)(nil)
+
+// SetAppComponent registers a new Component on app under
+// APP_COMPONENT_KEY, configured from the config section named by configKey.
+func SetAppComponent(app kernel.IApp, configKey string) error {
+	if app.HasComponent(APP_COMPONENT_KEY) {
+		return fmt.Errorf("the application already has component: %s", APP_COMPONENT_KEY)
+	}
+
+	c := NewComponent()
+	if err := lxApp.InitComponent(c, app, configKey); err != nil {
+		return fmt.Errorf("can not init rate limit component: %s", err)
+	}
+	if err := c.init(); err != nil {
+		return fmt.Errorf("can not init rate limit component: %s", err)
+	}
+
+	app.SetComponent(APP_COMPONENT_KEY, c)
+	return nil
+}
+
+// AppComponent returns the Component registered on app under APP_COMPONENT_KEY.
+func AppComponent(app kernel.IApp) (*Component, error) {
+	c := app.Component(APP_COMPONENT_KEY)
+	if c == nil {
+		return nil, fmt.Errorf("application component '%s' not found", APP_COMPONENT_KEY)
+	}
+
+	cc, ok := c.(*Component)
+	if !ok {
+		return nil, fmt.Errorf("application component '%s' is not '*ratelimit.Component'", APP_COMPONENT_KEY)
+	}
+
+	return cc, nil
+}
+
+/** @constructor */
+
+// NewComponent constructs a Component counting in a MemoryStore.
+func NewComponent() *Component {
+	return &Component{
+		AppComponent: lxApp.NewAppComponent(),
+		store:        NewMemoryStore(),
+		keyFuncs:     make(map[string]FKey),
+	}
+}
+
+// Name returns the component's name - see kernel.IAppComponent.
+func (c *Component) Name() string {
+	return "RateLimiter"
+}
+
+// LogCategory returns the category the component's log methods write under.
+func (c *Component) LogCategory() string {
+	return "RateLimiter"
+}
+
+// CConfig returns Config's constructor - see kernel.IAppComponent.
+func (c *Component) CConfig() kernel.CAppComponentConfig {
+	return NewConfig
+}
+
+// Config returns the component's bound Config.
+func (c *Component) Config() *Config {
+	return (c.GetConfig()).(*Config)
+}
+
+// Store returns the store the requests are counted in.
+func (c *Component) Store() IStore {
+	return c.store
+}
+
+// SetStore replaces the store the requests are counted in.
+func (c *Component) SetStore(s IStore) {
+	c.store = s
+}
+
+// SetKeyFunc sets the key function name stands for in Rule.Key - e.g.
+// KEY_USER's, returning the signed-in user's ID.
+func (c *Component) SetKeyFunc(name string, f FKey) {
+	c.keyFuncs[name] = f
+}
+
+// Middleware returns a middleware limiting the requests it runs for per
+// rule, its unset fields taking Config's - for use with
+// IRouteGroup.AddWrapMiddleware. name scopes its counters.
+func (c *Component) Middleware(name string, rule Rule) kernel.FWrapMiddleware {
+	nr := c.namedRule(name, rule)
+	return func(ctx kernel.IHandleContext, next kernel.FNextHandler) kernel.IHttpResponse {
+		return c.limit(ctx, nr, next)
+	}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// namedRule is a Rule resolved against Config - name scopes its counters.
+type namedRule struct {
+	name     string
+	method   string
+	prefix   string
+	disabled bool
+	limit    Limit
+	keys     []string
+}
+
+// init resolves the config's rules and registers the router-wide
+// middleware applying them.
+func (c *Component) init() error {
+	conf := c.Config()
+	if conf.Limit > 0 {
+		c.fallback = c.namedRule("*", Rule{})
+		if err := checkAlgorithm(c.fallback.limit.Algorithm); err != nil {
+			return err
+		}
+	}
+
+	c.exact = make(map[string]*namedRule)
+	for route, rule := range conf.Routes {
+		if !rule.Disabled && rule.Limit <= 0 && conf.Limit <= 0 {
+			return fmt.Errorf("route '%s' has no limit", route)
+		}
+		nr := c.namedRule(route, rule)
+		if err := checkAlgorithm(nr.limit.Algorithm); err != nil {
+			return fmt.Errorf("route '%s': %s", route, err)
+		}
+		path := route
+		if i := strings.Index(route, "["); i != -1 && strings.HasSuffix(route, "]") {
+			path, nr.method = route[:i], strings.ToUpper(route[i+1:len(route)-1])
+		}
+		if prefix, ok := strings.CutSuffix(path, "*"); ok {
+			nr.prefix = prefix
+			c.prefixes = append(c.prefixes, nr)
+		} else {
+			c.exact[path+"["+nr.method+"]"] = nr
+		}
+	}
+	sort.Slice(c.prefixes, func(i, j int) bool {
+		pi, pj := c.prefixes[i], c.prefixes[j]
+		if len(pi.prefix) != len(pj.prefix) {
+			return len(pi.prefix) > len(pj.prefix)
+		}
+		return pi.method > pj.method
+	})
+
+	if c.fallback != nil || len(c.exact) > 0 || len(c.prefixes) > 0 {
+		c.App().Router().AddWrapMiddleware(func(ctx kernel.IHandleContext, next kernel.FNextHandler) kernel.IHttpResponse {
+			nr := c.match(ctx)
+			if nr == nil {
+				return next()
+			}
+			return c.limit(ctx, nr, next)
+		})
+	}
+	return nil
+}
+
+// namedRule resolves rule against Config.
+func (c *Component) namedRule(name string, rule Rule) *namedRule {
+	conf := c.Config()
+	if rule.Algorithm == "" {
+		rule.Algorithm = conf.Algorithm
+	}
+	if rule.Algorithm == "" {
+		rule.Algorithm = ALGORITHM_SLIDING_WINDOW
+	}
+	if rule.Limit <= 0 {
+		rule.Limit = conf.Limit
+		if rule.Burst <= 0 {
+			rule.Burst = conf.Burst
+		}
+	}
+	if rule.Period <= 0 {
+		rule.Period = conf.Period
+	}
+	if rule.Period <= 0 {
+		rule.Period = DefaultPeriod
+	}
+	if rule.Key == "" {
+		rule.Key = conf.Key
+	}
+	if rule.Key == "" {
+		rule.Key = KEY_IP
+	}
+
+	nr := &namedRule{
+		name:     name,
+		disabled: rule.Disabled,
+		limit: Limit{
+			Algorithm: rule.Algorithm,
+			Requests:  rule.Limit,
+			Period:    time.Duration(rule.Period) * time.Second,
+			Burst:     rule.Burst,
+		},
+	}
+	for _, key := range strings.Split(rule.Key, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			nr.keys = append(nr.keys, key)
+		}
+	}
+	return nr
+}
+
+// match returns the rule applying to ctx's request, if any.
+func (c *Component) match(ctx kernel.IHandleContext) *namedRule {
+	route, method := ctx.Route(), ctx.Method()
+	if nr, ok := c.exact[route+"["+method+"]"]; ok {
+		return nr
+	}
+	if nr, ok := c.exact[route+"[]"]; ok {
+		return nr
+	}
+	path := ctx.Request().URL.Path
+	for _, nr := range c.prefixes {
+		if (nr.method == "" || nr.method == method) && strings.HasPrefix(path, nr.prefix) {
+			return nr
+		}
+	}
+	return c.fallback
+}
+
+// limit counts ctx's request under nr, answering it with a 429 if it's
+// over the limit. The request is let through if the store fails.
+func (c *Component) limit(ctx kernel.IHandleContext, nr *namedRule, next kernel.FNextHandler) kernel.IHttpResponse {
+	if nr.disabled {
+		return next()
+	}
+
+	res, err := c.store.Take(ctx.Request().Context(), c.key(ctx, nr), nr.limit, time.Now())
+	if err != nil {
+		ctx.Logger().Warning("rate limit check failed", "rule", nr.name, "error", err.Error())
+		return next()
+	}
+
+	h := ctx.ResponseWriter().Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	if res.Allowed {
+		return next()
+	}
+
+	h.Set("Retry-After", strconv.Itoa(max(1, ceilSeconds(res.RetryAfter))))
+	ctx.Logger().Warning("rate limit exceeded", "rule", nr.name, "route", ctx.Route())
+	return lxHttp.ErrorResponse(http.StatusTooManyRequests, "Too Many Requests")
+}
+
+// key is "<rule name>|<key part>|..." - key functions returning "" and
+// unknown ones fall back to the client IP.
+func (c *Component) key(ctx kernel.IHandleContext, nr *namedRule) string {
+	parts := make([]string, 0, len(nr.keys)+1)
+	parts = append(parts, nr.name)
+	for _, name := range nr.keys {
+		var part string
+		switch name {
+		case KEY_IP:
+		case KEY_ROUTE:
+			part = ctx.Method() + " " + ctx.Route()
+		default:
+			if f, ok := c.keyFuncs[name]; ok {
+				part = f(ctx)
+			}
+			if part != "" {
+				part = name + ":" + part
+			}
+		}
+		if part == "" {
+			part = "ip:" + clientIP(ctx.Request(), c.Config().TrustProxy, c.Config().ProxyHops)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "|")
+}
+
+func checkAlgorithm(algorithm string) error {
+	if algorithm != ALGORITHM_SLIDING_WINDOW && algorithm != ALGORITHM_TOKEN_BUCKET {
+		return fmt.Errorf("unknown algorithm: %s", algorithm)
+	}
+	return nil
+}
+
+// clientIP returns r's client IP - from X-Forwarded-For/X-Real-IP if
+// trustProxy is set, see Config.ProxyHops.
+func clientIP(r *http.Request, trustProxy bool, hops int) string {
+	if trustProxy {
+		var forwarded []string
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			for _, ip := range strings.Split(value, ",") {
+				if ip = strings.TrimSpace(ip); ip != "" {
+					forwarded = append(forwarded, ip)
+				}
+			}
+		}
+		if len(forwarded) > 0 {
+			hops = max(hops, 1)
+			return forwarded[max(len(forwarded)-hops, 0)]
+		}
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
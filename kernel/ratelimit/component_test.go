@@ -0,0 +1,167 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+	"github.com/epicoon/lxgo/kernel/ratelimit"
+)
+
+type okResource struct {
+	*lxHttp.Resource
+}
+
+func (r *okResource) Run() kernel.IHttpResponse {
+	return r.JsonResponse(kernel.JsonResponseConfig{Data: kernel.Dict{"success": true}})
+}
+
+func newTestApp(t *testing.T, conf kernel.Dict) (kernel.IApp, *ratelimit.Component) {
+	t.Helper()
+	a, c := apptest.NewComponent(t, "RateLimit", conf, ratelimit.SetAppComponent, ratelimit.AppComponent)
+	cOk := func() kernel.IHttpResource {
+		return &okResource{Resource: lxHttp.NewResource()}
+	}
+	for _, route := range []string{"/login", "/signup", "/api/items", "/api/health", "/page"} {
+		a.Router().RegisterResource(route, "", cOk)
+	}
+	return a, c
+}
+
+func request(a kernel.IApp, method, path, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+	a.Router().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestComponent_RouteLimits(t *testing.T) {
+	a, _ := newTestApp(t, kernel.Dict{
+		"Limit": 100,
+		"Routes": kernel.Dict{
+			"/login[POST]":  kernel.Dict{"Limit": 2, "Period": 60},
+			"/api/*":        kernel.Dict{"Limit": 1, "Algorithm": "token_bucket"},
+			"/api/health":   kernel.Dict{"Disabled": true},
+			"/signup[POST]": kernel.Dict{"Limit": 1, "Key": "ip,route"},
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		if rec := request(a, "POST", "/login", "10.0.0.1"); rec.Code != http.StatusOK {
+			t.Fatalf("login %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := request(a, "POST", "/login", "10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the login limit, got %d", rec.Code)
+	}
+	h := rec.Header()
+	if h.Get("Retry-After") == "" || h.Get("X-RateLimit-Limit") != "2" || h.Get("X-RateLimit-Remaining") != "0" || h.Get("X-RateLimit-Reset") == "" {
+		t.Fatalf("expected the rate limit headers, got %v", h)
+	}
+	if rec := request(a, "POST", "/login", "10.0.0.2"); rec.Code != http.StatusOK {
+		t.Fatalf("expected another IP to have its own limit, got %d", rec.Code)
+	}
+	if rec := request(a, "GET", "/login", "10.0.0.1"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "100" {
+		t.Fatalf("expected GET /login under the default limit, got %d %v", rec.Code, rec.Header())
+	}
+
+	if rec := request(a, "GET", "/api/items", "10.0.0.1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the first api request allowed, got %d", rec.Code)
+	}
+	if rec := request(a, "GET", "/api/items", "10.0.0.1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the prefix rule to apply, got %d", rec.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if rec := request(a, "GET", "/api/health", "10.0.0.1"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("expected a disabled rule to exempt the route, got %d %v", rec.Code, rec.Header())
+		}
+	}
+}
+
+func TestComponent_TrustProxy(t *testing.T) {
+	a, _ := newTestApp(t, kernel.Dict{
+		"TrustProxy": true,
+		"ProxyHops":  2,
+		"Routes":     kernel.Dict{"/login[POST]": kernel.Dict{"Limit": 1}},
+	})
+	login := func(forwarded ...string) int {
+		req := httptest.NewRequest("POST", "/login", nil)
+		req.RemoteAddr = "10.0.0.2:1234"
+		for _, f := range forwarded {
+			req.Header.Add("X-Forwarded-For", f)
+		}
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// The client's own entries come first - rotating them changes nothing.
+	if code := login("1.1.1.1, 203.0.113.7, 10.0.0.1"); code != http.StatusOK {
+		t.Fatalf("expected the first login to pass, got %d", code)
+	}
+	if code := login("2.2.2.2", "203.0.113.7, 10.0.0.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected a spoofed leftmost entry not to reset the limit, got %d", code)
+	}
+	if code := login("203.0.113.8, 10.0.0.1"); code != http.StatusOK {
+		t.Fatalf("expected another client to pass, got %d", code)
+	}
+}
+
+func TestComponent_KeyFuncAndMiddleware(t *testing.T) {
+	a, c := newTestApp(t, kernel.Dict{
+		"Routes": kernel.Dict{"/page": kernel.Dict{"Limit": 1, "Key": "user"}},
+	})
+	c.SetKeyFunc(ratelimit.KEY_USER, func(ctx kernel.IHandleContext) string {
+		return ctx.Request().Header.Get("X-User")
+	})
+
+	send := func(user, ip string) int {
+		req := httptest.NewRequest("GET", "/page", nil)
+		req.RemoteAddr = ip + ":1234"
+		req.Header.Set("X-User", user)
+		rec := httptest.NewRecorder()
+		a.Router().ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if send("alice", "10.0.0.1") != http.StatusOK || send("bob", "10.0.0.1") != http.StatusOK {
+		t.Fatal("expected each user to have its own limit")
+	}
+	if send("alice", "10.0.0.2") != http.StatusTooManyRequests {
+		t.Fatal("expected a user's limit to follow them across IPs")
+	}
+	if send("", "10.0.0.3") != http.StatusOK || send("", "10.0.0.3") != http.StatusTooManyRequests {
+		t.Fatal("expected anonymous requests to be limited by IP")
+	}
+
+	g := a.Router().Group("/admin")
+	g.AddWrapMiddleware(c.Middleware("admin", ratelimit.Rule{Limit: 1}))
+	g.RegisterResource("/stats", "GET", func() kernel.IHttpResource {
+		return &okResource{Resource: lxHttp.NewResource()}
+	})
+	if rec := request(a, "GET", "/admin/stats", "10.0.0.1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the first admin request allowed, got %d", rec.Code)
+	}
+	if rec := request(a, "GET", "/admin/stats", "10.0.0.1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the group's limit to apply, got %d", rec.Code)
+	}
+}
+
+func TestComponent_InvalidConfig(t *testing.T) {
+	for name, conf := range map[string]kernel.Dict{
+		"no limit":          {"Routes": kernel.Dict{"/login": kernel.Dict{"Period": 10}}},
+		"unknown algorithm": {"Limit": 10, "Algorithm": "leaky"},
+	} {
+		a, err := apptest.New(kernel.Dict{"Components": kernel.Dict{"RateLimit": conf}})
+		if err != nil {
+			t.Fatalf("apptest.New: %v", err)
+		}
+		if err := ratelimit.SetAppComponent(a, "Components.RateLimit"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// memorySweepEvery is how many Takes a MemoryStore makes between sweeps of
+// its idle keys.
+const memorySweepEvery = 1000
+
+/** @interface IStore */
+
+// MemoryStore is an IStore counting the requests in memory - the limits
+// apply per process.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	takes   int
+}
+
+var _ IStore = (*MemoryStore)(nil)
+
+/** @constructor */
+
+// NewMemoryStore constructs an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+// Take counts a request for key - see IStore.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	if limit.Requests <= 0 || limit.Period <= 0 {
+		return Result{}, fmt.Errorf("invalid rate limit: %d requests per %s", limit.Requests, limit.Period)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.takes++
+	if s.takes%memorySweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	e, ok := s.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+	e.expires = now.Add(2 * limit.Period)
+	switch limit.Algorithm {
+	case ALGORITHM_TOKEN_BUCKET:
+		return e.tokenBucket(limit, now), nil
+	case ALGORITHM_SLIDING_WINDOW, "":
+		return e.slidingWindow(limit, now), nil
+	}
+	return Result{}, fmt.Errorf("unknown rate limit algorithm: %s", limit.Algorithm)
+}
+
+// Len returns the number of keys counted, idle ones not yet swept included.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+type memoryEntry struct {
+	expires time.Time
+
+	// token bucket
+	tokens float64
+	last   time.Time
+
+	// sliding window
+	window time.Time
+	prev   int
+	curr   int
+}
+
+// sweep drops the keys idle long enough to be back to their full limit -
+// s.mu must be held.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, e := range s.entries {
+		if now.After(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+func (e *memoryEntry) tokenBucket(limit Limit, now time.Time) Result {
+	capacity := float64(limit.Burst)
+	if capacity <= 0 {
+		capacity = float64(limit.Requests)
+	}
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+
+	if e.last.IsZero() {
+		e.tokens = capacity
+	} else if elapsed := now.Sub(e.last).Seconds(); elapsed > 0 {
+		e.tokens = math.Min(capacity, e.tokens+elapsed*rate)
+	}
+	e.last = now
+
+	res := Result{Limit: int(capacity)}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - e.tokens) / rate)
+	}
+	res.Remaining = int(e.tokens)
+	res.Reset = seconds((capacity - e.tokens) / rate)
+	return res
+}
+
+func (e *memoryEntry) slidingWindow(limit Limit, now time.Time) Result {
+	window := now.Truncate(limit.Period)
+	if !window.Equal(e.window) {
+		if window.Sub(e.window) == limit.Period {
+			e.prev = e.curr
+		} else {
+			e.prev = 0
+		}
+		e.curr = 0
+		e.window = window
+	}
+
+	elapsed := now.Sub(window)
+	weight := 1 - elapsed.Seconds()/limit.Period.Seconds()
+	count := float64(e.prev)*weight + float64(e.curr)
+
+	res := Result{Limit: limit.Requests, Reset: limit.Period - elapsed}
+	if count+1 <= float64(limit.Requests) {
+		e.curr++
+		count++
+		res.Allowed = true
+	} else if e.curr+1 <= limit.Requests {
+		// Wait for the previous window's share to drop enough.
+		needed := 1 - float64(limit.Requests-e.curr-1)/float64(e.prev)
+		res.RetryAfter = time.Duration(needed*float64(limit.Period)) - elapsed
+	} else {
+		// Wait for the next window, where this one's count is the share.
+		needed := 1 - float64(limit.Requests-1)/float64(e.curr)
+		res.RetryAfter = limit.Period - elapsed + time.Duration(needed*float64(limit.Period))
+	}
+	res.Remaining = max(0, limit.Requests-int(math.Ceil(count)))
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,80 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel/ratelimit"
+)
+
+func take(t *testing.T, s *ratelimit.MemoryStore, limit ratelimit.Limit, now time.Time) ratelimit.Result {
+	t.Helper()
+	res, err := s.Take(context.Background(), "k", limit, now)
+	if err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	return res
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Algorithm: ratelimit.ALGORITHM_SLIDING_WINDOW, Requests: 3, Period: time.Minute}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		res := take(t, s, limit, start.Add(time.Duration(i)*time.Second))
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("request %d: expected allowed with %d remaining, got %+v", i, 2-i, res)
+		}
+	}
+	res := take(t, s, limit, start.Add(10*time.Second))
+	if res.Allowed {
+		t.Fatal("expected the 4th request in the window to be denied")
+	}
+	if res.RetryAfter <= 0 || res.RetryAfter > 2*time.Minute {
+		t.Fatalf("unexpected RetryAfter: %s", res.RetryAfter)
+	}
+
+	// Half into the next window, half of the previous one's 3 requests still count.
+	if res := take(t, s, limit, start.Add(90*time.Second)); !res.Allowed {
+		t.Fatalf("expected a request allowed half into the next window, got %+v", res)
+	}
+	if res := take(t, s, limit, start.Add(91*time.Second)); res.Allowed {
+		t.Fatalf("expected the previous window's share to still count, got %+v", res)
+	}
+	// Two windows later nothing counts.
+	if res := take(t, s, limit, start.Add(3*time.Minute)); !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("expected a fresh window, got %+v", res)
+	}
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	limit := ratelimit.Limit{Algorithm: ratelimit.ALGORITHM_TOKEN_BUCKET, Requests: 1, Period: time.Second, Burst: 2}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if res := take(t, s, limit, now); !res.Allowed || res.Limit != 2 || res.Remaining != 1 {
+		t.Fatalf("expected a burst of 2, got %+v", res)
+	}
+	if res := take(t, s, limit, now); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("expected the 2nd burst request allowed, got %+v", res)
+	}
+	res := take(t, s, limit, now.Add(500*time.Millisecond))
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("expected a denial for 500ms, got %+v", res)
+	}
+	if res := take(t, s, limit, now.Add(time.Second)); !res.Allowed {
+		t.Fatalf("expected a refilled token, got %+v", res)
+	}
+}
+
+func TestMemoryStore_InvalidLimit(t *testing.T) {
+	s := ratelimit.NewMemoryStore()
+	if _, err := s.Take(context.Background(), "k", ratelimit.Limit{Period: time.Second}, time.Now()); err == nil {
+		t.Fatal("expected an error for a limit without requests")
+	}
+	if _, err := s.Take(context.Background(), "k", ratelimit.Limit{Algorithm: "leaky", Requests: 1, Period: time.Second}, time.Now()); err == nil {
+		t.Fatal("expected an error for an unknown algorithm")
+	}
+}
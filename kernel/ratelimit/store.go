@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// ALGORITHM_SLIDING_WINDOW counts the requests of a window sliding over
+// the last Limit.Period - approximated from the current and previous
+// fixed windows' counts.
+const ALGORITHM_SLIDING_WINDOW = "sliding_window"
+
+// ALGORITHM_TOKEN_BUCKET refills a bucket of Limit.Burst tokens at
+// Limit.Requests per Limit.Period, every request taking one - allowing
+// bursts after idle time.
+const ALGORITHM_TOKEN_BUCKET = "token_bucket"
+
+// Limit is a rate limit as a store applies it.
+type Limit struct {
+	// Algorithm is ALGORITHM_SLIDING_WINDOW or ALGORITHM_TOKEN_BUCKET.
+	Algorithm string
+	// Requests is how many requests are allowed per Period.
+	Requests int
+	// Period is the time Requests are allowed in.
+	Period time.Duration
+	// Burst is the token bucket's size - Requests if 0.
+	Burst int
+}
+
+// Result is a store's decision on a request.
+type Result struct {
+	// Allowed reports whether the request is within the limit.
+	Allowed bool
+	// Limit is how many requests the limit allows at once.
+	Limit int
+	// Remaining is how many more requests are allowed right now.
+	Remaining int
+	// Reset is how long until the limit is fully available again.
+	Reset time.Duration
+	// RetryAfter is, for a request not allowed, how long until one is.
+	RetryAfter time.Duration
+}
+
+// IStore counts the requests per key - see MemoryStore for the in-memory
+// one. An external one (e.g. Redis) implements it to share the limits
+// between processes.
+type IStore interface {
+	// Take counts a request for key at now under limit, returning whether
+	// it's allowed. A request not allowed isn't counted.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
@@ -0,0 +1,488 @@
+// Package scheduler provides a background job scheduler component for
+// lxgo/kernel applications - jobs run on cron expressions or at fixed
+// intervals, declared in code and/or the app's config, never overlapping
+// themselves unless allowed to, and can be listed and run by hand over
+// the manage socket.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+)
+
+// APP_COMPONENT_KEY is the key Component registers itself under - see
+// SetAppComponent/AppComponent.
+const APP_COMPONENT_KEY = "lxgo_scheduler"
+
+// MANAGE_COMMAND is the manage-socket command listing the jobs, or running
+// the one named by its "run" param - see kernel/cmd's ManageCommand "jobs" action.
+const MANAGE_COMMAND = "jobs"
+
+// DefaultStopTimeout is how long Final waits for running jobs unless
+// Config.StopTimeout says otherwise.
+const DefaultStopTimeout = 30 * time.Second
+
+// ErrRunning is returned by Component.RunJob for a job already running
+// that isn't allowed to overlap itself.
+var ErrRunning = errors.New("job is already running")
+
+// FJob is a job's work - ctx is canceled when the app stops.
+type FJob func(ctx context.Context) error
+
+// Job is a job declared in code - see Component.AddJob. A "Jobs" entry of
+// the same name in the component's config overrides its schedule.
+type Job struct {
+	Name string
+	// Cron is a cron expression - see ParseCron. Either it or Every is required.
+	Cron string
+	// Every is a fixed interval between runs.
+	Every time.Duration
+	// Jitter is the upper bound of a random delay added to every scheduled
+	// run, so instances of the app don't all run the job at once.
+	Jitter time.Duration
+	// AllowOverlap lets a run start while the previous one is still going -
+	// otherwise that run is skipped.
+	AllowOverlap bool
+	Run          FJob
+}
+
+// JobInfo is a job's state - see Component.Jobs.
+type JobInfo struct {
+	Name     string
+	Schedule string
+	Running  bool
+	// Next is when the job is scheduled to run next - zero if it isn't.
+	Next time.Time
+	// LastRun is when the latest run started - zero if there wasn't one.
+	LastRun time.Time
+	// LastDuration is how long the latest finished run took.
+	LastDuration time.Duration
+	// LastError is the latest finished run's error, if any.
+	LastError error
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Config
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// JobConfig is a "Jobs" entry of the component's config - durations are
+// in seconds.
+type JobConfig struct {
+	Cron         string
+	Every        int
+	Jitter       int
+	AllowOverlap bool
+	// Disabled keeps the job from being scheduled - it can still be run by
+	// hand.
+	Disabled bool
+}
+
+/** @interface kernel.IAppComponentConfig */
+
+// Config is Component's app-component configuration.
+type Config struct {
+	*lxApp.ComponentConfig
+	// Jobs configures jobs by name - see JobConfig.
+	Jobs map[string]JobConfig
+	// Location is the time zone cron expressions are read in, e.g.
+	// "Europe/Berlin" - the local one if unset.
+	Location string
+	// StopTimeout is how long Final waits for running jobs, in seconds -
+	// DefaultStopTimeout if unset.
+	StopTimeout int
+}
+
+/** @constructor kernel.CAppComponentConfig */
+
+// NewConfig constructs a Config.
+func NewConfig() kernel.IAppComponentConfig {
+	return &Config{ComponentConfig: lxApp.NewComponentConfigStruct()}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Component
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponent */
+
+// Component schedules the jobs added to it once the app runs, logging every
+// run through the app's logger, and stops them in Final - see
+// SetAppComponent to register it on an app.
+type Component struct {
+	*lxApp.AppComponent
+
+	mu       sync.Mutex
+	jobs     map[string]*jobState
+	location *time.Location
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+var _ kernel.IAppComponent = (*Component)(nil)
+
+// SetAppComponent registers a new Component on app under
+// APP_COMPONENT_KEY, configured from the config section named by configKey.
+func SetAppComponent(app kernel.IApp, configKey string) error {
+	if app.HasComponent(APP_COMPONENT_KEY) {
+		return fmt.Errorf("the application already has component: %s", APP_COMPONENT_KEY)
+	}
+
+	c := NewComponent()
+	if err := lxApp.InitComponent(c, app, configKey); err != nil {
+		return fmt.Errorf("can not init scheduler component: %s", err)
+	}
+	if err := c.init(); err != nil {
+		return fmt.Errorf("can not init scheduler component: %s", err)
+	}
+
+	app.SetComponent(APP_COMPONENT_KEY, c)
+	return nil
+}
+
+// AppComponent returns the Component registered on app under APP_COMPONENT_KEY.
+func AppComponent(app kernel.IApp) (*Component, error) {
+	c := app.Component(APP_COMPONENT_KEY)
+	if c == nil {
+		return nil, fmt.Errorf("application component '%s' not found", APP_COMPONENT_KEY)
+	}
+
+	s, ok := c.(*Component)
+	if !ok {
+		return nil, fmt.Errorf("application component '%s' is not '*scheduler.Component'", APP_COMPONENT_KEY)
+	}
+
+	return s, nil
+}
+
+/** @constructor */
+
+// NewComponent constructs a Component with no jobs.
+func NewComponent() *Component {
+	return &Component{
+		AppComponent: lxApp.NewAppComponent(),
+		jobs:         make(map[string]*jobState),
+		location:     time.Local,
+	}
+}
+
+// Name returns the component's name - see kernel.IAppComponent.
+func (c *Component) Name() string {
+	return "Scheduler"
+}
+
+// LogCategory returns the category the component's log methods write under.
+func (c *Component) LogCategory() string {
+	return "Scheduler"
+}
+
+// CConfig returns Config's constructor - see kernel.IAppComponent.
+func (c *Component) CConfig() kernel.CAppComponentConfig {
+	return NewConfig
+}
+
+// Config returns the component's bound Config.
+func (c *Component) Config() *Config {
+	return (c.GetConfig()).(*Config)
+}
+
+// AddJob adds a job, scheduling it right away if the app already runs.
+// Fails if the name is taken, or if the job (with its config applied) has
+// no valid schedule or no Run.
+func (c *Component) AddJob(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("a job needs a Name and a Run")
+	}
+
+	disabled := false
+	if conf, ok := c.Config().Jobs[job.Name]; ok {
+		if conf.Cron != "" || conf.Every > 0 {
+			job.Cron = conf.Cron
+			job.Every = time.Duration(conf.Every) * time.Second
+		}
+		if conf.Jitter > 0 {
+			job.Jitter = time.Duration(conf.Jitter) * time.Second
+		}
+		job.AllowOverlap = job.AllowOverlap || conf.AllowOverlap
+		disabled = conf.Disabled
+	}
+
+	js := &jobState{job: job, disabled: disabled}
+	switch {
+	case job.Cron != "":
+		schedule, err := ParseCron(job.Cron)
+		if err != nil {
+			return fmt.Errorf("job '%s': %w", job.Name, err)
+		}
+		js.schedule = schedule
+	case job.Every > 0:
+		js.schedule = Every(job.Every)
+	default:
+		return fmt.Errorf("job '%s' has neither Cron nor Every", job.Name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.jobs[job.Name]; exists {
+		return fmt.Errorf("job '%s' already added", job.Name)
+	}
+	c.jobs[job.Name] = js
+	if c.ctx != nil {
+		c.schedule(js)
+	}
+	return nil
+}
+
+// RunJob runs the job named name right away, in the background - failing
+// if there's no such job or it's running and mustn't overlap itself.
+func (c *Component) RunJob(name string) error {
+	c.mu.Lock()
+	js, ok := c.jobs[name]
+	ctx := c.ctx
+	c.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("job '%s' not found", name)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return c.start(ctx, js, "manual")
+}
+
+// Jobs returns the jobs' states, ordered by name.
+func (c *Component) Jobs() []JobInfo {
+	c.mu.Lock()
+	list := make([]*jobState, 0, len(c.jobs))
+	for _, js := range c.jobs {
+		list = append(list, js)
+	}
+	c.mu.Unlock()
+
+	infos := make([]JobInfo, 0, len(list))
+	for _, js := range list {
+		infos = append(infos, js.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Run schedules the jobs - see kernel.IAppComponent.
+func (c *Component) Run() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ctx != nil {
+		return nil
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	for _, js := range c.jobs {
+		c.schedule(js)
+	}
+	for name := range c.Config().Jobs {
+		if _, ok := c.jobs[name]; !ok {
+			c.LogWarning("Job '%s' is configured, but was never added", name)
+		}
+	}
+	return nil
+}
+
+// Final stops scheduling the jobs and waits up to Config.StopTimeout for
+// the running ones to finish - see kernel.IAppComponent.
+func (c *Component) Final() error {
+	c.mu.Lock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.mu.Unlock()
+
+	timeout := DefaultStopTimeout
+	if c.Config().StopTimeout > 0 {
+		timeout = time.Duration(c.Config().StopTimeout) * time.Second
+	}
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("jobs still running after %s", timeout)
+	}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+type jobState struct {
+	job      Job
+	schedule Schedule
+	disabled bool
+
+	mu           sync.Mutex
+	running      int
+	next         time.Time
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastError    error
+}
+
+func (js *jobState) info() JobInfo {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	schedule := js.job.Cron
+	if schedule == "" {
+		schedule = "every " + js.job.Every.String()
+	}
+	if js.disabled {
+		schedule += " (disabled)"
+	}
+	return JobInfo{
+		Name:         js.job.Name,
+		Schedule:     schedule,
+		Running:      js.running > 0,
+		Next:         js.next,
+		LastRun:      js.lastRun,
+		LastDuration: js.lastDuration,
+		LastError:    js.lastError,
+	}
+}
+
+// init reads the config's Location and registers the manage command.
+func (c *Component) init() error {
+	if c.Config().Location != "" {
+		loc, err := time.LoadLocation(c.Config().Location)
+		if err != nil {
+			return err
+		}
+		c.location = loc
+	}
+	return lxApp.RegisterManageCommand(c.App(), MANAGE_COMMAND, c.manage)
+}
+
+// schedule starts js's scheduling loop - c.mu must be held.
+func (c *Component) schedule(js *jobState) {
+	if js.disabled {
+		return
+	}
+	ctx := c.ctx
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for {
+			now := time.Now().In(c.location)
+			next := js.schedule.Next(now)
+			if next.IsZero() {
+				c.LogWarning("Job '%s' has no next run time", js.job.Name)
+				return
+			}
+			if js.job.Jitter > 0 {
+				next = next.Add(rand.N(js.job.Jitter))
+			}
+			js.mu.Lock()
+			js.next = next
+			js.mu.Unlock()
+
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				if err := c.start(ctx, js, "schedule"); err != nil {
+					c.Logger().Warning("job run skipped", "job", js.job.Name, "error", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+// start runs js in the background, unless it's running and mustn't overlap.
+func (c *Component) start(ctx context.Context, js *jobState, trigger string) error {
+	js.mu.Lock()
+	if js.running > 0 && !js.job.AllowOverlap {
+		js.mu.Unlock()
+		return ErrRunning
+	}
+	js.running++
+	js.lastRun = time.Now()
+	js.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		log := c.Logger().With("job", js.job.Name, "trigger", trigger)
+		log.Info("job started")
+
+		start := time.Now()
+		err := runJob(ctx, js.job.Run)
+		duration := time.Since(start)
+
+		js.mu.Lock()
+		js.running--
+		js.lastDuration = duration
+		js.lastError = err
+		js.mu.Unlock()
+
+		if err != nil {
+			log.Error("job failed", "duration", duration.String(), "error", err.Error())
+		} else {
+			log.Info("job finished", "duration", duration.String())
+		}
+	}()
+	return nil
+}
+
+func runJob(ctx context.Context, f FJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return f(ctx)
+}
+
+// manage handles MANAGE_COMMAND - lists the jobs, or runs the one named by
+// params["run"].
+func (c *Component) manage(params map[string]string) string {
+	if name := params["run"]; name != "" {
+		if err := c.RunJob(name); err != nil {
+			return fmt.Sprintf("Can not run job '%s': %s", name, err)
+		}
+		return fmt.Sprintf("Job '%s' started", name)
+	}
+
+	jobs := c.Jobs()
+	if len(jobs) == 0 {
+		return "No jobs"
+	}
+	var b strings.Builder
+	for _, j := range jobs {
+		fmt.Fprintf(&b, "%s [%s]", j.Name, j.Schedule)
+		if j.Running {
+			b.WriteString(" running")
+		}
+		if !j.Next.IsZero() {
+			fmt.Fprintf(&b, " next: %s", j.Next.Format(time.RFC3339))
+		}
+		if !j.LastRun.IsZero() {
+			fmt.Fprintf(&b, " last: %s", j.LastRun.Format(time.RFC3339))
+			if j.LastError != nil {
+				fmt.Fprintf(&b, " (failed: %s)", j.LastError)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,169 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	"github.com/epicoon/lxgo/kernel/scheduler"
+)
+
+func newScheduler(t *testing.T, conf kernel.Dict) *scheduler.Component {
+	t.Helper()
+	_, s := apptest.NewComponent(t, "Scheduler", conf, scheduler.SetAppComponent, scheduler.AppComponent)
+	return s
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestComponent_RunsJobsWithoutOverlap(t *testing.T) {
+	s := newScheduler(t, kernel.Dict{})
+
+	var runs, active, maxActive atomic.Int32
+	release := make(chan struct{})
+	err := s.AddJob(scheduler.Job{
+		Name:  "sync",
+		Every: 10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			n := active.Add(1)
+			if n > maxActive.Load() {
+				maxActive.Store(n)
+			}
+			runs.Add(1)
+			<-release
+			active.Add(-1)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+	if err := s.AddJob(scheduler.Job{Name: "sync", Every: time.Second, Run: func(context.Context) error { return nil }}); err == nil {
+		t.Fatal("expected a duplicate job name to be rejected")
+	}
+
+	if err := s.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	waitFor(t, func() bool { return runs.Load() == 1 })
+	time.Sleep(50 * time.Millisecond)
+	if runs.Load() != 1 {
+		t.Fatalf("expected runs to be skipped while the job is running, got %d", runs.Load())
+	}
+	if err := s.RunJob("sync"); !errors.Is(err, scheduler.ErrRunning) {
+		t.Fatalf("expected ErrRunning, got %v", err)
+	}
+
+	close(release)
+	waitFor(t, func() bool { return runs.Load() >= 3 })
+	if err := s.Final(); err != nil {
+		t.Fatalf("Final: %v", err)
+	}
+	if maxActive.Load() != 1 {
+		t.Fatalf("expected the job never to overlap itself, got %d concurrent runs", maxActive.Load())
+	}
+}
+
+func TestComponent_ConfigAndManualRun(t *testing.T) {
+	s := newScheduler(t, kernel.Dict{
+		"Jobs": kernel.Dict{
+			"report":  kernel.Dict{"Cron": "0 3 * * *"},
+			"cleanup": kernel.Dict{"Every": 1, "Disabled": true},
+		},
+	})
+
+	var reported atomic.Int32
+	if err := s.AddJob(scheduler.Job{Name: "report", Every: time.Millisecond, Run: func(context.Context) error {
+		reported.Add(1)
+		return errors.New("no data")
+	}}); err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+	var cleaned atomic.Int32
+	if err := s.AddJob(scheduler.Job{Name: "cleanup", Cron: "* * * * *", Run: func(context.Context) error {
+		cleaned.Add(1)
+		return nil
+	}}); err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+	if err := s.AddJob(scheduler.Job{Name: "broken", Cron: "* * *", Run: func(context.Context) error { return nil }}); err == nil {
+		t.Fatal("expected an invalid cron expression to be rejected")
+	}
+
+	if err := s.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if reported.Load() != 0 || cleaned.Load() != 0 {
+		t.Fatal("expected the config to override the jobs' schedules")
+	}
+
+	jobs := s.Jobs()
+	if len(jobs) != 2 || jobs[0].Schedule != "every 1s (disabled)" || !jobs[0].Next.IsZero() ||
+		jobs[1].Schedule != "0 3 * * *" || jobs[1].Next.Hour() != 3 {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+
+	if err := s.RunJob("cleanup"); err != nil {
+		t.Fatalf("RunJob: %v", err)
+	}
+	if err := s.RunJob("report"); err != nil {
+		t.Fatalf("RunJob: %v", err)
+	}
+	if err := s.RunJob("missing"); err == nil {
+		t.Fatal("expected an unknown job to fail")
+	}
+	waitFor(t, func() bool { return cleaned.Load() == 1 && reported.Load() == 1 })
+	waitFor(t, func() bool { return s.Jobs()[1].LastError != nil })
+	if s.Jobs()[1].LastError.Error() != "no data" {
+		t.Fatalf("expected the last error to be kept, got %v", s.Jobs()[1].LastError)
+	}
+	s.Final()
+}
+
+func TestComponent_FinalWaitsForJobs(t *testing.T) {
+	s := newScheduler(t, kernel.Dict{"StopTimeout": 1})
+
+	var finished atomic.Bool
+	started := make(chan struct{})
+	s.AddJob(scheduler.Job{Name: "flush", Every: time.Hour, Run: func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	}})
+	hold := make(chan struct{})
+	defer close(hold)
+	s.AddJob(scheduler.Job{Name: "stuck", Every: time.Hour, Run: func(ctx context.Context) error {
+		<-hold
+		return nil
+	}})
+	s.Run()
+	s.RunJob("flush")
+	<-started
+
+	if err := s.RunJob("stuck"); err != nil {
+		t.Fatalf("RunJob: %v", err)
+	}
+	err := s.Final()
+	if !finished.Load() {
+		t.Fatal("expected Final to cancel the running job and wait for it")
+	}
+	if err == nil || err.Error() != "jobs still running after 1s" {
+		t.Fatalf("expected Final to give up on the stuck job, got %v", err)
+	}
+}
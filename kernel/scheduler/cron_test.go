@@ -0,0 +1,50 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/epicoon/lxgo/kernel/scheduler"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	from := time.Date(2024, time.January, 31, 10, 17, 42, 0, time.UTC) // a Wednesday
+	cases := []struct {
+		expr string
+		next string
+	}{
+		{"* * * * *", "2024-01-31 10:18"},
+		{"*/15 * * * *", "2024-01-31 10:30"},
+		{"0 9-17 * * mon-fri", "2024-01-31 11:00"},
+		{"30 2 * * *", "2024-02-01 02:30"},
+		{"0 0 29 feb *", "2024-02-29 00:00"},
+		{"0 0 * * 7", "2024-02-04 00:00"},
+		{"0 0 1,15 * sun", "2024-02-01 00:00"},
+		{"5,10 0 * jun *", "2024-06-01 00:05"},
+		{"@monthly", "2024-02-01 00:00"},
+		{"@hourly", "2024-01-31 11:00"},
+	}
+	for _, c := range cases {
+		s, err := scheduler.ParseCron(c.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q): %v", c.expr, err)
+			continue
+		}
+		if next := s.Next(from).Format("2006-01-02 15:04"); next != c.next {
+			t.Errorf("%q: expected %s, got %s", c.expr, c.next, next)
+		}
+	}
+
+	s, _ := scheduler.ParseCron("0 0 30 2 *")
+	if !s.Next(from).IsZero() {
+		t.Error("expected no next time for February 30th")
+	}
+}
+
+func TestParseCron_Errors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "5-1 * * * *", "*/0 * * * *", "* * * * funday", "@often"} {
+		if _, err := scheduler.ParseCron(expr); err == nil {
+			t.Errorf("expected ParseCron(%q) to fail", expr)
+		}
+	}
+}
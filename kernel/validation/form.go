@@ -0,0 +1,81 @@
+package validation
+
+import (
+	"reflect"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/cast"
+)
+
+// CheckForm checks the rules of form f's fields given in data (a field
+// the data doesn't mention is left to f's Required), collecting an error
+// per failed field into into, coded with one of the ERR_* codes and
+// prefixed with the field's path ("name: ..." or, under a non-empty path,
+// "path.name: ..."). Fields holding nested forms are skipped - those are
+// checked as they are filled. Reports whether every field passed.
+func CheckForm(f kernel.IForm, data kernel.Dict, into kernel.IErrorsCollector, path string) bool {
+	v := reflect.ValueOf(f)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return true
+	}
+	return checkFields(v, f.Config(), data, into, path)
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+var formType = reflect.TypeOf((*kernel.IForm)(nil)).Elem()
+
+func checkFields(v reflect.Value, conf kernel.FormConfig, data kernel.Dict, into kernel.IErrorsCollector, path string) bool {
+	ok := true
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		if field.Anonymous {
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct && !checkFields(value, conf, data, into, path) {
+				ok = false
+			}
+			continue
+		}
+		if !field.IsExported() || field.Tag.Get("json") == "-" || isForm(field.Type) {
+			continue
+		}
+
+		name := cast.FieldName(field)
+		if !data.Has(name) {
+			continue
+		}
+		fullName := name
+		if path != "" {
+			fullName = path + "." + name
+		}
+
+		rules, err := FieldRules(field, conf[name])
+		if err != nil {
+			into.CollectErrorf("%s: invalid validation rules: %v", fullName, err)
+			ok = false
+			continue
+		}
+		if err := rules.Check(value.Interface()); err != nil {
+			into.CollectCodifiedErrorf(err.Code(), "%s: %s", fullName, err.Error())
+			ok = false
+		}
+	}
+	return ok
+}
+
+func isForm(t reflect.Type) bool {
+	return t.Implements(formType) || (t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(formType))
+}
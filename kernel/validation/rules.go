@@ -0,0 +1,308 @@
+// Package validation implements the declarative rules of form fields: a
+// rule set is given by kernel.FormFieldConfig.Rules and/or a field's
+// "validate" struct tag, and is checked by the form filler (see CheckForm)
+// right after a form is filled, so forms don't need to re-implement length,
+// range, format and enum checks in Validate/AfterFill.
+//
+// A rule set is a comma-separated list of rules:
+//
+//	min=N      numbers: the minimum value; strings, slices, maps: the minimum length
+//	max=N      numbers: the maximum value; strings, slices, maps: the maximum length
+//	len=N      strings, slices, maps: the exact length
+//	email      strings: a valid email address
+//	enum=a|b   strings, numbers: one of the listed values
+//	regex=RE   strings: must match RE - must be the last rule, as it takes
+//	           the rest of the rule set (commas included)
+//
+// String rules apply to each element of a slice of strings. String lengths
+// are counted in characters (runes).
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/errors"
+)
+
+// Error codes of failed rules - in the 9xx range so they don't clash with
+// applications' own codes (e.g. auth's start at 1001).
+const (
+	ERR_MIN uint = 901 + iota
+	ERR_MAX
+	ERR_LEN
+	ERR_EMAIL
+	ERR_ENUM
+	ERR_REGEX
+)
+
+// Rules is a parsed rule set - nil fields aren't checked.
+type Rules struct {
+	Min   *float64
+	Max   *float64
+	Len   *int
+	Email bool
+	Enum  []string
+	Regex string
+
+	re *regexp.Regexp
+}
+
+/** @constructor */
+
+// Parse parses a rule set, returning nil for an empty one.
+func Parse(s string) (*Rules, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if cached, ok := parsed.Load(s); ok {
+		return cached.(*Rules), nil
+	}
+
+	r := &Rules{}
+	rest := s
+	for rest != "" {
+		var item string
+		rest = strings.TrimLeft(rest, " ")
+		if strings.HasPrefix(rest, "regex=") {
+			item, rest = rest, ""
+		} else {
+			item, rest, _ = strings.Cut(rest, ",")
+		}
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(item, "=")
+		switch name {
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: a number expected", item)
+			}
+			if name == "min" {
+				r.Min = &n
+			} else {
+				r.Max = &n
+			}
+		case "len":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("rule %q: a non-negative integer expected", item)
+			}
+			r.Len = &n
+		case "email":
+			if hasValue {
+				return nil, fmt.Errorf("rule %q takes no value", item)
+			}
+			r.Email = true
+		case "enum":
+			if value == "" {
+				return nil, fmt.Errorf("rule %q: values expected", item)
+			}
+			r.Enum = strings.Split(value, "|")
+		case "regex":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %v", item, err)
+			}
+			r.Regex, r.re = value, re
+		default:
+			return nil, fmt.Errorf("unknown rule %q", item)
+		}
+	}
+
+	parsed.Store(s, r)
+	return r, nil
+}
+
+// FieldRules returns the rule set of a form field - its "validate" tag
+// merged with conf.Rules, the latter winning - or nil if it has none.
+func FieldRules(field reflect.StructField, conf kernel.FormFieldConfig) (*Rules, error) {
+	tagRules, err := Parse(field.Tag.Get("validate"))
+	if err != nil {
+		return nil, err
+	}
+	confRules, err := Parse(conf.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return tagRules.Merge(confRules), nil
+}
+
+// Merge returns a rule set with the rules of both r and other, other's
+// winning where both set the same rule. Either may be nil.
+func (r *Rules) Merge(other *Rules) *Rules {
+	if r == nil {
+		return other
+	}
+	if other == nil {
+		return r
+	}
+	res := *r
+	if other.Min != nil {
+		res.Min = other.Min
+	}
+	if other.Max != nil {
+		res.Max = other.Max
+	}
+	if other.Len != nil {
+		res.Len = other.Len
+	}
+	if other.Email {
+		res.Email = true
+	}
+	if other.Enum != nil {
+		res.Enum = other.Enum
+	}
+	if other.re != nil {
+		res.Regex, res.re = other.Regex, other.re
+	}
+	return &res
+}
+
+// String returns the rule set in its parseable form.
+func (r *Rules) String() string {
+	if r == nil {
+		return ""
+	}
+	var list []string
+	if r.Min != nil {
+		list = append(list, "min="+formatNumber(*r.Min))
+	}
+	if r.Max != nil {
+		list = append(list, "max="+formatNumber(*r.Max))
+	}
+	if r.Len != nil {
+		list = append(list, "len="+strconv.Itoa(*r.Len))
+	}
+	if r.Email {
+		list = append(list, "email")
+	}
+	if r.Enum != nil {
+		list = append(list, "enum="+strings.Join(r.Enum, "|"))
+	}
+	if r.re != nil {
+		list = append(list, "regex="+r.Regex)
+	}
+	return strings.Join(list, ",")
+}
+
+// Check checks value v against the rule set, returning the first failed
+// rule's error (coded with one of the ERR_* codes), or nil. A nil pointer
+// passes every rule - whether a value must be given at all is the form's
+// Required concern.
+func (r *Rules) Check(v any) kernel.IError {
+	if r == nil {
+		return nil
+	}
+	if code, msg := r.check(reflect.ValueOf(v)); code != 0 {
+		return errors.NewCodifiedError(code, msg)
+	}
+	return nil
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// parsed caches rule sets by their source - forms are filled per request.
+var parsed sync.Map
+
+func (r *Rules) check(v reflect.Value) (uint, string) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return 0, ""
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if code, msg := r.checkLen(utf8.RuneCountInString(s)); code != 0 {
+			return code, msg
+		}
+		return r.checkString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return r.checkNumber(float64(v.Int()), strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return r.checkNumber(float64(v.Uint()), strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		return r.checkNumber(v.Float(), formatNumber(v.Float()))
+	case reflect.Map:
+		return r.checkLen(v.Len())
+	case reflect.Slice, reflect.Array:
+		if code, msg := r.checkLen(v.Len()); code != 0 {
+			return code, msg
+		}
+		if v.Type().Elem().Kind() != reflect.String {
+			return 0, ""
+		}
+		for i := 0; i < v.Len(); i++ {
+			if code, msg := r.checkString(v.Index(i).String()); code != 0 {
+				return code, fmt.Sprintf("item %d %s", i, msg)
+			}
+		}
+	}
+	return 0, ""
+}
+
+func (r *Rules) checkLen(n int) (uint, string) {
+	if r.Len != nil && n != *r.Len {
+		return ERR_LEN, fmt.Sprintf("length must be exactly %d", *r.Len)
+	}
+	if r.Min != nil && float64(n) < *r.Min {
+		return ERR_MIN, fmt.Sprintf("length must be at least %s", formatNumber(*r.Min))
+	}
+	if r.Max != nil && float64(n) > *r.Max {
+		return ERR_MAX, fmt.Sprintf("length must be at most %s", formatNumber(*r.Max))
+	}
+	return 0, ""
+}
+
+func (r *Rules) checkString(s string) (uint, string) {
+	if r.Email {
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return ERR_EMAIL, "must be a valid email address"
+		}
+	}
+	if code, msg := r.checkEnum(s); code != 0 {
+		return code, msg
+	}
+	if r.re != nil && !r.re.MatchString(s) {
+		return ERR_REGEX, fmt.Sprintf("must match %q", r.Regex)
+	}
+	return 0, ""
+}
+
+func (r *Rules) checkNumber(n float64, s string) (uint, string) {
+	if r.Min != nil && n < *r.Min {
+		return ERR_MIN, fmt.Sprintf("must be at least %s", formatNumber(*r.Min))
+	}
+	if r.Max != nil && n > *r.Max {
+		return ERR_MAX, fmt.Sprintf("must be at most %s", formatNumber(*r.Max))
+	}
+	return r.checkEnum(s)
+}
+
+func (r *Rules) checkEnum(s string) (uint, string) {
+	if r.Enum != nil && !slices.Contains(r.Enum, s) {
+		return ERR_ENUM, fmt.Sprintf("must be one of: %s", strings.Join(r.Enum, ", "))
+	}
+	return 0, ""
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
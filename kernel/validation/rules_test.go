@@ -0,0 +1,79 @@
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel/validation"
+)
+
+func TestParse(t *testing.T) {
+	r, err := validation.Parse("min=3, max=20,email,enum=a|b,regex=^[a-z]{1,3}$")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *r.Min != 3 || *r.Max != 20 || !r.Email || len(r.Enum) != 2 || r.Regex != "^[a-z]{1,3}$" {
+		t.Fatalf("unexpected rules %+v", r)
+	}
+	if got := r.String(); got != "min=3,max=20,email,enum=a|b,regex=^[a-z]{1,3}$" {
+		t.Errorf("got %q", got)
+	}
+
+	if r, err := validation.Parse(" "); r != nil || err != nil {
+		t.Errorf("expected no rules for an empty set, got %v, %v", r, err)
+	}
+	for _, bad := range []string{"foo", "min=x", "len=-1", "email=1", "enum=", "regex=("} {
+		if _, err := validation.Parse(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestRulesCheck(t *testing.T) {
+	cases := []struct {
+		rules string
+		value any
+		want  uint
+	}{
+		{"min=3,max=5", "abc", 0},
+		{"min=3,max=5", "ab", validation.ERR_MIN},
+		{"min=3,max=5", "abcdef", validation.ERR_MAX},
+		{"max=3", "яяя", 0},
+		{"len=2", []int{1, 2}, 0},
+		{"len=2", map[string]int{"a": 1}, validation.ERR_LEN},
+		{"min=1,max=10", 10, 0},
+		{"min=1,max=10", uint(11), validation.ERR_MAX},
+		{"min=0.5", 0.25, validation.ERR_MIN},
+		{"email", "john@example.com", 0},
+		{"email", "John <john@example.com>", validation.ERR_EMAIL},
+		{"enum=1|2", 2, 0},
+		{"enum=1|2", 3, validation.ERR_ENUM},
+		{"regex=^[a-z]+(,[a-z]+)*$", "a,b", 0},
+		{"regex=^[a-z]+$", "a1", validation.ERR_REGEX},
+		{"enum=a|b", []string{"a", "c"}, validation.ERR_ENUM},
+		{"min=3", (*string)(nil), 0},
+	}
+	for _, tc := range cases {
+		r, err := validation.Parse(tc.rules)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tc.rules, err)
+		}
+		var got uint
+		if err := r.Check(tc.value); err != nil {
+			got = err.Code()
+		}
+		if got != tc.want {
+			t.Errorf("%q on %#v: got code %d, want %d", tc.rules, tc.value, got, tc.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	tag, _ := validation.Parse("min=1,max=5")
+	conf, _ := validation.Parse("max=10,email")
+	if got := tag.Merge(conf).String(); got != "min=1,max=10,email" {
+		t.Errorf("got %q", got)
+	}
+	if got := tag.Merge(nil); got != tag {
+		t.Error("merging nil must keep the rules")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 type manager struct {
 	db             *sql.DB
+	driver         string
 	migrationsPath string
 	seedsPath      string
 }
@@ -41,10 +42,24 @@ func (m *manager) getAppliedData() (*appliedData, error) {
 }
 
 func (m *manager) isTableExist() (bool, error) {
-	query := fmt.Sprintf(`
+	var query string
+	switch m.driver {
+	case "mysql":
+		query = fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name = '%s'`, cTABLE_NAME)
+	case "sqlite":
+		query = fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM sqlite_master
+		WHERE type = 'table' AND name = '%s'`, cTABLE_NAME)
+	default:
+		query = fmt.Sprintf(`
 		SELECT COUNT(*)
 		FROM information_schema.tables
 		WHERE table_name = '%s'`, cTABLE_NAME)
+	}
 	var count int
 	err := m.db.QueryRow(query).Scan(&count)
 	if err != nil {
@@ -75,3 +90,11 @@ func (m *manager) createTable() error {
 
 	return nil
 }
+
+// placeholder returns the driver's bind parameter for the i-th (1-based) value.
+func (m *manager) placeholder(i int) string {
+	if m.driver == "mysql" {
+		return "?"
+	}
+	return fmt.Sprintf("$%d", i)
+}
@@ -0,0 +1,32 @@
+package migrator
+
+import (
+	"database/sql"
+	"testing"
+)
+
+type testConnection struct {
+	driver string
+}
+
+func (c *testConnection) DB() *sql.DB    { return nil }
+func (c *testConnection) Driver() string { return c.driver }
+
+func TestManager_Placeholder(t *testing.T) {
+	cases := map[string]string{"": "$2", "postgres": "$2", "sqlite": "$2", "mysql": "?"}
+	for driver, want := range cases {
+		mm := &manager{driver: driver}
+		if got := mm.placeholder(2); got != want {
+			t.Errorf("driver %q: got %q, want %q", driver, got, want)
+		}
+	}
+}
+
+func TestSetConnection(t *testing.T) {
+	defer Init(Config{})
+
+	SetConnection(&testConnection{driver: "mysql"})
+	if m.driver != "mysql" || m.placeholder(1) != "?" {
+		t.Fatalf("expected the connection's driver to be used, got %q", m.driver)
+	}
+}
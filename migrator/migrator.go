@@ -26,10 +26,19 @@ up: | # TODO SQL to up migration
 down: | # TODO SQL to down migration
 `
 
+// Connection is a named DB connection of an app - kernel.IConnection
+// satisfies it, see SetConnection.
+type Connection interface {
+	DB() *sql.DB
+	Driver() string
+}
+
 // Config configures the package-level migrator state - see Init.
 type Config struct {
 	// DB is the database connection migrations/seeds run against.
 	DB *sql.DB
+	// Driver is the DB's driver - "postgres" (default), "mysql" or "sqlite".
+	Driver string
 	// MigrationsPath is the directory migration YAML files are read from/written to.
 	MigrationsPath string
 	// SeedsPath is the directory seed YAML files are read from.
@@ -40,6 +49,7 @@ type Config struct {
 // before using any other function in the package.
 func Init(conf Config) {
 	m.db = conf.DB
+	m.driver = conf.Driver
 	m.migrationsPath = conf.MigrationsPath
 	m.seedsPath = conf.SeedsPath
 }
@@ -49,6 +59,15 @@ func SetDB(db *sql.DB) {
 	m.db = db
 }
 
+// SetConnection overrides the database connection and driver set by Init
+// with c's - e.g. one of an app's named connections:
+//
+//	migrator.SetConnection(app.NamedConnection("replica"))
+func SetConnection(c Connection) {
+	m.db = c.DB()
+	m.driver = c.Driver()
+}
+
 // SetMigrationsPath overrides the migrations directory set by Init.
 func SetMigrationsPath(migrationsPath string) {
 	m.migrationsPath = migrationsPath
@@ -280,7 +299,8 @@ func upMigration(tx *sql.Tx, mig *migration) error {
 		}
 	}
 
-	_, err = tx.Exec(`INSERT INTO `+cTABLE_NAME+` (time, name) VALUES ($1, $2)`, mig.timestamp, mig.name)
+	query := fmt.Sprintf("INSERT INTO %s (time, name) VALUES (%s, %s)", cTABLE_NAME, m.placeholder(1), m.placeholder(2))
+	_, err = tx.Exec(query, mig.timestamp, mig.name)
 	if err != nil {
 		return fmt.Errorf("failed to update migrations table for '%s': %s", mig.file, err)
 	}
@@ -326,7 +346,8 @@ func downMigration(tx *sql.Tx, mig *migration) error {
 		}
 	}
 
-	_, err = tx.Exec(`DELETE FROM `+cTABLE_NAME+` WHERE time = $1 AND name = $2`, mig.timestamp, mig.name)
+	query := fmt.Sprintf("DELETE FROM %s WHERE time = %s AND name = %s", cTABLE_NAME, m.placeholder(1), m.placeholder(2))
+	_, err = tx.Exec(query, mig.timestamp, mig.name)
 	if err != nil {
 		return fmt.Errorf("failed to update migrations table for '%s': %s", mig.file, err)
 	}
@@ -372,7 +393,7 @@ func insertRow(tx *sql.Tx, table string, row map[string]any) error {
 	i := 1
 	for k, v := range row {
 		cols = append(cols, k)
-		placeholders = append(placeholders, fmt.Sprintf("$%d", i))
+		placeholders = append(placeholders, m.placeholder(i))
 		values = append(values, v)
 		i++
 	}
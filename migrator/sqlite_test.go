@@ -0,0 +1,74 @@
+package migrator_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/glebarez/go-sqlite"
+
+	"github.com/epicoon/lxgo/migrator"
+)
+
+// sqliteConnection is a named connection of an app, as migrator.SetConnection
+// takes it - kernel.IConnection satisfies the same interface.
+type sqliteConnection struct {
+	db *sql.DB
+}
+
+func (c *sqliteConnection) DB() *sql.DB    { return c.db }
+func (c *sqliteConnection) Driver() string { return "sqlite" }
+
+func sqliteTableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	if err != nil {
+		t.Fatalf("check table %s: %v", name, err)
+	}
+	return count > 0
+}
+
+func TestSqlite_UpDownOnNamedConnection(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite", filepath.Join(dir, "replica.db"))
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+
+	migrationsDir := filepath.Join(dir, "migrations")
+	if err := os.Mkdir(migrationsDir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for name, content := range map[string]string{
+		"00000001_create_a.yaml": "name: a\ntype: query\n\nup: CREATE TABLE widgets_a (id INTEGER PRIMARY KEY)\n\ndown: DROP TABLE widgets_a\n",
+		"00000002_create_b.yaml": "name: b\ntype: query\n\nup: CREATE TABLE widgets_b (id INTEGER PRIMARY KEY)\n\ndown: DROP TABLE widgets_b\n",
+	} {
+		if err := os.WriteFile(filepath.Join(migrationsDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("write migration: %v", err)
+		}
+	}
+
+	// The main connection is a postgres one - never touched here.
+	migrator.Init(migrator.Config{Driver: "postgres", MigrationsPath: migrationsDir})
+	defer migrator.Init(migrator.Config{})
+	migrator.SetConnection(&sqliteConnection{db: db})
+
+	migrator.Up()
+	if !sqliteTableExists(t, db, "widgets_a") || !sqliteTableExists(t, db, "widgets_b") {
+		t.Fatal("expected both migrations' tables to exist after Up()")
+	}
+	if mm, err := migrator.Check(); err != nil || len(mm) != 0 {
+		t.Fatalf("expected no unapplied migrations, got %d, %v", len(mm), err)
+	}
+
+	migrator.Down(1)
+	if sqliteTableExists(t, db, "widgets_b") || !sqliteTableExists(t, db, "widgets_a") {
+		t.Fatal("expected Down(1) to roll back the last migration only")
+	}
+	if mm, err := migrator.Check(); err != nil || len(mm) != 1 {
+		t.Fatalf("expected one unapplied migration, got %d, %v", len(mm), err)
+	}
+}
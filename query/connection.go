@@ -0,0 +1,59 @@
+package query
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Dialector builds a GORM dialector over an already open *sql.DB - see
+// RegisterDialector.
+type Dialector func(db *sql.DB) gorm.Dialector
+
+var dialectors = map[string]Dialector{
+	"postgres": func(db *sql.DB) gorm.Dialector {
+		return postgres.New(postgres.Config{Conn: db})
+	},
+	"mysql": func(db *sql.DB) gorm.Dialector {
+		return mysql.New(mysql.Config{Conn: db})
+	},
+	"sqlite": func(db *sql.DB) gorm.Dialector {
+		return sqlite.Dialector{Conn: db}
+	},
+}
+
+// RegisterDialector makes Open support the connection driver named driver
+// (or replaces its dialector) - "postgres", "mysql" and "sqlite" are
+// supported out of the box. E.g. for SQL Server with gorm.io/driver/sqlserver:
+//
+//	query.RegisterDialector("sqlserver", func(db *sql.DB) gorm.Dialector {
+//		return sqlserver.New(sqlserver.Config{Conn: db})
+//	})
+func RegisterDialector(driver string, d Dialector) {
+	dialectors[driver] = d
+}
+
+// Open wraps conn - the app's connection or one of its named ones, e.g.
+// app.NamedConnection("replica") - in a *gorm.DB for NewBaseRepo, picking
+// the dialector by conn.Driver(). conn must already be connected.
+func Open(conn kernel.IConnection, conf ...*gorm.Config) (*gorm.DB, error) {
+	if conn == nil || conn.DB() == nil {
+		return nil, errors.New("DB connection is not established")
+	}
+	d, ok := dialectors[conn.Driver()]
+	if !ok {
+		return nil, fmt.Errorf("no GORM dialector registered for DB driver '%s'", conn.Driver())
+	}
+
+	var c gorm.Config
+	if len(conf) > 0 && conf[0] != nil {
+		c = *conf[0]
+	}
+	return gorm.Open(d(conn.DB()), &c)
+}
@@ -0,0 +1,107 @@
+package query
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type testConnection struct {
+	kernel.IConnection
+	driver string
+	db     *sql.DB
+}
+
+func (c *testConnection) Driver() string { return c.driver }
+func (c *testConnection) DB() *sql.DB    { return c.db }
+
+type note struct {
+	BaseModel
+	Body string
+}
+
+// connectReplica connects the "replica" named connection of a config with
+// an in-memory SQLite database.
+func connectReplica(t *testing.T) *lxApp.Connection {
+	t.Helper()
+	conn, err := lxApp.NewNamedConnection(kernel.Dict{
+		"Driver": "postgres",
+		"Connections": kernel.Dict{
+			"replica": kernel.Dict{"Driver": "sqlite", "DBName": ":memory:"},
+		},
+	}, "replica")
+	if err != nil {
+		t.Fatalf("NewNamedConnection: %v", err)
+	}
+	if err := conn.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestOpen(t *testing.T) {
+	conn := connectReplica(t)
+
+	if _, err := Open(&testConnection{driver: "postgres"}); err == nil {
+		t.Error("expected an error for a connection that isn't established")
+	}
+	if _, err := Open(&testConnection{driver: "oracle", db: conn.DB()}); err == nil {
+		t.Error("expected an error for a driver without a dialector")
+	}
+
+	RegisterDialector("stub", func(db *sql.DB) gorm.Dialector {
+		return postgres.New(postgres.Config{Conn: db})
+	})
+	defer delete(dialectors, "stub")
+
+	gormDB, err := Open(&testConnection{driver: "stub", db: conn.DB()}, &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if gormDB.Dialector.Name() != "postgres" || !gormDB.SkipDefaultTransaction {
+		t.Fatalf("unexpected gorm DB %#v", gormDB.Config)
+	}
+	if db, _ := gormDB.DB(); db != conn.DB() {
+		t.Fatal("expected the connection's *sql.DB to be reused")
+	}
+}
+
+func TestOpen_Sqlite(t *testing.T) {
+	conn := connectReplica(t)
+
+	db, err := Open(conn)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if db.Dialector.Name() != "sqlite" {
+		t.Fatalf("expected the sqlite dialector, got %q", db.Dialector.Name())
+	}
+	if err := db.AutoMigrate(&note{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	repo := NewBaseRepo[note](db, nil)
+	n := &note{Body: "hello"}
+	if err := repo.Create(n); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	got, err := repo.ReadByID(n.ID)
+	if err != nil || got.Body != "hello" {
+		t.Fatalf("expected the note back, got %v, %v", got, err)
+	}
+	items, err := repo.QueryBuilder().Where(Eq("body", "hello")).All()
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected the query builder to find the note, got %v, %v", items, err)
+	}
+	if err := repo.DeleteByID(n.ID); err != nil {
+		t.Fatalf("DeleteByID: %v", err)
+	}
+	if count, _ := repo.Count(); count != 0 {
+		t.Fatalf("expected the note soft-deleted, count %d", count)
+	}
+}
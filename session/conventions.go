@@ -5,6 +5,7 @@
 package session
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -116,6 +117,16 @@ type IProvider interface {
 	content() string
 }
 
+// IProviderPinger is an IProvider backed by a store that may be
+// unreachable (e.g. Redis) - Storage reports the app not ready while Ping
+// fails, if the app has a health component (see kernel/health).
+type IProviderPinger interface {
+	IProvider
+
+	// Ping reports whether the backing store is reachable.
+	Ping(ctx context.Context) error
+}
+
 // IScanner inspects a session store for debugging/diagnostics - see Storage.Scanner.
 type IScanner interface {
 	// Len returns the number of sessions currently stored.
@@ -0,0 +1,347 @@
+// Package csrf provides a CSRF protection component for lxgo/kernel
+// applications built on session.Storage - a token per session, stored in
+// the session, handed to templates (see Component.Renderer) and to
+// scripts (see Config.HeaderName), and checked on every unsafe request to
+// the protected routes.
+package csrf
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/epicoon/lxgo/kernel"
+	lxApp "github.com/epicoon/lxgo/kernel/app"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+	"github.com/epicoon/lxgo/session"
+)
+
+// APP_COMPONENT_KEY is the key Component registers itself under - see
+// SetAppComponent/AppComponent.
+const APP_COMPONENT_KEY = "lxgo_csrf"
+
+// SESSION_KEY is the session key the token is stored under.
+const SESSION_KEY = "lxgo_csrf_token"
+
+// TEMPLATE_PARAM is the template param the token is available under - see
+// Component.Renderer.
+const TEMPLATE_PARAM = "CsrfToken"
+
+// TEMPLATE_FIELD_PARAM is the template param the form field name is
+// available under - see Component.Renderer.
+const TEMPLATE_FIELD_PARAM = "CsrfField"
+
+// ERR_TOKEN_MISMATCH is the fail response's default "error_code" - see
+// Config.ErrorCode.
+const ERR_TOKEN_MISMATCH = 419
+
+// DefaultHeaderName is the header the token is sent and read in unless
+// Config.HeaderName says otherwise.
+const DefaultHeaderName = "X-CSRF-Token"
+
+// DefaultFieldName is the form field the token is read from unless
+// Config.FieldName says otherwise.
+const DefaultFieldName = "csrf_token"
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Config
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponentConfig */
+
+// Config is Component's app-component configuration.
+type Config struct {
+	*lxApp.ComponentConfig
+	// Routes lists the routes whose unsafe requests are checked - a
+	// registered route (e.g. "/users/{id:int}") or a path prefix ending with
+	// "*" (e.g. "/admin/*"). Every route if empty.
+	Routes []string
+	// Except lists the routes never checked (e.g. webhooks), in the same format.
+	Except []string
+	// HeaderName is the header scripts send the token in, and every
+	// response carries it in - DefaultHeaderName if unset.
+	HeaderName string
+	// FieldName is the form field HTML forms send the token in -
+	// DefaultFieldName if unset. A multipart form must send it as its
+	// first field.
+	FieldName string
+	// ErrorCode is the fail response's "error_code" - ERR_TOKEN_MISMATCH if unset.
+	ErrorCode int
+}
+
+/** @constructor kernel.CAppComponentConfig */
+
+// NewConfig constructs a Config.
+func NewConfig() kernel.IAppComponentConfig {
+	return &Config{ComponentConfig: lxApp.NewComponentConfigStruct()}
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * Component
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+/** @interface kernel.IAppComponent */
+
+// Component issues the sessions' CSRF tokens and checks them - see
+// SetAppComponent to register it on an app.
+type Component struct {
+	*lxApp.AppComponent
+}
+
+var _ kernel.IAppComponent = (*Component)(nil)
+
+// SetAppComponent registers a new Component on app under
+// APP_COMPONENT_KEY, configured from the config section named by configKey.
+// The app must have its session storage already (see
+// session.SetAppComponent) - the tokens are kept in the sessions.
+func SetAppComponent(app kernel.IApp, configKey string) error {
+	if app.HasComponent(APP_COMPONENT_KEY) {
+		return fmt.Errorf("the application already has component: %s", APP_COMPONENT_KEY)
+	}
+	if _, err := session.AppComponent(app); err != nil {
+		return fmt.Errorf("can not init csrf component: %s", err)
+	}
+
+	c := NewComponent()
+	if err := lxApp.InitComponent(c, app, configKey); err != nil {
+		return fmt.Errorf("can not init csrf component: %s", err)
+	}
+
+	app.SetComponent(APP_COMPONENT_KEY, c)
+	return nil
+}
+
+// AppComponent returns the Component registered on app under APP_COMPONENT_KEY.
+func AppComponent(app kernel.IApp) (*Component, error) {
+	c := app.Component(APP_COMPONENT_KEY)
+	if c == nil {
+		return nil, fmt.Errorf("application component '%s' not found", APP_COMPONENT_KEY)
+	}
+
+	cc, ok := c.(*Component)
+	if !ok {
+		return nil, fmt.Errorf("application component '%s' is not '*csrf.Component'", APP_COMPONENT_KEY)
+	}
+
+	return cc, nil
+}
+
+/** @constructor */
+
+// NewComponent constructs a Component.
+func NewComponent() *Component {
+	return &Component{AppComponent: lxApp.NewAppComponent()}
+}
+
+// Name returns the component's name - see kernel.IAppComponent.
+func (c *Component) Name() string {
+	return "Csrf"
+}
+
+// LogCategory returns the category the component's log methods write under.
+func (c *Component) LogCategory() string {
+	return "Csrf"
+}
+
+// CConfig returns Config's constructor - see kernel.IAppComponent.
+func (c *Component) CConfig() kernel.CAppComponentConfig {
+	return NewConfig
+}
+
+// Config returns the component's bound Config.
+func (c *Component) Config() *Config {
+	return (c.GetConfig()).(*Config)
+}
+
+// DependsOn returns the session storage's key - see
+// kernel.IAppComponentDependent.
+func (c *Component) DependsOn() []any {
+	return []any{session.APP_COMPONENT_KEY}
+}
+
+// AfterInit registers the middleware checking the tokens - it runs after
+// the session storage's, registered before. See kernel.IAppComponent.
+func (c *Component) AfterInit() {
+	conf := c.Config()
+	if conf.HeaderName == "" {
+		conf.HeaderName = DefaultHeaderName
+	}
+	if conf.FieldName == "" {
+		conf.FieldName = DefaultFieldName
+	}
+	if conf.ErrorCode == 0 {
+		conf.ErrorCode = ERR_TOKEN_MISMATCH
+	}
+	c.App().Router().AddWrapMiddleware(c.middleware)
+}
+
+// Token returns the session's token, issuing one if it has none yet.
+func (c *Component) Token(ctx kernel.IHandleContext) (string, error) {
+	sess, err := session.ExtractSession(ctx)
+	if err != nil {
+		return "", fmt.Errorf("can not get csrf token: %w", err)
+	}
+	if token, ok := sess.Get(SESSION_KEY).(string); ok && token != "" {
+		return token, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", fmt.Errorf("can not generate csrf token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	sess.SetForce(SESSION_KEY, token)
+	return token, nil
+}
+
+// RegenerateToken replaces the session's token - call it when the user
+// signs in or out.
+func (c *Component) RegenerateToken(ctx kernel.IHandleContext) (string, error) {
+	sess, err := session.ExtractSession(ctx)
+	if err != nil {
+		return "", fmt.Errorf("can not regenerate csrf token: %w", err)
+	}
+	sess.Remove(SESSION_KEY)
+	return c.Token(ctx)
+}
+
+// Renderer returns the app's template renderer with the token under
+// TEMPLATE_PARAM and the form field name under TEMPLATE_FIELD_PARAM, e.g.
+// for a form:
+//
+//	<input type="hidden" name="{{.CsrfField}}" value="{{.CsrfToken}}">
+func (c *Component) Renderer(ctx kernel.IHandleContext) (kernel.ITemplateRenderer, error) {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.App().TemplateRenderer().
+		AddParam(TEMPLATE_PARAM, token).
+		AddParam(TEMPLATE_FIELD_PARAM, c.Config().FieldName), nil
+}
+
+/* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
+ * PRIVATE
+ * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * */
+
+// middleware hands the token to the client in the response header, and
+// answers an unsafe request to a protected route without a matching token
+// with the resource's FailResponse.
+func (c *Component) middleware(ctx kernel.IHandleContext, next kernel.FNextHandler) kernel.IHttpResponse {
+	conf := c.Config()
+	token, err := c.Token(ctx)
+	if err != nil {
+		c.Logger().Error(err.Error())
+		http.Error(ctx.ResponseWriter(), "Internal server error", http.StatusInternalServerError)
+		return nil
+	}
+	ctx.ResponseWriter().Header().Set(conf.HeaderName, token)
+
+	if safeMethod(ctx.Method()) || !c.protected(ctx) {
+		return next()
+	}
+
+	r := ctx.Request()
+	sent := r.Header.Get(conf.HeaderName)
+	if sent == "" {
+		sent = formToken(ctx, conf.FieldName)
+	}
+	if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) == 1 {
+		return next()
+	}
+
+	ctx.Logger().Warning("csrf token mismatch", "route", ctx.Route(), "method", ctx.Method())
+	return ctx.Resource().FailResponse(kernel.JsonResponseConfig{
+		Code: http.StatusForbidden,
+		Dict: kernel.Dict{
+			"success":       false,
+			"error_code":    conf.ErrorCode,
+			"error_message": "CSRF token mismatch",
+		},
+	})
+}
+
+// maxFirstPartSize bounds what's read of a multipart body looking for the
+// token - its first part's headers and value.
+const maxFirstPartSize = 16 << 10
+
+// maxTokenSize bounds the token value read from a form.
+const maxTokenSize = 256
+
+func (c *Component) protected(ctx kernel.IHandleContext) bool {
+	conf := c.Config()
+	if routesMatch(conf.Except, ctx) {
+		return false
+	}
+	return len(conf.Routes) == 0 || routesMatch(conf.Routes, ctx)
+}
+
+func routesMatch(routes []string, ctx kernel.IHandleContext) bool {
+	for _, route := range routes {
+		if prefix, ok := strings.CutSuffix(route, "*"); ok {
+			if strings.HasPrefix(ctx.Request().URL.Path, prefix) {
+				return true
+			}
+		} else if route == ctx.Route() || route == ctx.Request().URL.Path {
+			return true
+		}
+	}
+	return false
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// formToken reads the token from an urlencoded or multipart body, within
+// the app's upload limits (see lxHttp.AppUploadLimits). Only a multipart
+// body's first part is read, and the bytes read are put back for the
+// resource's form filler - an unauthenticated request doesn't get its
+// files parsed.
+func formToken(ctx kernel.IHandleContext, field string) string {
+	r := ctx.Request()
+	ct := r.Header.Get("Content-Type")
+	if strings.HasPrefix(ct, "application/x-www-form-urlencoded") {
+		limits := lxHttp.AppUploadLimits(ctx.App())
+		r.Body = http.MaxBytesReader(ctx.ResponseWriter(), r.Body, limits.MaxBodySize)
+		return r.PostFormValue(field)
+	}
+	if !strings.HasPrefix(ct, "multipart/form-data") {
+		return ""
+	}
+
+	_, params, err := mime.ParseMediaType(ct)
+	if err != nil || params["boundary"] == "" {
+		return ""
+	}
+	body := r.Body
+	read := new(bytes.Buffer)
+	defer func() {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(read, body), body}
+	}()
+
+	mr := multipart.NewReader(io.TeeReader(io.LimitReader(body, maxFirstPartSize), read), params["boundary"])
+	part, err := mr.NextPart()
+	if err != nil || part.FormName() != field || part.FileName() != "" {
+		return ""
+	}
+	token, err := io.ReadAll(io.LimitReader(part, maxTokenSize))
+	if err != nil {
+		return ""
+	}
+	return string(token)
+}
@@ -0,0 +1,261 @@
+package csrf_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/epicoon/lxgo/kernel"
+	"github.com/epicoon/lxgo/kernel/apptest"
+	lxHttp "github.com/epicoon/lxgo/kernel/http"
+	"github.com/epicoon/lxgo/session"
+	"github.com/epicoon/lxgo/session/csrf"
+)
+
+type formResource struct {
+	*lxHttp.Resource
+}
+
+func (r *formResource) Run() kernel.IHttpResponse {
+	c, err := csrf.AppComponent(r.App())
+	if err != nil {
+		return r.ErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+	renderer, err := c.Renderer(r.Context())
+	if err != nil {
+		return r.ErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+	html, err := renderer.
+		SetTemplate(`<input type="hidden" name="{{.CsrfField}}" value="{{.CsrfToken}}">`).
+		Render()
+	if err != nil {
+		return r.ErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+	return r.HtmlResponse(kernel.HtmlResponseConfig{Html: html})
+}
+
+type uploadResource struct {
+	*lxHttp.Resource
+}
+
+func (r *uploadResource) Run() kernel.IHttpResponse {
+	if err := r.Request().ParseMultipartForm(1 << 20); err != nil {
+		return r.ErrorResponse(http.StatusBadRequest, err.Error())
+	}
+	return r.JsonResponse(kernel.JsonResponseConfig{Data: kernel.Dict{"name": r.Request().FormValue("name")}})
+}
+
+type okResource struct {
+	*lxHttp.Resource
+}
+
+func (r *okResource) Run() kernel.IHttpResponse {
+	return r.JsonResponse(kernel.JsonResponseConfig{Data: kernel.Dict{"success": true}})
+}
+
+func newTestServer(t *testing.T, conf kernel.Dict) (*http.Client, string) {
+	t.Helper()
+	a, err := apptest.New(kernel.Dict{
+		"Components": kernel.Dict{
+			"SessionsStorage": kernel.Dict{"CookieName": "lxgosessid", "MaxLifeTime": 3600},
+			"Csrf":            conf,
+		},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if err := csrf.SetAppComponent(a, "Components.Csrf"); err == nil {
+		t.Fatal("expected an error without the session storage")
+	}
+	if err := session.SetAppComponent(a, "Components.SessionsStorage"); err != nil {
+		t.Fatalf("session.SetAppComponent: %v", err)
+	}
+	if err := csrf.SetAppComponent(a, "Components.Csrf"); err != nil {
+		t.Fatalf("csrf.SetAppComponent: %v", err)
+	}
+
+	a.Router().RegisterResource("/form", "GET", func() kernel.IHttpResource {
+		return &formResource{Resource: lxHttp.NewResource()}
+	})
+	cOk := func() kernel.IHttpResource {
+		return &okResource{Resource: lxHttp.NewResource()}
+	}
+	a.Router().RegisterResource("/form", "POST", cOk)
+	a.Router().RegisterResource("/hooks/stripe", "POST", cOk)
+	a.Router().RegisterResource("/upload", "POST", func() kernel.IHttpResource {
+		return &uploadResource{Resource: lxHttp.NewResource()}
+	})
+
+	srv := apptest.Server(a)
+	t.Cleanup(srv.Close)
+	jar, _ := cookiejar.New(nil)
+	return &http.Client{Jar: jar}, srv.URL
+}
+
+func fetchToken(t *testing.T, client *http.Client, base string) string {
+	t.Helper()
+	resp, err := client.Get(base + "/form")
+	if err != nil {
+		t.Fatalf("GET /form: %v", err)
+	}
+	defer resp.Body.Close()
+	token := resp.Header.Get(csrf.DefaultHeaderName)
+	if token == "" {
+		t.Fatal("expected the token in the response header")
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `name="csrf_token" value="`+token+`"`) {
+		t.Fatalf("expected the token rendered in the template, got %q", body)
+	}
+	return token
+}
+
+func TestCsrf_ChecksUnsafeRequests(t *testing.T) {
+	client, base := newTestServer(t, kernel.Dict{"Except": []any{"/hooks/*"}})
+	token := fetchToken(t, client, base)
+
+	resp, err := client.PostForm(base+"/form", url.Values{"name": {"x"}})
+	if err != nil {
+		t.Fatalf("POST /form: %v", err)
+	}
+	var fail map[string]any
+	json.NewDecoder(resp.Body).Decode(&fail)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 without a token, got %d", resp.StatusCode)
+	}
+	if fail["error_code"] != float64(csrf.ERR_TOKEN_MISMATCH) || fail["success"] != false {
+		t.Fatalf("expected a coded fail response, got %v", fail)
+	}
+
+	resp, err = client.PostForm(base+"/form", url.Values{"csrf_token": {token}})
+	if err != nil {
+		t.Fatalf("POST /form: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 with the form field token, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest("POST", base+"/form", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(csrf.DefaultHeaderName, token)
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("POST /form: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 with the header token, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.PostForm(base+"/hooks/stripe", nil)
+	if err != nil {
+		t.Fatalf("POST /hooks/stripe: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected an excepted route to pass, got %d", resp.StatusCode)
+	}
+}
+
+func TestCsrf_TokenPerSession(t *testing.T) {
+	client, base := newTestServer(t, kernel.Dict{"Routes": []any{"/form"}})
+	token := fetchToken(t, client, base)
+	if again := fetchToken(t, client, base); again != token {
+		t.Fatalf("expected the same token within a session, got %q and %q", token, again)
+	}
+
+	other, _ := cookiejar.New(nil)
+	otherClient := &http.Client{Jar: other}
+	if fetchToken(t, otherClient, base) == token {
+		t.Fatal("expected another session to get another token")
+	}
+
+	resp, err := otherClient.PostForm(base+"/form", url.Values{"csrf_token": {token}})
+	if err != nil {
+		t.Fatalf("POST /form: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected another session's token to be rejected, got %d", resp.StatusCode)
+	}
+
+	resp, err = client.PostForm(base+"/hooks/stripe", nil)
+	if err != nil {
+		t.Fatalf("POST /hooks/stripe: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected a route not in Routes to pass, got %d", resp.StatusCode)
+	}
+}
+
+func multipartBody(fields [][2]string, fileSize int64) (io.Reader, string) {
+	const boundary = "lxgo-test-boundary"
+	var head strings.Builder
+	for _, f := range fields {
+		head.WriteString("--" + boundary + "\r\nContent-Disposition: form-data; name=\"" + f[0] + "\"\r\n\r\n" + f[1] + "\r\n")
+	}
+	head.WriteString("--" + boundary + "\r\nContent-Disposition: form-data; name=\"file\"; filename=\"big.bin\"\r\n" +
+		"Content-Type: application/octet-stream\r\n\r\n")
+	return io.MultiReader(
+		strings.NewReader(head.String()),
+		io.LimitReader(zeros{}, fileSize),
+		strings.NewReader("\r\n--"+boundary+"--\r\n"),
+	), "multipart/form-data; boundary=" + boundary
+}
+
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestCsrf_Multipart(t *testing.T) {
+	client, base := newTestServer(t, kernel.Dict{})
+	token := fetchToken(t, client, base)
+
+	body, ct := multipartBody([][2]string{{"csrf_token", token}, {"name", "report"}}, 1024)
+	resp, err := client.Post(base+"/upload", ct, body)
+	if err != nil {
+		t.Fatalf("POST /upload: %v", err)
+	}
+	var got map[string]any
+	json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || got["name"] != "report" {
+		t.Fatalf("expected the form to reach the resource whole, got %d %v", resp.StatusCode, got)
+	}
+
+	// Far over the default upload limits and the memory multipart forms
+	// are parsed in - it must be rejected without being read.
+	body, ct = multipartBody([][2]string{{"name", "report"}}, 100<<20)
+	counted := &countingReader{Reader: body}
+	resp, err = client.Post(base+"/upload", ct, counted)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 without a token, got %d", resp.StatusCode)
+		}
+	}
+	if counted.n > 10<<20 {
+		t.Fatalf("expected the body to be left unread, %d bytes were sent", counted.n)
+	}
+}
@@ -1,6 +1,7 @@
 package session
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/epicoon/lxgo/kernel"
 	lxApp "github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/health"
 )
 
 /* * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * * *
@@ -116,6 +118,21 @@ func (s *Storage) AfterInit() {
 	s.GC()
 }
 
+// Run registers the "session" readiness check on the app's health
+// component, if the app has one - failing while the provider, if it's an
+// IProviderPinger, can't be reached. See kernel.IAppComponent.
+func (s *Storage) Run() error {
+	if h, err := health.AppComponent(s.App()); err == nil {
+		h.AddReadinessCheck("session", func(ctx context.Context) error {
+			if p, ok := s.Provider().(IProviderPinger); ok {
+				return p.Ping(ctx)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
 // CConfig returns Config's constructor - see kernel.IAppComponent.
 func (c *Storage) CConfig() kernel.CAppComponentConfig {
 	return NewConfig
@@ -1,6 +1,8 @@
 package component
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
@@ -9,6 +11,8 @@ import (
 
 	"github.com/epicoon/lxgo/kernel"
 	lxApp "github.com/epicoon/lxgo/kernel/app"
+	"github.com/epicoon/lxgo/kernel/health"
+	"github.com/epicoon/lxgo/kernel/metrics"
 	"github.com/epicoon/lxgo/ws"
 	"github.com/epicoon/lxgo/ws/internal/src"
 )
@@ -28,7 +32,8 @@ type WSServer struct {
 	// listener is guarded by mu - Start() (meant to run in its own
 	// goroutine, per its doc-comment) sets it, while Stop() (called from
 	// whatever goroutine is coordinating shutdown - a different one) reads
-	// it to close it.
+	// it to close it, clearing it - so it's set exactly while the server is
+	// accepting connections, see Run's readiness check.
 	mu       sync.Mutex
 	listener net.Listener
 
@@ -208,8 +213,50 @@ func (s *WSServer) CreateMessage() ws.IMessage {
 	return src.NewMessage(s)
 }
 
-// Start opens the TCP listener and blocks, accepting connections until Stop
-// is called (or the listener errors) - run it in its own goroutine.
+// Run registers the server's connection/channel gauges on the app's
+// metrics component and its "ws" readiness check (failing unless Start is
+// accepting connections) on the app's health component, if the app has
+// them - see kernel/metrics and kernel/health. It doesn't start the server
+// itself, that's still Start's job.
+func (s *WSServer) Run() error {
+	if h, err := health.AppComponent(s.App()); err == nil {
+		h.AddReadinessCheck("ws", func(context.Context) error {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.listener == nil {
+				return errors.New("not accepting connections")
+			}
+			return nil
+		})
+	}
+
+	m, err := metrics.AppComponent(s.App())
+	if err != nil {
+		return nil
+	}
+
+	gauges := []struct {
+		name, help string
+		f          func() float64
+	}{
+		{"lxgo_ws_connections", "Number of live WebSocket connections.",
+			func() float64 { return float64(len(s.conns.GetAll())) }},
+		{"lxgo_ws_channels", "Number of open WebSocket channels.",
+			func() float64 { return float64(len(s.channels.Channels())) }},
+		{"lxgo_ws_public_channels", "Number of open public WebSocket channels.",
+			func() float64 { return float64(len(s.channels.PublicChannels())) }},
+	}
+	for _, g := range gauges {
+		if err := m.Registry().NewGaugeFunc(g.name, g.help, g.f); err != nil {
+			return fmt.Errorf("can not register WS metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// Start opens the TCP listener (TLS one if Config().TLS is set) and blocks,
+// accepting connections until Stop is called (or the listener errors) - run
+// it in its own goroutine.
 func (s *WSServer) Start() error {
 	// Deliberately not in AfterInit(): that runs synchronously inside
 	// SetAppComponent, before application code has a chance to call
@@ -219,11 +266,21 @@ func (s *WSServer) Start() error {
 	// to already be registered by the time it fires for it.
 	s.channels.Init()
 
+	var tlsConf *tls.Config
+	if s.Config().TLS {
+		if tlsConf = lxApp.AppTLSConfig(s.App()); tlsConf == nil {
+			return errors.New("TLS is enabled, but the application has no TLS config")
+		}
+	}
+
 	addr := fmt.Sprintf("%s:%d", s.Config().Host, s.Config().Port)
 	ln, err := net.Listen("tcp", addr)
 	if err != nil {
 		return fmt.Errorf("error creating socket: %w", err)
 	}
+	if tlsConf != nil {
+		ln = tls.NewListener(ln, tlsConf)
+	}
 	s.mu.Lock()
 	s.listener = ln
 	s.mu.Unlock()
@@ -252,6 +309,7 @@ func (s *WSServer) Start() error {
 func (s *WSServer) Stop() {
 	s.mu.Lock()
 	ln := s.listener
+	s.listener = nil
 	s.mu.Unlock()
 	if ln != nil {
 		if err := ln.Close(); err != nil {
@@ -1,11 +1,15 @@
 package component
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/epicoon/lxgo/kernel"
 	"github.com/epicoon/lxgo/kernel/apptest"
+	"github.com/epicoon/lxgo/kernel/health"
+	"github.com/epicoon/lxgo/kernel/metrics"
 )
 
 // newTestWSServer builds a real *WSServer via a real (apptest-backed)
@@ -71,3 +75,87 @@ func TestWSServer_StopDoesNotHangAcceptLoop(t *testing.T) {
 		t.Fatalf("Start()'s accept loop is still running after Stop() - regression on the historical infinite-loop bug")
 	}
 }
+
+// TestWSServer_Run_RegistersMetrics checks Run() puts the connection/channel
+// gauges on the app's metrics component, when there is one.
+func TestWSServer_Run_RegistersMetrics(t *testing.T) {
+	app, err := apptest.New(kernel.Dict{
+		"Components": kernel.Dict{
+			"WSServer": kernel.Dict{"Host": "127.0.0.1", "Port": 0},
+			"Metrics":  kernel.Dict{},
+		},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if err := metrics.SetAppComponent(app, "Components.Metrics"); err != nil {
+		t.Fatalf("metrics.SetAppComponent: %v", err)
+	}
+	if err := SetAppComponent(app, "Components.WSServer"); err != nil {
+		t.Fatalf("SetAppComponent: %v", err)
+	}
+	s, _ := AppComponent(app)
+	if err := s.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	m, _ := metrics.AppComponent(app)
+	var out strings.Builder
+	if _, err := m.Registry().WriteTo(&out); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	for _, want := range []string{"lxgo_ws_connections 0\n", "lxgo_ws_channels 0\n", "lxgo_ws_public_channels 0\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+// TestWSServer_Run_RegistersReadinessCheck checks the "ws" readiness check
+// Run() puts on the app's health component passes exactly while Start() is
+// accepting connections.
+func TestWSServer_Run_RegistersReadinessCheck(t *testing.T) {
+	app, err := apptest.New(kernel.Dict{
+		"Components": kernel.Dict{
+			"WSServer": kernel.Dict{"Host": "127.0.0.1", "Port": 0},
+			"Health":   kernel.Dict{},
+		},
+	})
+	if err != nil {
+		t.Fatalf("apptest.New: %v", err)
+	}
+	if err := health.SetAppComponent(app, "Components.Health"); err != nil {
+		t.Fatalf("health.SetAppComponent: %v", err)
+	}
+	if err := SetAppComponent(app, "Components.WSServer"); err != nil {
+		t.Fatalf("SetAppComponent: %v", err)
+	}
+	s, _ := AppComponent(app)
+	if err := s.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	h, _ := health.AppComponent(app)
+	ready := func() bool {
+		return h.Readiness(context.Background()).Checks["ws"].Status == health.STATUS_OK
+	}
+	if ready() {
+		t.Fatal("expected the server not to be ready before Start()")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.Start() }()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && !ready() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !ready() {
+		t.Fatal("expected the server to be ready once started")
+	}
+
+	s.Stop()
+	<-done
+	if ready() {
+		t.Fatal("expected the server not to be ready once stopped")
+	}
+}
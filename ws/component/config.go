@@ -13,6 +13,9 @@ type WSServerConfig struct {
 	Host string
 	// Port is the port the WS server listens on.
 	Port int
+	// TLS serves wss:// with the app's TLS settings (its "TLS" config
+	// section, see app.AppTLSConfig) - certificate reloads included.
+	TLS bool
 	// AllowedOrigins is the Origin allowlist for the WS handshake -
 	// empty/unset means no restriction.
 	AllowedOrigins []string
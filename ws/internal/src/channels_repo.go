@@ -16,8 +16,9 @@ type ChannelRepo struct {
 	mu       sync.RWMutex
 	channels map[string]ws.IChannel
 
-	wg   sync.WaitGroup
-	quit chan struct{}
+	wg    sync.WaitGroup
+	quit  chan struct{}
+	sweep sync.Once
 }
 
 var _ ws.IChannelRepo = (*ChannelRepo)(nil)
@@ -29,8 +30,6 @@ func NewChannelRepo(s ws.IWSServer) *ChannelRepo {
 		channels: map[string]ws.IChannel{},
 		quit:     make(chan struct{}),
 	}
-	r.wg.Add(1)
-	go r.sweeper()
 	return r
 }
 
@@ -39,7 +38,15 @@ func (r *ChannelRepo) Close() {
 	r.wg.Wait()
 }
 
+// Init creates the DefaultChannel, if configured, and starts the sweeper -
+// not before, as it reads the server's config, which isn't bound until the
+// component is set up.
 func (r *ChannelRepo) Init() {
+	r.sweep.Do(func() {
+		r.wg.Add(1)
+		go r.sweeper()
+	})
+
 	if r.server.DefaultChannelKey() != "" {
 		// The DefaultChannel is a startup-time technical bootstrap, not a
 		// creation request from anyone - it always skips ChannelValidator,
@@ -218,6 +218,7 @@ func TestChannelRepo_PublicChannels(t *testing.T) {
 func TestChannelRepo_Sweeper_ClosesEmptyChannelPastTTL(t *testing.T) {
 	s := newFakeServer(withEmptyChannelTTL(1)) // seconds - the sweeper ticks every second too
 	t.Cleanup(s.close)
+	s.Channels().Init() // starts the sweeper
 
 	ch, reason := s.Channels().CreateChannel(ws.NewChannelBuilder().SetKey("k1"))
 	if ch == nil {
@@ -145,7 +145,7 @@ var _ ws.IWSServer = (*fakeServer)(nil)
 
 // fakeServerOption configures a fakeServer before its background sweepers
 // start - config fields must never be mutated after newFakeServer returns,
-// since NewConnRepo/NewChannelRepo's sweeper goroutines read them
+// since the NewConnRepo and ChannelRepo.Init sweeper goroutines read them
 // concurrently (see the ConnRepo.GetAll/Reconnect races this same audit
 // found and fixed in the production code - the harness must not reintroduce
 // the same class of bug against itself).
@@ -190,7 +190,7 @@ func newFakeServer(opts ...fakeServerOption) *fakeServer {
 	return s
 }
 
-// close stops the background sweepers started by NewConnRepo/NewChannelRepo -
+// close stops the background sweepers started by NewConnRepo/ChannelRepo.Init -
 // call via t.Cleanup in every test that builds a fakeServer.
 func (s *fakeServer) close() {
 	s.conns.Close()